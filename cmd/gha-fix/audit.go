@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	ghafix "github.com/Finatext/gha-fix"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit --allowlist FILE [file1 file2 ...]",
+	Short: "Report actions pinned to commit SHAs that are not on an allowlist",
+	Long: `Report every action pinned to a commit SHA that an allowlist doesn't approve for its repository,
+catching pins that drifted from the approved SHAs and actions added without approval.
+
+The allowlist is a YAML file mapping owner/repo to its approved commit SHAs, as for 'pin --allowlist':
+  actions/checkout:
+    - 11bd71901bbe5b1630ceea73d27597364c9af683
+Repositories missing from the allowlist have no approved SHA. Actions that aren't pinned to a commit
+SHA are not audited; use 'pin --check' to find them.
+Usage:
+  audit --allowlist FILE [file1 file2 ...]
+If no files are specified, all workflow files (.yml or .yaml) in the current directory
+and subdirectories will be processed. Pass '-' as the only file to read from stdin.
+
+The violations are written to stdout as a table, and the command exits 1 when there is any:
+  FILE  LINE  ACTION  SHA
+
+You can customize the behavior with the following options:
+  --allowlist: YAML file mapping owner/repo to approved commit SHAs (required)
+
+Global options:
+  --ignore-dirs: Skip specific directories when searching for workflow files (e.g., "node_modules,dist")
+  --include-action-yml-names: Only discover workflows under .github/workflows/ and action.yml/action.yaml files
+  --max-depth: Skip directories nested deeper than this below the current directory (default 0 = unlimited)
+
+Note: no GitHub token is needed; the audit never calls the GitHub API.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := commandContext(0)
+		defer cancel()
+
+		allowlistPath := viper.GetString("audit.allowlist")
+		if allowlistPath == "" {
+			slog.Error("--allowlist is required")
+			os.Exit(exitFailure)
+		}
+		allowlist, err := ghafix.LoadAllowlist(allowlistPath)
+		if err != nil {
+			slog.Error("failed to load allowlist", "error", err)
+			os.Exit(exitFailure)
+		}
+
+		auditCmd := ghafix.NewAuditCommand(ghafix.AuditOptions{
+			IgnoreDirs:      viper.GetStringSlice("ignore-dirs"), // Use common ignore-dirs configuration
+			ActionFilesOnly: viper.GetBool("include-action-yml-names"),
+			MaxDepth:        viper.GetInt("max-depth"),
+			Allowlist:       allowlist,
+		})
+
+		violations, err := auditCmd.Run(ctx, args)
+		if len(violations) > 0 {
+			printAuditViolations(violations)
+		}
+		if err != nil {
+			slog.Error("failed to audit some files", "error", err)
+			os.Exit(exitFailure)
+		}
+		if len(violations) > 0 {
+			slog.Error("found actions pinned to commit SHAs that are not on the allowlist", slog.Int("count", len(violations)))
+			os.Exit(exitFailure)
+		}
+		slog.Info("all pinned actions are on the allowlist")
+	},
+}
+
+func printAuditViolations(violations []ghafix.AuditViolation) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tLINE\tACTION\tSHA")
+	for _, v := range violations {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", v.File, v.Line, v.Action, v.SHA)
+	}
+	cobra.CheckErr(w.Flush())
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().String("allowlist", "", "YAML file mapping owner/repo to approved commit SHAs (required)")
+	cobra.CheckErr(viper.BindPFlag("audit.allowlist", auditCmd.Flags().Lookup("allowlist")))
+}
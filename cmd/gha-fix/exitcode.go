@@ -0,0 +1,74 @@
+package main
+
+import ghafix "github.com/Finatext/gha-fix"
+
+// Exit codes of the commands, documented in the README for automation to branch on.
+const (
+	exitOK = 0
+	// A generic failure, e.g. invalid options or files failing for action-specific reasons, or pending changes in
+	// check and pre-commit modes.
+	exitFailure = 1
+	// The GitHub API failed the run: rejected credentials, rate limiting, server errors or an unreachable API.
+	exitAPIFailure = 2
+	// Some files failed while the others were processed.
+	exitPartialFailure = 3
+)
+
+// runOutcome summarizes how a rewrite command went.
+type runOutcome struct {
+	// The command failed, e.g. an action couldn't be resolved or a file couldn't be written.
+	failed bool
+	// The failure was caused by the GitHub API, see ghafix.IsAPIFailure.
+	apiFailure bool
+	// The failure only affected some of the files.
+	partial bool
+	// Files were changed, or would be in dry-run/check mode.
+	changed bool
+	dryRun  bool
+	check   bool
+	// Files were pinned by a pre-commit hook, which must block the commit until they are staged again.
+	preCommit bool
+}
+
+// failedOutcome is the outcome of a rewrite command that returned err along with res.
+func failedOutcome(res ghafix.Result, err error) runOutcome {
+	return runOutcome{
+		failed:     true,
+		apiFailure: ghafix.IsAPIFailure(err),
+		partial:    res.FailedCount > 0 && res.FailedCount < res.TotalCount,
+		changed:    res.Changed,
+	}
+}
+
+// exitPolicy decides the process exit code of a rewrite command. All commands go through it so that the exit
+// semantics stay consistent:
+//
+//   - API failure: 2, even if only some files failed
+//   - partial failure: 3
+//   - other failures: 1
+//   - check mode with pending changes: 1
+//   - pre-commit mode with changes written: 1
+//   - dry-run with pending changes: dryRunExitCode (0 by default, so previews don't break pipelines)
+//   - otherwise: 0
+type exitPolicy struct {
+	dryRunExitCode int
+}
+
+func (p exitPolicy) code(o runOutcome) int {
+	switch {
+	case o.failed && o.apiFailure:
+		return exitAPIFailure
+	case o.failed && o.partial:
+		return exitPartialFailure
+	case o.failed:
+		return exitFailure
+	case !o.changed:
+		return exitOK
+	case o.check, o.preCommit:
+		return exitFailure
+	case o.dryRun:
+		return p.dryRunExitCode
+	default:
+		return exitOK
+	}
+}
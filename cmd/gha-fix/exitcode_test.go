@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-github/v72/github"
+	"github.com/stretchr/testify/assert"
+
+	ghafix "github.com/Finatext/gha-fix"
+)
+
+func TestExitPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		outcome        runOutcome
+		dryRunExitCode int
+		expected       int
+	}{
+		{name: "No changes", outcome: runOutcome{}, expected: 0},
+		{name: "Changes written", outcome: runOutcome{changed: true}, expected: 0},
+		{name: "Failure", outcome: runOutcome{failed: true}, expected: 1},
+		{name: "Failure with changes", outcome: runOutcome{failed: true, changed: true}, expected: 1},
+		{name: "API failure", outcome: runOutcome{failed: true, apiFailure: true}, expected: 2},
+		{name: "Partial failure", outcome: runOutcome{failed: true, partial: true, changed: true}, expected: 3},
+		{name: "API failure takes precedence over partial failure", outcome: runOutcome{failed: true, apiFailure: true, partial: true}, expected: 2},
+		{name: "Check failure", outcome: runOutcome{check: true, failed: true, partial: true}, expected: 3},
+		{name: "Dry-run without changes", outcome: runOutcome{dryRun: true}, dryRunExitCode: 2, expected: 0},
+		{name: "Dry-run with changes, default", outcome: runOutcome{dryRun: true, changed: true}, expected: 0},
+		{name: "Dry-run with changes, custom code", outcome: runOutcome{dryRun: true, changed: true}, dryRunExitCode: 2, expected: 2},
+		{name: "Dry-run failure ignores the custom code", outcome: runOutcome{dryRun: true, failed: true}, dryRunExitCode: 2, expected: 1},
+		{name: "Check without changes", outcome: runOutcome{check: true}, expected: 0},
+		{name: "Check with changes", outcome: runOutcome{check: true, changed: true}, expected: 1},
+		{name: "Check ignores the dry-run code", outcome: runOutcome{check: true, changed: true}, dryRunExitCode: 2, expected: 1},
+		{name: "Pre-commit without changes", outcome: runOutcome{preCommit: true}, expected: 0},
+		{name: "Pre-commit with changes", outcome: runOutcome{preCommit: true, changed: true}, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := exitPolicy{dryRunExitCode: tt.dryRunExitCode}
+			assert.Equal(t, tt.expected, p.code(tt.outcome))
+		})
+	}
+}
+
+func TestFailedOutcome(t *testing.T) {
+	unauthorized := &github.ErrorResponse{
+		Response: &http.Response{StatusCode: http.StatusUnauthorized, Request: &http.Request{Method: http.MethodGet, URL: &url.URL{}}},
+		Message:  "Bad credentials",
+	}
+	fileErr := errors.New("specified tag not found")
+
+	tests := []struct {
+		name     string
+		result   ghafix.Result
+		err      error
+		expected int
+	}{
+		{name: "Single file failure", result: ghafix.Result{TotalCount: 1, FailedCount: 1}, err: fileErr, expected: 1},
+		{name: "All files failed", result: ghafix.Result{TotalCount: 2, FailedCount: 2}, err: errors.Join(fileErr, fileErr), expected: 1},
+		{name: "Some files failed", result: ghafix.Result{TotalCount: 3, FailedCount: 1}, err: errors.Join(fileErr), expected: 3},
+		{
+			name:     "Rejected credentials",
+			result:   ghafix.Result{TotalCount: 3, FailedCount: 1},
+			err:      errors.Join(fileErr, errors.Wrap(unauthorized, "failed to process file: a.yml")),
+			expected: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exitPolicy{}.code(failedOutcome(tt.result, tt.err)))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	ghafix "github.com/Finatext/gha-fix"
+)
+
+// runPreCommit pins the files passed by a pre-commit hook, printing one line per pinned action to w, and returns the
+// exit code. Files are never discovered: without arguments nothing matched the hook and there is nothing to do.
+func runPreCommit(ctx context.Context, pinCmd ghafix.PinCommand, files []string, w io.Writer) int {
+	if len(files) == 0 {
+		return exitOK
+	}
+
+	result, err := pinCmd.Run(ctx, files)
+	for _, f := range result.Files {
+		for _, c := range f.Changes {
+			action := c.Owner + "/" + c.Repo
+			if c.Path != "" {
+				action += "/" + c.Path
+			}
+			fmt.Fprintf(w, "%s:%d: pinned %s@%s\n", f.Path, c.Line, action, c.FromRef)
+		}
+	}
+	if err != nil {
+		slog.Error("failed to pin actions", "error", err)
+		return exitPolicy{}.code(failedOutcome(result, err))
+	}
+	if result.Changed {
+		fmt.Fprintln(w, "gha-fix pinned GitHub Actions; review and stage the changes, then commit again")
+	}
+	return exitPolicy{}.code(runOutcome{changed: result.Changed, preCommit: true})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ghafix "github.com/Finatext/gha-fix"
+	"github.com/Finatext/gha-fix/internal/githubclient"
+)
+
+const checkoutSHA = "11bd71901bbe5b1630ceea73d27597364c9af683"
+
+func TestRunPreCommit(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/actions/checkout/tags":
+			_, _ = io.WriteString(w, `[{"name":"v4.2.2","commit":{"sha":"`+checkoutSHA+`"}}]`)
+		case "/api/v3/repos/actions/checkout/git/ref/tags/v4.2.2":
+			_, _ = io.WriteString(w, `{"ref":"refs/tags/v4.2.2","object":{"type":"commit","sha":"`+checkoutSHA+`"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message":"Not Found"}`)
+		}
+	}))
+	defer api.Close()
+
+	pinned := "steps:\n  - uses: actions/checkout@" + checkoutSHA + " # v4.2.2\n"
+	tests := []struct {
+		name       string
+		staged     map[string]string
+		wantCode   int
+		wantOutput string
+	}{
+		{
+			name:       "Unpinned action blocks the commit",
+			staged:     map[string]string{"ci.yml": "steps:\n  - uses: actions/checkout@v4\n"},
+			wantCode:   1,
+			wantOutput: "ci.yml:2: pinned actions/checkout@v4\ngha-fix pinned GitHub Actions; review and stage the changes, then commit again\n",
+		},
+		{
+			name:     "Pinned actions pass",
+			staged:   map[string]string{"ci.yml": pinned},
+			wantCode: 0,
+		},
+		{
+			name:     "No staged files",
+			wantCode: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := githubclient.NewClient("token", api.URL+"/api/v3/")
+			require.NoError(t, err)
+			pinCmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{})
+
+			dir := t.TempDir()
+			t.Chdir(dir)
+			var files []string
+			for name, content := range tt.staged {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+				files = append(files, name)
+			}
+
+			var out bytes.Buffer
+			code := runPreCommit(context.Background(), pinCmd, files, &out)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantOutput, out.String())
+			for name := range tt.staged {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				assert.Equal(t, pinned, string(content))
+			}
+		})
+	}
+}
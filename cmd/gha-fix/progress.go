@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	ghafix "github.com/Finatext/gha-fix"
+	"golang.org/x/term"
+)
+
+// progressLine renders the progress of a pin run on a single, continuously rewritten terminal line.
+type progressLine struct {
+	w       io.Writer
+	written bool
+}
+
+// newProgressLine returns a progressLine writing to f, or nil when disabled or when f isn't a terminal, so that CI
+// logs aren't filled with carriage returns.
+func newProgressLine(enabled bool, f *os.File) *progressLine {
+	if !enabled || !term.IsTerminal(int(f.Fd())) {
+		return nil
+	}
+	return &progressLine{w: f}
+}
+
+// update replaces the progress line with p. Calls must be serialized, as ghafix.PinOptions.Progress calls are.
+func (l *progressLine) update(p ghafix.Progress) {
+	// Clear the rest of the line in case the previous one was longer.
+	_, _ = fmt.Fprintf(l.w, "\r%s\033[K", formatProgress(p))
+	l.written = true
+}
+
+// finish ends the progress line so that following output starts on a new line.
+func (l *progressLine) finish() {
+	if l == nil || !l.written {
+		return
+	}
+	_, _ = fmt.Fprintln(l.w)
+}
+
+func formatProgress(p ghafix.Progress) string {
+	return fmt.Sprintf("processed %d/%d files, %d actions resolved, %d cache hits", p.Done, p.Total, p.Resolved, p.CacheHits)
+}
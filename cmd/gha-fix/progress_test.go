@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ghafix "github.com/Finatext/gha-fix"
+)
+
+func TestFormatProgress(t *testing.T) {
+	got := formatProgress(ghafix.Progress{Done: 123, Total: 400, Resolved: 58, CacheHits: 12})
+	assert.Equal(t, "processed 123/400 files, 58 actions resolved, 12 cache hits", got)
+}
+
+func TestProgressLine(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	assert.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	assert.Nil(t, newProgressLine(false, f), "disabled without --progress")
+	assert.Nil(t, newProgressLine(true, f), "disabled when not a terminal")
+	// A nil progressLine is safe to finish.
+	newProgressLine(true, f).finish()
+
+	var buf bytes.Buffer
+	l := &progressLine{w: &buf}
+	l.update(ghafix.Progress{Done: 1, Total: 2})
+	l.update(ghafix.Progress{Done: 2, Total: 2, Resolved: 3, CacheHits: 1})
+	l.finish()
+	assert.Equal(t, "\rprocessed 1/2 files, 0 actions resolved, 0 cache hits\033[K"+
+		"\rprocessed 2/2 files, 3 actions resolved, 1 cache hits\033[K\n", buf.String())
+}
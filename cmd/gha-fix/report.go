@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ghafix "github.com/Finatext/gha-fix"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report on the GitHub Actions used in workflow files without modifying them",
+	Long: `Report on the GitHub Actions used in workflow files without modifying them.
+
+Options shared by all report subcommands:
+  --github-token: GitHub token for accessing GitHub API (can also be set via GITHUB_TOKEN env var or report.github-token in config)
+  --ghes-github-token: GitHub token for GitHub Enterprise Server (can also be set via GHES_GITHUB_TOKEN env var or report.ghes-github-token in config)
+  --github-token-file, --ghes-github-token-file: Read the tokens from files (trimmed), e.g. Kubernetes or Docker secret mounts
+  --api-server: Full GitHub API base URL (defaults to https://api.github.com/ when not specified)
+  --fail-on-fallback: Fail instead of falling back to GitHub.com when the GHES API returns 404
+  --retry-budget: Total number of API retries allowed across the whole run (default 0 = unlimited)`,
+}
+
+var reportTrustCmd = &cobra.Command{
+	Use:   "trust [file1 file2 ...]",
+	Short: "Report signature verification and age of the commits GitHub Actions resolve to",
+	Long: `Report, for each distinct owner/repo@ref used in workflow files, the commit it resolves to,
+whether GitHub verified the commit signature, and how old the commit is.
+
+Pinned references are reported with the highest tag pointing at their commit, if any.
+This helps triage supply-chain risk: unsigned or very old commits deserve a closer look.
+Usage:
+  report trust [file1 file2 ...]
+If no files are specified, all workflow files (.yml or .yaml) in the current directory
+and subdirectories will be processed. Pass '-' as the only file to read from stdin.
+
+The report is written to stdout as a table:
+  ACTION  REF  COMMIT  TAG  VERIFIED  AGE
+
+Global options:
+  --ignore-dirs: Skip specific directories when searching for workflow files (e.g., "node_modules,dist")
+  --include-action-yml-names: Only discover workflows under .github/workflows/ and action.yml/action.yaml files
+  --max-depth: Skip directories nested deeper than this below the current directory (default 0 = unlimited)
+
+Note: GITHUB_TOKEN environment variable is required to fetch tags and commits from GitHub.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := commandContext(0)
+		defer cancel()
+
+		primaryClient, fallbackClient := newGitHubClients("report", true)
+
+		trustCmd := ghafix.NewTrustReportCommand(primaryClient, fallbackClient, ghafix.TrustReportOptions{
+			IgnoreDirs:      viper.GetStringSlice("ignore-dirs"), // Use common ignore-dirs configuration
+			ActionFilesOnly: viper.GetBool("include-action-yml-names"),
+			MaxDepth:        viper.GetInt("max-depth"),
+			FailOnFallback:  viper.GetBool("report.fail-on-fallback"),
+			RetryBudget:     viper.GetInt("report.retry-budget"),
+		})
+
+		entries, err := trustCmd.Run(ctx, args)
+		printTrustEntries(entries)
+		if err != nil {
+			slog.Error("failed to report some actions", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func printTrustEntries(entries []ghafix.TrustEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tREF\tCOMMIT\tTAG\tVERIFIED\tAGE")
+	for _, e := range entries {
+		tag := e.Tag
+		if tag == "" {
+			tag = "-"
+		}
+		verified := "yes"
+		if !e.Verified {
+			verified = "no (" + e.VerificationReason + ")"
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\t%s\t%dd\n",
+			e.Owner, e.Repo, e.Ref, e.CommitSHA, tag, verified, int(e.Age/(24*time.Hour)))
+	}
+	cobra.CheckErr(w.Flush())
+}
+
+var reportConsolidateCmd = &cobra.Command{
+	Use:   "consolidate [file1 file2 ...]",
+	Short: "Suggest a single version for actions used with several refs",
+	Long: `Suggest, for each action used with several refs across workflow files (e.g. v4, v4.1 and v4.1.1),
+the single version all occurrences could be pinned to: the highest tag satisfying every ref.
+
+Pinned references count as the version of their comment (e.g. "@<sha> # v4.1.1" counts as v4.1.1).
+Actions whose refs have no common version (e.g. v3 and v4, or a branch) are listed without a suggestion.
+Usage:
+  report consolidate [file1 file2 ...]
+If no files are specified, all workflow files (.yml or .yaml) in the current directory
+and subdirectories will be processed. Pass '-' as the only file to read from stdin.
+
+The report is written to stdout as a table:
+  ACTION  REFS  VERSION  COMMIT
+
+Global options:
+  --ignore-dirs: Skip specific directories when searching for workflow files (e.g., "node_modules,dist")
+  --include-action-yml-names: Only discover workflows under .github/workflows/ and action.yml/action.yaml files
+  --max-depth: Skip directories nested deeper than this below the current directory (default 0 = unlimited)
+
+Note: GITHUB_TOKEN environment variable is required to fetch tags from GitHub.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := commandContext(0)
+		defer cancel()
+
+		primaryClient, fallbackClient := newGitHubClients("report", true)
+
+		consolidateCmd := ghafix.NewConsolidateReportCommand(primaryClient, fallbackClient, ghafix.ConsolidateReportOptions{
+			IgnoreDirs:      viper.GetStringSlice("ignore-dirs"), // Use common ignore-dirs configuration
+			ActionFilesOnly: viper.GetBool("include-action-yml-names"),
+			MaxDepth:        viper.GetInt("max-depth"),
+			FailOnFallback:  viper.GetBool("report.fail-on-fallback"),
+			RetryBudget:     viper.GetInt("report.retry-budget"),
+		})
+
+		entries, err := consolidateCmd.Run(ctx, args)
+		printConsolidationEntries(entries)
+		if err != nil {
+			slog.Error("failed to report some actions", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func printConsolidationEntries(entries []ghafix.ConsolidationEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tREFS\tVERSION\tCOMMIT")
+	for _, e := range entries {
+		version, commit := e.Version, e.CommitSHA
+		if version == "" {
+			version, commit = "-", "-"
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\n", e.Owner, e.Repo, strings.Join(e.Refs, ","), version, commit)
+	}
+	cobra.CheckErr(w.Flush())
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportTrustCmd)
+	reportCmd.AddCommand(reportConsolidateCmd)
+
+	reportCmd.PersistentFlags().String("github-token", "", "GitHub token for accessing GitHub API (can also be set via GITHUB_TOKEN env var or report.github-token in config)")
+	cobra.CheckErr(viper.BindPFlag("report.github-token", reportCmd.PersistentFlags().Lookup("github-token")))
+	cobra.CheckErr(viper.BindEnv("report.github-token", "GITHUB_TOKEN"))
+
+	reportCmd.PersistentFlags().String("ghes-github-token", "", "GitHub token for GHES API calls (can also be set via GHES_GITHUB_TOKEN env var or report.ghes-github-token in config)")
+	cobra.CheckErr(viper.BindPFlag("report.ghes-github-token", reportCmd.PersistentFlags().Lookup("ghes-github-token")))
+	cobra.CheckErr(viper.BindEnv("report.ghes-github-token", "GHES_GITHUB_TOKEN"))
+
+	reportCmd.PersistentFlags().String("github-token-file", "", "File containing the GitHub token, e.g. a mounted secret (instead of --github-token/GITHUB_TOKEN)")
+	cobra.CheckErr(viper.BindPFlag("report.github-token-file", reportCmd.PersistentFlags().Lookup("github-token-file")))
+
+	reportCmd.PersistentFlags().String("ghes-github-token-file", "", "File containing the GHES token, e.g. a mounted secret (instead of --ghes-github-token/GHES_GITHUB_TOKEN)")
+	cobra.CheckErr(viper.BindPFlag("report.ghes-github-token-file", reportCmd.PersistentFlags().Lookup("ghes-github-token-file")))
+
+	reportCmd.PersistentFlags().String("api-server", "", "Full GitHub API base URL (e.g., https://github.enterprise.company.com/api/v3/)")
+	cobra.CheckErr(viper.BindPFlag("report.api-server", reportCmd.PersistentFlags().Lookup("api-server")))
+
+	reportCmd.PersistentFlags().Bool("fail-on-fallback", false, "Fail instead of falling back to GitHub.com when the GHES API returns 404")
+	cobra.CheckErr(viper.BindPFlag("report.fail-on-fallback", reportCmd.PersistentFlags().Lookup("fail-on-fallback")))
+
+	reportCmd.PersistentFlags().Int("retry-budget", 0, "Total number of API retries allowed across the whole run (0 = unlimited)")
+	cobra.CheckErr(viper.BindPFlag("report.retry-budget", reportCmd.PersistentFlags().Lookup("retry-budget")))
+}
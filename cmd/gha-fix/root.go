@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	ghafix "github.com/Finatext/gha-fix"
 	"github.com/phsym/console-slog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -27,8 +32,10 @@ func Execute() {
 	}
 }
 
+// logLevel is the level of the default logger, set from log-level.
+var logLevel = new(slog.LevelVar)
+
 func init() {
-	logLevel := new(slog.LevelVar)
 	slog.SetDefault(slog.New(console.NewHandler(os.Stderr, &console.HandlerOptions{
 		Level:      logLevel,
 		NoColor:    !term.IsTerminal(int(os.Stderr.Fd())),
@@ -57,6 +64,21 @@ func init() {
 		}
 	})
 
+	rootCmd.PersistentFlags().Bool("include-action-yml-names", false, "Only discover workflows under .github/workflows/ and action.yml/action.yaml files instead of any YAML file")
+
+	rootCmd.PersistentFlags().Int64("github-app-id", 0, "GitHub App ID; with the installation ID and private key, authenticates API calls as the app installation instead of a token")
+	rootCmd.PersistentFlags().Int64("github-app-installation-id", 0, "Installation ID of the GitHub App")
+	rootCmd.PersistentFlags().String("github-app-private-key-file", "", "Path to the PEM private key of the GitHub App")
+	rootCmd.PersistentFlags().String("proxy", "", "HTTP(S) proxy URL for GitHub API calls (default: HTTPS_PROXY/HTTP_PROXY env vars)")
+	rootCmd.PersistentFlags().String("ca-cert", "", "PEM bundle of CA certificates to trust for GitHub API calls, e.g. the internal CA of a GHES instance")
+	rootCmd.PersistentFlags().Bool("insecure-skip-tls-verify", false, "Skip TLS certificate verification of GitHub API calls (insecure; test environments only)")
+
+	rootCmd.PersistentFlags().Int("max-depth", 0, "Skip directories nested deeper than this below the current directory when searching for workflow files (0 = unlimited)")
+
+	rootCmd.PersistentFlags().Int("concurrency", 0, "Number of files processed in parallel (0 = GOMAXPROCS)")
+
+	rootCmd.PersistentFlags().String("report-path-style", "", "Report file paths relative to the current directory or absolute (relative, absolute; default: as given)")
+
 	// Bind the ignore-dirs flag explicitly to ensure it's available globally
 	cobra.CheckErr(viper.BindPFlag("ignore-dirs", rootCmd.PersistentFlags().Lookup("ignore-dirs")))
 
@@ -64,6 +86,32 @@ func init() {
 	cobra.CheckErr(viper.BindPFlags(rootCmd.PersistentFlags()))
 }
 
+// commandContext returns the context of a command run. It is canceled on SIGINT or SIGTERM, aborting pending GitHub
+// API calls so that the run ends with their errors instead of being killed mid-write, and after timeout when positive.
+// A second signal kills the process as usual.
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// reportPathStyle returns the validated report-path-style setting, exiting on invalid values.
+func reportPathStyle() ghafix.PathStyle {
+	style, err := ghafix.ParsePathStyle(viper.GetString("report-path-style"))
+	if err != nil {
+		slog.Error("invalid report-path-style", "error", err)
+		os.Exit(1)
+	}
+	return style
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"log/slog"
 	"os"
 
@@ -27,9 +26,16 @@ and subdirectories will be processed.
 
 You can customize the behavior with the following options:
   --timeout-value, -t: The timeout value in minutes to add (default: 5)
+  --only-jobs: Only touch jobs whose ID matches one of these names or globs (e.g. build,test-*)
+  --overwrite: Replace existing timeout-minutes values that differ from --timeout-value
+  --ci: Syntax of the files: auto (default; .gitlab-ci.yml files as GitLab CI, other files as GitHub Actions), github or gitlab
 
 Global options:
   --ignore-dirs: Skip specific directories when searching for workflow files
+  --include-action-yml-names: Only discover workflows under .github/workflows/ and action.yml/action.yaml files
+  --max-depth: Skip directories nested deeper than this below the current directory (default 0 = unlimited)
+  --concurrency: Number of files processed in parallel (default 0 = GOMAXPROCS)
+  --report-path-style: Report file paths relative to the current directory or absolute (default: as given)
 
 Example:
   # Add default 5-minute timeout to all jobs
@@ -39,10 +45,17 @@ Example:
   gha-fix timeout -t 10 .github/workflows/build.yml
 
   # Process all files but ignore certain directories
-  gha-fix --ignore-dirs node_modules,dist timeout --timeout-value 15`,
+  gha-fix --ignore-dirs node_modules,dist timeout --timeout-value 15
+
+  # Force a 30-minute timeout on the deploy job, replacing any existing value
+  gha-fix timeout -t 30 --only-jobs deploy --overwrite
+
+  # Add a 30-minute timeout (timeout: 30m) to the jobs of GitLab CI files included by .gitlab-ci.yml
+  gha-fix timeout -t 30 --ci gitlab ci/*.yml`,
 
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		ctx, cancel := commandContext(0)
+		defer cancel()
 
 		// Get values from viper which can come from flags, config file, or environment variables
 		timeoutValue := viper.GetUint64("timeout.timeout-value")
@@ -52,10 +65,22 @@ Example:
 			slog.Error("timeout value must be greater than 0")
 			os.Exit(1)
 		}
+		ci, err := ghafix.ParseCI(viper.GetString("timeout.ci"))
+		if err != nil {
+			slog.Error("invalid ci", "error", err)
+			os.Exit(1)
+		}
 
 		timeoutCmd := ghafix.NewTimeoutCommand(ghafix.TimeoutOptions{
-			IgnoreDirs:     ignoreDirs,
-			TimeoutMinutes: timeoutValue,
+			IgnoreDirs:      ignoreDirs,
+			ActionFilesOnly: viper.GetBool("include-action-yml-names"),
+			MaxDepth:        viper.GetInt("max-depth"),
+			Concurrency:     viper.GetInt("concurrency"),
+			PathStyle:       reportPathStyle(),
+			TimeoutMinutes:  timeoutValue,
+			OnlyJobs:        viper.GetStringSlice("timeout.only-jobs"),
+			Overwrite:       viper.GetBool("timeout.overwrite"),
+			CI:              ci,
 		})
 
 		result, err := timeoutCmd.Run(ctx, args)
@@ -76,6 +101,12 @@ func init() {
 	rootCmd.AddCommand(timeoutCmd)
 
 	timeoutCmd.Flags().Uint64P("timeout-value", "t", 5, "Timeout value in minutes to add to jobs")
+	timeoutCmd.Flags().StringSlice("only-jobs", []string{}, "Only touch jobs whose ID matches one of these names or globs")
+	timeoutCmd.Flags().Bool("overwrite", false, "Replace existing timeout-minutes values that differ from --timeout-value")
+	timeoutCmd.Flags().String("ci", "auto", "Syntax of the files: auto (.gitlab-ci.yml files as GitLab CI, other files as GitHub Actions), github or gitlab")
 
 	cobra.CheckErr(viper.BindPFlag("timeout.timeout-value", timeoutCmd.Flags().Lookup("timeout-value")))
+	cobra.CheckErr(viper.BindPFlag("timeout.only-jobs", timeoutCmd.Flags().Lookup("only-jobs")))
+	cobra.CheckErr(viper.BindPFlag("timeout.overwrite", timeoutCmd.Flags().Lookup("overwrite")))
+	cobra.CheckErr(viper.BindPFlag("timeout.ci", timeoutCmd.Flags().Lookup("ci")))
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	ghafix "github.com/Finatext/gha-fix"
+	"github.com/google/go-github/v72/github"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin [file1 file2 ...]",
+	Short: "Replace pinned commit SHAs with the version refs recorded in their comments",
+	Long: `Replace pinned commit SHAs in workflow files with the version refs recorded in their comments.
+
+This command reverses 'pin': it rewrites 'owner/repo@<sha> # v4.1.1' back to 'owner/repo@v4.1.1'.
+Lines without a ref comment are left untouched.
+Usage:
+  unpin [file1 file2 ...]
+If no files are specified, all workflow files (.yml or .yaml) in the current directory
+and subdirectories will be processed. Pass '-' as the only file to read from stdin and write the result to stdout.
+
+You can customize the behavior with the following options:
+  --force-api: Look up a tag pointing at the commit via the GitHub API when a line has no ref comment
+  --github-token: GitHub token for accessing GitHub API (can also be set via GITHUB_TOKEN env var or unpin.github-token in config)
+  --ghes-github-token: GitHub token for GitHub Enterprise Server (can also be set via GHES_GITHUB_TOKEN env var or unpin.ghes-github-token in config)
+  --github-token-file, --ghes-github-token-file: Read the tokens from files (trimmed), e.g. Kubernetes or Docker secret mounts
+  --api-server: Full GitHub API base URL (defaults to https://api.github.com/ when not specified)
+
+Global options:
+  --ignore-dirs: Skip specific directories when searching for workflow files (e.g., "node_modules,dist")
+  --include-action-yml-names: Only discover workflows under .github/workflows/ and action.yml/action.yaml files
+  --max-depth: Skip directories nested deeper than this below the current directory (default 0 = unlimited)
+  --concurrency: Number of files processed in parallel (default 0 = GOMAXPROCS)
+  --report-path-style: Report file paths relative to the current directory or absolute (default: as given)
+
+Note: a GitHub token is only required with --force-api.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := commandContext(0)
+		defer cancel()
+
+		forceAPI := viper.GetBool("unpin.force-api")
+		ignoreDirs := viper.GetStringSlice("ignore-dirs") // Use common ignore-dirs configuration
+
+		var primaryClient, fallbackClient *github.Client
+		if forceAPI {
+			primaryClient, fallbackClient = newGitHubClients("unpin", true)
+		}
+
+		unpinCmd := ghafix.NewUnpinCommand(primaryClient, fallbackClient, ghafix.UnpinOptions{
+			IgnoreDirs:      ignoreDirs,
+			ActionFilesOnly: viper.GetBool("include-action-yml-names"),
+			MaxDepth:        viper.GetInt("max-depth"),
+			Concurrency:     viper.GetInt("concurrency"),
+			PathStyle:       reportPathStyle(),
+			ForceAPI:        forceAPI,
+		})
+
+		result, err := unpinCmd.Run(ctx, args)
+		if err != nil {
+			slog.Error("failed to unpin actions", "error", err)
+			os.Exit(exitPolicy{}.code(failedOutcome(result, err)))
+		}
+
+		if !result.Changed {
+			slog.Info("no changes needed. no pinned GitHub Actions with a recoverable ref found.")
+		} else {
+			slog.Info("successfully unpinned GitHub Actions", slog.Int("changed", result.FileCount))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unpinCmd)
+
+	unpinCmd.Flags().Bool("force-api", false, "Look up a tag pointing at the commit via the GitHub API when a line has no ref comment")
+	cobra.CheckErr(viper.BindPFlag("unpin.force-api", unpinCmd.Flags().Lookup("force-api")))
+
+	unpinCmd.Flags().String("github-token", "", "GitHub token for accessing GitHub API (can also be set via GITHUB_TOKEN env var or unpin.github-token in config)")
+	cobra.CheckErr(viper.BindPFlag("unpin.github-token", unpinCmd.Flags().Lookup("github-token")))
+	cobra.CheckErr(viper.BindEnv("unpin.github-token", "GITHUB_TOKEN"))
+
+	unpinCmd.Flags().String("ghes-github-token", "", "GitHub token for GHES API calls (can also be set via GHES_GITHUB_TOKEN env var or unpin.ghes-github-token in config)")
+	cobra.CheckErr(viper.BindPFlag("unpin.ghes-github-token", unpinCmd.Flags().Lookup("ghes-github-token")))
+	cobra.CheckErr(viper.BindEnv("unpin.ghes-github-token", "GHES_GITHUB_TOKEN"))
+
+	unpinCmd.Flags().String("github-token-file", "", "File containing the GitHub token, e.g. a mounted secret (instead of --github-token/GITHUB_TOKEN)")
+	cobra.CheckErr(viper.BindPFlag("unpin.github-token-file", unpinCmd.Flags().Lookup("github-token-file")))
+
+	unpinCmd.Flags().String("ghes-github-token-file", "", "File containing the GHES token, e.g. a mounted secret (instead of --ghes-github-token/GHES_GITHUB_TOKEN)")
+	cobra.CheckErr(viper.BindPFlag("unpin.ghes-github-token-file", unpinCmd.Flags().Lookup("ghes-github-token-file")))
+
+	unpinCmd.Flags().String("api-server", "", "Full GitHub API base URL (e.g., https://github.enterprise.company.com/api/v3/)")
+	cobra.CheckErr(viper.BindPFlag("unpin.api-server", unpinCmd.Flags().Lookup("api-server")))
+}
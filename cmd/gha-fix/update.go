@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	ghafix "github.com/Finatext/gha-fix"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [file1 file2 ...]",
+	Short: "Bump pinned GitHub Actions to the latest tag matching their version comment",
+	Long: `Bump GitHub Actions pinned to commit SHAs to the latest tag matching the version recorded in their comments.
+
+This command reads the '# vX.Y.Z' comment next to a pinned SHA, treats its major version as the constraint,
+and rewrites both the SHA and the comment when a newer matching tag exists.
+For example, 'actions/checkout@<sha> # v4.1.1' becomes 'actions/checkout@<newer sha> # v4.2.2'.
+Pinned lines without a version comment are skipped with a warning.
+Usage:
+  update [file1 file2 ...]
+If no files are specified, all workflow files (.yml or .yaml) in the current directory
+and subdirectories will be processed. Pass '-' as the only file to read from stdin and write the result to stdout.
+
+You can customize the behavior with the following options:
+  --same-minor: Only update within the current minor line (v4.1.1 -> v4.1.x) instead of the major line
+  --replace-only-if-newer: Only replace a SHA when the new commit is strictly newer than the pinned one by commit date
+  --github-token: GitHub token for accessing GitHub API (can also be set via GITHUB_TOKEN env var or update.github-token in config)
+  --ghes-github-token: GitHub token for GitHub Enterprise Server (can also be set via GHES_GITHUB_TOKEN env var or update.ghes-github-token in config)
+  --github-token-file, --ghes-github-token-file: Read the tokens from files (trimmed), e.g. Kubernetes or Docker secret mounts
+  --api-server: Full GitHub API base URL (defaults to https://api.github.com/ when not specified)
+  --fail-on-fallback: Fail instead of falling back to GitHub.com when the GHES API returns 404
+  --retry-budget: Total number of API retries allowed across the whole run (default 0 = unlimited)
+
+Global options:
+  --ignore-dirs: Skip specific directories when searching for workflow files (e.g., "node_modules,dist")
+  --include-action-yml-names: Only discover workflows under .github/workflows/ and action.yml/action.yaml files
+  --max-depth: Skip directories nested deeper than this below the current directory (default 0 = unlimited)
+  --concurrency: Number of files processed in parallel (default 0 = GOMAXPROCS)
+  --report-path-style: Report file paths relative to the current directory or absolute (default: as given)
+
+Note: GITHUB_TOKEN environment variable is required to fetch tags and commit SHAs from GitHub.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := commandContext(0)
+		defer cancel()
+
+		primaryClient, fallbackClient := newGitHubClients("update", true)
+
+		updateCmd := ghafix.NewUpdateCommand(primaryClient, fallbackClient, ghafix.UpdateOptions{
+			IgnoreDirs:         viper.GetStringSlice("ignore-dirs"), // Use common ignore-dirs configuration
+			ActionFilesOnly:    viper.GetBool("include-action-yml-names"),
+			MaxDepth:           viper.GetInt("max-depth"),
+			Concurrency:        viper.GetInt("concurrency"),
+			PathStyle:          reportPathStyle(),
+			SameMinor:          viper.GetBool("update.same-minor"),
+			ReplaceOnlyIfNewer: viper.GetBool("update.replace-only-if-newer"),
+			FailOnFallback:     viper.GetBool("update.fail-on-fallback"),
+			RetryBudget:        viper.GetInt("update.retry-budget"),
+		})
+
+		result, err := updateCmd.Run(ctx, args)
+		if err != nil {
+			slog.Error("failed to update actions", "error", err)
+			os.Exit(exitPolicy{}.code(failedOutcome(result, err)))
+		}
+
+		if !result.Changed {
+			slog.Info("no changes needed. all pinned GitHub Actions are up to date.")
+		} else {
+			slog.Info("successfully updated pinned GitHub Actions", slog.Int("changed", result.FileCount))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().String("github-token", "", "GitHub token for accessing GitHub API (can also be set via GITHUB_TOKEN env var or update.github-token in config)")
+	cobra.CheckErr(viper.BindPFlag("update.github-token", updateCmd.Flags().Lookup("github-token")))
+	cobra.CheckErr(viper.BindEnv("update.github-token", "GITHUB_TOKEN"))
+
+	updateCmd.Flags().String("ghes-github-token", "", "GitHub token for GHES API calls (can also be set via GHES_GITHUB_TOKEN env var or update.ghes-github-token in config)")
+	cobra.CheckErr(viper.BindPFlag("update.ghes-github-token", updateCmd.Flags().Lookup("ghes-github-token")))
+	cobra.CheckErr(viper.BindEnv("update.ghes-github-token", "GHES_GITHUB_TOKEN"))
+
+	updateCmd.Flags().String("github-token-file", "", "File containing the GitHub token, e.g. a mounted secret (instead of --github-token/GITHUB_TOKEN)")
+	cobra.CheckErr(viper.BindPFlag("update.github-token-file", updateCmd.Flags().Lookup("github-token-file")))
+
+	updateCmd.Flags().String("ghes-github-token-file", "", "File containing the GHES token, e.g. a mounted secret (instead of --ghes-github-token/GHES_GITHUB_TOKEN)")
+	cobra.CheckErr(viper.BindPFlag("update.ghes-github-token-file", updateCmd.Flags().Lookup("ghes-github-token-file")))
+
+	updateCmd.Flags().String("api-server", "", "Full GitHub API base URL (e.g., https://github.enterprise.company.com/api/v3/)")
+	cobra.CheckErr(viper.BindPFlag("update.api-server", updateCmd.Flags().Lookup("api-server")))
+
+	updateCmd.Flags().Bool("same-minor", false, "Only update within the current minor line (v4.1.1 -> v4.1.x) instead of the major line")
+	cobra.CheckErr(viper.BindPFlag("update.same-minor", updateCmd.Flags().Lookup("same-minor")))
+
+	updateCmd.Flags().Bool("replace-only-if-newer", false, "Only replace a SHA when the new commit is strictly newer than the pinned one by commit date")
+	cobra.CheckErr(viper.BindPFlag("update.replace-only-if-newer", updateCmd.Flags().Lookup("replace-only-if-newer")))
+
+	updateCmd.Flags().Bool("fail-on-fallback", false, "Fail instead of falling back to GitHub.com when the GHES API returns 404")
+	cobra.CheckErr(viper.BindPFlag("update.fail-on-fallback", updateCmd.Flags().Lookup("fail-on-fallback")))
+
+	updateCmd.Flags().Int("retry-budget", 0, "Total number of API retries allowed across the whole run (0 = unlimited)")
+	cobra.CheckErr(viper.BindPFlag("update.retry-budget", updateCmd.Flags().Lookup("retry-budget")))
+}
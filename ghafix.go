@@ -2,10 +2,17 @@ package ghafix
 
 import (
 	"context"
+	"io"
+	"log/slog"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	gogithub "github.com/google/go-github/v72/github"
 
+	internalpin "github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/report"
 	"github.com/Finatext/gha-fix/internal/rewrite"
+	"github.com/Finatext/gha-fix/internal/sarif"
 	"github.com/Finatext/gha-fix/pin"
 	"github.com/Finatext/gha-fix/timeout"
 )
@@ -13,44 +20,829 @@ import (
 // Result represents the result of a auto-fix operation.
 type Result = rewrite.RewriteResult
 
+// Finding represents a line that an auto-fix operation would change.
+type Finding = rewrite.Finding
+
+// Change records a line pinned by PinCommand.Run: the action, its original ref and the commit SHA it was pinned to.
+type Change = rewrite.Change
+
+// FileChanges lists the changes made to one file, see Result.Files.
+type FileChanges = rewrite.FileChanges
+
+// ResolutionCache stores resolved action versions. Implement it to share resolutions between processes, e.g. backed
+// by Redis. A cache must only be shared between commands using the same API server and resolution options.
+type ResolutionCache = internalpin.Cache
+
+// CacheKey identifies a cached resolution.
+type CacheKey = internalpin.CacheKey
+
+// ResolvedVersion is a cached resolution: the commit SHA and the ref written in the comment.
+type ResolvedVersion = internalpin.ResolvedVersion
+
+// Resolver resolves action references to commit SHAs in place of the GitHub API. See pin.Resolver for the contract,
+// including the errors to return.
+type Resolver = pin.Resolver
+
+// ActionDef is an action reference (owner/repo[/path]@ref) passed to a Resolver.
+type ActionDef = pin.ActionDef
+
+// QuoteStyle controls how the `uses:` value of rewritten lines is quoted: keep, none, double or single.
+// Values YAML can't hold in the requested style keep their original quotes.
+type QuoteStyle = pin.QuoteStyle
+
+// ParseQuoteStyle parses a quote style name. An empty name keeps the original quotes.
+func ParseQuoteStyle(s string) (QuoteStyle, error) {
+	return pin.ParseQuoteStyle(s)
+}
+
+// Provider is the kind of API server of the primary client: github (GitHub.com or GHES) or gitea (Gitea or Forgejo).
+type Provider = pin.Provider
+
+// Providers of the primary API server, see Provider.
+const (
+	ProviderGitHub = pin.ProviderGitHub
+	ProviderGitea  = pin.ProviderGitea
+)
+
+// ParseProvider parses a provider name: github or gitea. An empty name means github.
+func ParseProvider(s string) (Provider, error) {
+	return pin.ParseProvider(s)
+}
+
+// Lockfile records what each owner/repo@ref resolved to, so that later runs pin the same commits. See LoadLockfile.
+type Lockfile = pin.Lockfile
+
+// LoadLockfile reads the YAML lockfile at path (e.g. gha-fix.lock), mapping owner/repo@ref to its locked commit SHA
+// and comment. A missing file is an empty lockfile, written by the first run recording a resolution.
+func LoadLockfile(path string) (*Lockfile, error) {
+	return pin.LoadLockfile(path)
+}
+
+// Allowlist is the set of pre-approved commit SHAs per action repository. See LoadAllowlist.
+type Allowlist = pin.Allowlist
+
+// LoadAllowlist reads an allowlist from a YAML file mapping owner/repo to the list of approved commit SHAs.
+func LoadAllowlist(path string) (*Allowlist, error) {
+	return pin.LoadAllowlist(path)
+}
+
+// PinTarget is what PinCommand pins version refs to: sha (default) or tag.
+type PinTarget = pin.PinTarget
+
+// ParsePinTarget parses a pin target name: sha or tag. An empty name means sha.
+func ParsePinTarget(s string) (PinTarget, error) {
+	return pin.ParsePinTarget(s)
+}
+
+// CI is the CI system whose syntax TimeoutCommand reads files with: auto (default), github or gitlab.
+type CI = timeout.CI
+
+// ParseCI parses a CI system name: auto, github or gitlab. An empty name means auto.
+func ParseCI(s string) (CI, error) {
+	return timeout.ParseCI(s)
+}
+
+// CommentTemplate renders the comment written after pinned commit SHAs. See ParseCommentTemplate.
+type CommentTemplate = pin.CommentTemplate
+
+// ParseCommentTemplate parses a text/template rendering the comment written after pinned commit SHAs, without the
+// leading "# ". It has the fields RefComment (the resolved ref, e.g. v4.1.1), Owner, Repo, Ref (the original ref) and
+// SHA. Invalid templates, including references to unknown fields, fail here.
+func ParseCommentTemplate(s string) (*CommentTemplate, error) {
+	return pin.ParseCommentTemplate(s)
+}
+
+// PathStyle controls how file paths are reported: as given (empty), relative to the current directory, or absolute.
+type PathStyle = rewrite.PathStyle
+
+// ParsePathStyle parses a path style name: relative or absolute. An empty name reports paths as given.
+func ParsePathStyle(s string) (PathStyle, error) {
+	return rewrite.ParsePathStyle(s)
+}
+
+// ValidateNamePatterns reports the invalid entries of an ignore or only list of PinOptions. Entries are exact names,
+// globs (e.g. myorg/*) or regular expressions wrapped in slashes (e.g. /^internal-/).
+func ValidateNamePatterns(list []string) error {
+	return pin.ValidateNamePatterns(list)
+}
+
+// ValidateFallbackStatuses reports an invalid PinOptions.FallbackStatuses: statuses that aren't HTTP errors, and 401
+// and 429, which must never fall back.
+func ValidateFallbackStatuses(statuses []int) error {
+	return internalpin.ValidateFallbackStatuses(statuses)
+}
+
+// IsAPIFailure reports whether err, as returned by a command, was caused by the GitHub API itself rather than by
+// specific actions: rejected credentials (401), rate limiting, server errors or an unreachable API.
+func IsAPIFailure(err error) bool {
+	return internalpin.IsAPIFailure(err)
+}
+
 // PinOptions defines options for the pin command.
 type PinOptions struct {
 	IgnoreOwners []string
 	IgnoreRepos  []string
-	IgnoreDirs   []string
+	// Only pin actions of these owners or owner/repo names; the ignore lists then apply within them.
+	OnlyOwners []string
+	OnlyRepos  []string
+	IgnoreDirs []string
+	// Only discover workflows under .github/workflows/ and action.yml/action.yaml files when no file is given.
+	ActionFilesOnly bool
+	// Skip directories nested deeper than this below the current directory when no file is given (.github/workflows
+	// is at depth 2). Zero means unlimited.
+	MaxDepth int
+	// Only pin the discovered files changed in git since this ref (e.g. origin/main), speeding up pull request checks
+	// of large repositories. Outside a git work tree, every discovered file is pinned with a warning.
+	Since string
+	// Number of files processed in parallel. Zero uses GOMAXPROCS.
+	Concurrency int
+	// Normalize the paths of given and discovered files in results, logs and diffs. Empty reports them as given.
+	PathStyle PathStyle
+	// Resolve actions and report which files would change without writing them.
+	DryRun bool
+	// When set, a unified diff of each file that would change is written to Diff instead of writing the files.
+	Diff io.Writer
+	// Number of unchanged lines shown around each change in Diff. Zero uses the default (3); negative shows none.
+	DiffContext int
+	// Only report the actions that changed a line: Result.Files leaves out the lines pinned as they already were,
+	// and Resolve the resolutions that change no line.
+	OnlyChangedActions bool
 	// Strict SHA pinning for new GitHub's SHA pinning enforcement policy. See README for details.
 	StrictPinning202508 bool
+	// Leave reusable workflows on their original refs while still pinning actions and composite actions.
+	ExcludeReusableWorkflows bool
+	// Resolve `git describe` style refs (e.g. v4.1.1-3-gabcdef0) to the embedded commit instead of a semver tag.
+	ResolveDescribe bool
+	// Fail instead of falling back to GitHub.com when the primary (GHES) API returns 404.
+	FailOnFallback bool
+	// Also fall back to GitHub.com when the primary (GHES) API denies access (403). By default a 403 fails with an
+	// error telling the token lacks access to the repository.
+	FallbackOnForbidden bool
+	// HTTP statuses of the primary (GHES) API falling back to GitHub.com, e.g. 500 for mirrored actions failing on
+	// GHES. Empty means 404 only. 401 never falls back. See ValidateFallbackStatuses.
+	FallbackStatuses []int
+	// Rewrite owner/repo with the canonical casing reported by the API instead of keeping the user's casing.
+	CanonicalizeNames bool
+	// Treat every v0 minor as breaking when resolving v0/v0.y refs.
+	V0Strict bool
+	// Let version refs resolve to pre-release tags (e.g. v4 to v4.1.0-rc.1), following semver precedence.
+	AllowPrerelease bool
+	// Resolve numeric-only refs (e.g. 4) to the branch of that name when it exists, instead of the latest 4.x.y tag.
+	PreferBranches bool
+	// Resolve the ref latest (e.g. actions/checkout@latest) to the newest release tag across all majors.
+	ResolveLatest bool
+	// Strip trailing whitespace from rewritten lines. Unmodified lines are never touched.
+	StripTrailingWhitespace bool
+	// Locate `uses:` keys with a YAML parser, so that lookalikes inside multi-line strings are never pinned. Files that
+	// aren't valid YAML fail.
+	YAMLMode bool
+	// Pin references without `@ref` (e.g. `uses: actions/checkout`) to the head of the default branch of their
+	// repository instead of leaving them as is.
+	AssumeDefaultBranch bool
+	// Check through the API that actions already pinned to a commit SHA reference an existing commit, failing the
+	// files holding a SHA that doesn't exist.
+	VerifyExistingSHA bool
+	// Rewrite commit SHAs already pinned with uppercase hex digits in lowercase.
+	NormalizeSHA bool
+	// Quoting of the `uses:` value of rewritten lines. Empty keeps the original quotes. See ParseQuoteStyle.
+	NormalizeQuotes QuoteStyle
+	// Renders the comment written after the commit SHA. Nil writes the resolved ref (e.g. `# v4.1.1`).
+	CommentTemplate *CommentTemplate
+	// Omit the comment after the commit SHA. Comments already on the line are kept.
+	NoComment bool
+	// Omit the comment after the commit SHA for refs resolved as branches (e.g. `# main`), keeping it for tags.
+	NoCommentOnBranchRefs bool
+	// Write the resolved version in the comment with a "v" prefix only if the original ref had one: v4 becomes
+	// `# v4.1.1` and 4 becomes `# 4.1.1`, even when the repository's tags are named the other way.
+	CommentMatchRefPrefix bool
+	// Append the resolution date to the comment after the commit SHA, e.g. `# v4.1.1 @2025-01-02`.
+	CommentIncludeDate bool
+	// Pin version refs to their fully qualified tag (v4 to `v4.1.1 # v4`) instead of the commit SHA. Empty means sha.
+	// Branches are still pinned to commit SHAs.
+	PinTo PinTarget
+	// Fail the file when an action can't be resolved (e.g. a missing tag or repository). By default the action's line
+	// is left unchanged with a warning and the rest of the file is pinned. FailOnFallback errors always fail.
+	FailOnUnresolvable bool
+	// Only pin to the pre-approved commit SHAs of the allowlist; other resolutions fail their line. Nil allows any SHA.
+	Allowlist *Allowlist
+	// Pin resolutions missing from the Allowlist anyway, logging a warning instead of failing.
+	AllowlistWarnOnly bool
+	// Pin `docker://image:tag` references to the manifest digest the tag points to, querying the image registry.
+	PinDocker bool
+	// Credentials for the registries queried with PinDocker. Empty authenticates anonymously (public images only).
+	RegistryUsername string
+	RegistryPassword string
+	// Total number of API retries (on 5xx and rate limit errors) allowed across the whole run. Zero means unlimited.
+	RetryBudget int
+	// Retries per API call on 5xx and rate limit errors. Zero uses the default (3); negative disables retries.
+	MaxRetries int
+	// Longest single wait between retries. Rate limits asking to wait longer fail instead. Zero uses the default (1m).
+	MaxBackoff time.Duration
+	// Log a warning for each rewritten line longer than this many characters. Zero disables the warning.
+	MaxLineLength int
+	// How long resolutions persisted in the on-disk cache stay valid. Zero (the default) disables the disk cache.
+	// Branch resolutions are never persisted, since their head moves.
+	CacheTTL time.Duration
+	// Location of the on-disk cache. Defaults to <user cache dir>/gha-fix/resolutions.json when empty.
+	CachePath string
+	// Replaces the default resolution cache (in-memory, or on-disk with CacheTTL).
+	Cache ResolutionCache
+	// Kind of API server of the primary client, which must then be created for it (e.g. with the Gitea API path).
+	// Empty means github.
+	Provider Provider
+	// Resolves action references instead of the GitHub API, e.g. from a lockfile; see Resolver. Nil uses the API.
+	Resolver Resolver
+	// Pin the refs recorded in the lockfile to their locked commit without calling the API, recording the resolutions
+	// of the others. Run and Resolve save it, except in dry-run. Nil disables the lockfile.
+	Lockfile *Lockfile
+	// Resolve every ref again, refreshing its lockfile entry, instead of pinning it to the locked commit.
+	UpdateLock bool
+	// Called by Run each time a file is processed. Calls are serialized. Nil disables progress reporting.
+	Progress func(Progress)
+	// Apply the per-directory configuration files (.gha-fix.yaml) found in the directory of each file and its parents,
+	// up to this directory, over these options. Empty disables them. See DirConfig.
+	DirConfigRoot string
+}
+
+// DirConfig holds the settings of a per-directory configuration file (.gha-fix.yaml), which override PinOptions for
+// the files in its directory and subdirectories, nearer files winning. See PinOptions.DirConfigRoot.
+type DirConfig = pin.DirConfig
+
+// LoadDirConfig reads and validates a per-directory configuration file.
+func LoadDirConfig(path string) (DirConfig, error) {
+	return pin.LoadDirConfig(path)
+}
+
+// Progress is the state of a PinCommand.Run in progress, see PinOptions.Progress.
+type Progress struct {
+	// Number of files processed so far, out of Total.
+	Done  int
+	Total int
+	// Number of action references resolved so far, of which CacheHits were served by the cache.
+	Resolved  int
+	CacheHits int
 }
 
 // PinCommand is a command to pin GitHub Actions in workflow files to specific commit SHAs.
 type PinCommand struct {
 	pin     pin.Pin
 	options PinOptions
+	// Per-directory configuration files; nil when disabled.
+	dirConfigs *pin.DirConfigs
+	rateLimits internalpin.RateLimitService
 }
 
 // NewPinCommand creates a new PinCommand with the provided GitHub clients and options.
-// primaryClient is required. fallbackClient (GitHub.com) is optional and used for tag resolution fallback.
+// primaryClient is required, unless PinOptions.Resolver replaces the resolution through the API. fallbackClient
+// (GitHub.com) is optional and used for tag resolution fallback.
 func NewPinCommand(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts PinOptions) PinCommand {
 	return PinCommand{
-		pin:     pin.NewPin(primaryClient, fallbackClient, opts.IgnoreOwners, opts.IgnoreRepos, opts.StrictPinning202508),
-		options: opts,
+		pin: pin.NewPin(primaryClient, fallbackClient, pin.Options{
+			IgnoreOwners:             opts.IgnoreOwners,
+			IgnoreRepos:              opts.IgnoreRepos,
+			OnlyOwners:               opts.OnlyOwners,
+			OnlyRepos:                opts.OnlyRepos,
+			StrictPinning202508:      opts.StrictPinning202508,
+			ExcludeReusableWorkflows: opts.ExcludeReusableWorkflows,
+			ResolveDescribe:          opts.ResolveDescribe,
+			V0Strict:                 opts.V0Strict,
+			AllowPrerelease:          opts.AllowPrerelease,
+			PreferBranches:           opts.PreferBranches,
+			ResolveLatest:            opts.ResolveLatest,
+			FailOnFallback:           opts.FailOnFallback,
+			FallbackOnForbidden:      opts.FallbackOnForbidden,
+			FallbackStatuses:         opts.FallbackStatuses,
+			CanonicalizeNames:        opts.CanonicalizeNames,
+			StripTrailingWhitespace:  opts.StripTrailingWhitespace,
+			YAMLMode:                 opts.YAMLMode,
+			AssumeDefaultBranch:      opts.AssumeDefaultBranch,
+			VerifyExistingSHA:        opts.VerifyExistingSHA,
+			NormalizeSHA:             opts.NormalizeSHA,
+			NormalizeQuotes:          opts.NormalizeQuotes,
+			CommentTemplate:          opts.CommentTemplate,
+			NoComment:                opts.NoComment,
+			NoCommentOnBranchRefs:    opts.NoCommentOnBranchRefs,
+			CommentMatchRefPrefix:    opts.CommentMatchRefPrefix,
+			CommentIncludeDate:       opts.CommentIncludeDate,
+			PinTo:                    opts.PinTo,
+			Allowlist:                opts.Allowlist,
+			AllowlistWarnOnly:        opts.AllowlistWarnOnly,
+			FailOnUnresolvable:       opts.FailOnUnresolvable,
+			PinDocker:                opts.PinDocker,
+			RegistryUsername:         opts.RegistryUsername,
+			RegistryPassword:         opts.RegistryPassword,
+			RetryBudget:              opts.RetryBudget,
+			MaxRetries:               opts.MaxRetries,
+			MaxBackoff:               opts.MaxBackoff,
+			MaxLineLength:            opts.MaxLineLength,
+			CacheTTL:                 opts.CacheTTL,
+			CachePath:                opts.CachePath,
+			Cache:                    opts.Cache,
+			Provider:                 opts.Provider,
+			Resolver:                 opts.Resolver,
+			Lockfile:                 opts.Lockfile,
+			UpdateLock:               opts.UpdateLock,
+		}),
+		options:    opts,
+		dirConfigs: newDirConfigs(opts.DirConfigRoot),
+		rateLimits: rateLimitService(primaryClient),
 	}
 }
 
+func rateLimitService(client *gogithub.Client) internalpin.RateLimitService {
+	if client == nil {
+		return nil
+	}
+	return client.RateLimit
+}
+
+func newDirConfigs(root string) *pin.DirConfigs {
+	if root == "" {
+		return nil
+	}
+	return pin.NewDirConfigs(root)
+}
+
 // Run executes the pin command with the provided context and file paths.
 //
 // If filePaths is specified, pin the specified workflow files. Accepts both absolute and relative paths.
 // If filePaths is emtpy, list all workflow files (.yml or .yaml) in the current directory and subdirectories.
 //
+// With PinOptions.DryRun, files are never written; Result.FileCount is the number of files that would change.
+// PinOptions.Diff works the same and additionally writes the diff of each such file.
+// Result.Files records each pinned (or, in dry-run, pinnable) line; see WriteJSONReport.
+// With PinOptions.CacheTTL, resolutions are read from and saved to the on-disk cache.
+// With PinOptions.Lockfile, locked refs are pinned to their locked commit and the other resolutions are recorded.
+//
 // When re-write YAML files, use temporary files then rename them to the original file names to do atomic updates.
 func (p *PinCommand) Run(ctx context.Context, filePaths []string) (Result, error) {
-	return rewrite.Rewrite(ctx, filePaths, p.options.IgnoreDirs, p.pin.Apply)
+	res, err := rewrite.RewritePathChanges(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:       p.options.IgnoreDirs,
+		ActionFilesOnly:  p.options.ActionFilesOnly,
+		MaxDepth:         p.options.MaxDepth,
+		Since:            p.options.Since,
+		Concurrency:      p.options.Concurrency,
+		PathStyle:        p.options.PathStyle,
+		DryRun:           p.options.DryRun,
+		Diff:             p.options.Diff,
+		DiffContext:      p.options.DiffContext,
+		OnlyChangedLines: p.options.OnlyChangedActions,
+		Progress:         p.progress(),
+	}, func(ctx context.Context, path string, content string) (string, []Change, error) {
+		pinner, err := p.pinFor(path)
+		if err != nil {
+			return "", nil, err
+		}
+		return pinner.ApplyChanges(ctx, content)
+	})
+	// The cache is an optimization; failing to persist it must not fail the run.
+	if saveErr := p.pin.SaveCache(); saveErr != nil {
+		slog.Warn("failed to save resolution cache", "error", saveErr)
+	}
+	if !p.options.DryRun && p.options.Diff == nil {
+		if lockErr := p.pin.SaveLockfile(); lockErr != nil {
+			return res, errors.Join(err, lockErr)
+		}
+	}
+	return res, err
+}
+
+// progress adapts PinOptions.Progress to the file progress reported by rewrite, adding the resolver statistics.
+func (p *PinCommand) progress() func(done, total int) {
+	if p.options.Progress == nil {
+		return nil
+	}
+	return func(done, total int) {
+		stats := p.pin.Stats()
+		p.options.Progress(Progress{Done: done, Total: total, Resolved: stats.Resolutions, CacheHits: stats.CacheHits})
+	}
+}
+
+// ResolverStats counts the action resolutions and GitHub API calls of a command, see PinCommand.Stats.
+type ResolverStats = internalpin.ResolverStats
+
+// Stats returns the resolver statistics of the Run or Resolve calls so far, e.g. to check how many API calls the
+// caches saved.
+func (p *PinCommand) Stats() ResolverStats {
+	return p.pin.Stats()
+}
+
+// WriteJSONReport writes the changes recorded in res (as returned by PinCommand.Run) as a JSON object:
+// {"changed": bool, "file_count": int, "files": [{"file": path, "changes": [Change...]}]}.
+func WriteJSONReport(w io.Writer, res Result) error {
+	return report.WriteJSON(w, res)
+}
+
+// WriteJSONReportFile writes the JSON report of WriteJSONReport to path atomically, replacing any existing file.
+func WriteJSONReportFile(path string, res Result) error {
+	return report.WriteFile(path, func(w io.Writer) error { return report.WriteJSON(w, res) })
+}
+
+// WriteSARIFReport writes the changes recorded in res (as returned by PinCommand.Run, usually with PinOptions.DryRun)
+// as a SARIF 2.1.0 log for code scanning: each line to pin is a result of the unpinned-action rule, with the pinned
+// line as its suggested fix.
+func WriteSARIFReport(w io.Writer, res Result) error {
+	return sarif.Write(w, res)
+}
+
+// WriteSARIFReportFile writes the SARIF report of WriteSARIFReport to path atomically, replacing any existing file.
+func WriteSARIFReportFile(path string, res Result) error {
+	return report.WriteFile(path, func(w io.Writer) error { return sarif.Write(w, res) })
+}
+
+// Resolution is what an action reference resolves to: the commit SHA and the ref written in the comment.
+type Resolution = pin.Resolution
+
+// Resolve resolves, concurrently, every distinct action reference that Run would pin, and returns the resolutions
+// sorted by owner, repo and ref, without modifying any file. The resolutions are saved to the on-disk cache (with
+// PinOptions.CacheTTL) and the lockfile (with PinOptions.Lockfile), so a following Run doesn't call the API again.
+// Resolutions that succeeded are returned even when others fail. With PinOptions.OnlyChangedActions, only the
+// resolutions that Run would change a line with are returned. See Run for details on file handling.
+func (p *PinCommand) Resolve(ctx context.Context, filePaths []string) ([]Resolution, error) {
+	findings, checkErr := p.Check(ctx, filePaths)
+	resolutions, err := p.pin.ResolveAll(ctx, findings, p.options.Concurrency)
+	if p.options.OnlyChangedActions {
+		resolutions = pin.ChangedResolutions(resolutions, p.changedLines(ctx, filePaths))
+	}
+	if saveErr := p.pin.SaveCache(); saveErr != nil {
+		slog.Warn("failed to save resolution cache", "error", saveErr)
+	}
+	lockErr := p.pin.SaveLockfile()
+	if checkErr != nil || err != nil || lockErr != nil {
+		return resolutions, errors.Join(checkErr, err, lockErr)
+	}
+	return resolutions, nil
+}
+
+// RateLimit is the REST API quota of the primary client: Remaining calls out of Limit until Reset.
+type RateLimit = internalpin.RateLimit
+
+// callsPerResolution is the number of API calls a typical resolution makes: listing the tags, then getting the tag ref
+// to peel it to its commit. Cached resolutions make none, so estimates based on it are an upper bound.
+const callsPerResolution = 2
+
+// Preflight is the expected cost of a Run or Resolve, see PinCommand.Preflight.
+type Preflight struct {
+	// Number of distinct action references to resolve, and the API calls estimated to resolve them.
+	Resolutions    int
+	EstimatedCalls int
+	// The quota of the primary client; nil when the API server doesn't rate limit (GitHub Enterprise Server with
+	// rate limiting disabled) or there is no primary client.
+	RateLimit *RateLimit
+}
+
+// Preflight estimates the API calls Run or Resolve would make for filePaths, from the action references found by
+// Check, and fetches the remaining quota of the primary client (GET /rate_limit, which doesn't count against it), so
+// that callers can warn or stop before running out of quota mid-run. Files that can't be read or parsed are left out
+// of the estimate for Run or Resolve to report, so the error is only ever a failure to fetch the quota.
+func (p *PinCommand) Preflight(ctx context.Context, filePaths []string) (Preflight, error) {
+	findings, err := p.Check(ctx, filePaths)
+	if err != nil {
+		slog.Debug("some files were left out of the API call estimate", "error", err)
+	}
+	resolutions := pin.CountResolutions(findings)
+	preflight := Preflight{Resolutions: resolutions, EstimatedCalls: resolutions * callsPerResolution}
+	if p.rateLimits == nil {
+		return preflight, nil
+	}
+	limit, ok, err := internalpin.CoreRateLimit(ctx, p.rateLimits)
+	if err != nil {
+		return preflight, err
+	}
+	if ok {
+		preflight.RateLimit = &limit
+	}
+	return preflight, nil
+}
+
+// ListFiles returns the files Run would process for filePaths, without reading them: filePaths themselves, or the
+// workflow files discovered under the current directory when empty, honoring IgnoreDirs, ActionFilesOnly, MaxDepth,
+// Since and the .gha-fix-ignore files.
+func (p *PinCommand) ListFiles(filePaths []string) ([]string, error) {
+	return rewrite.ListFiles(filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      p.options.IgnoreDirs,
+		ActionFilesOnly: p.options.ActionFilesOnly,
+		MaxDepth:        p.options.MaxDepth,
+		Since:           p.options.Since,
+		PathStyle:       p.options.PathStyle,
+	})
+}
+
+// Check reports every `uses:` line in the workflow files that Run would pin, without modifying any file and
+// without calling the GitHub API. Each finding's Message is the action reference (owner/repo@ref).
+// See Run for details on file handling.
+func (p *PinCommand) Check(ctx context.Context, filePaths []string) ([]Finding, error) {
+	return rewrite.CheckPath(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      p.options.IgnoreDirs,
+		ActionFilesOnly: p.options.ActionFilesOnly,
+		MaxDepth:        p.options.MaxDepth,
+		Since:           p.options.Since,
+		Concurrency:     p.options.Concurrency,
+		PathStyle:       p.options.PathStyle,
+	}, func(ctx context.Context, path string, content string) ([]Finding, error) {
+		pinner, err := p.pinFor(path)
+		if err != nil {
+			return nil, err
+		}
+		return pinner.Check(ctx, content)
+	})
+}
+
+// pinFor returns the Pin for the file at path, with the per-directory configuration files applying to it.
+func (p *PinCommand) pinFor(path string) (*pin.Pin, error) {
+	if p.dirConfigs == nil {
+		return &p.pin, nil
+	}
+	config, ok, err := p.dirConfigs.For(path)
+	if err != nil || !ok {
+		return &p.pin, err
+	}
+	return p.pin.WithDirConfig(config)
+}
+
+// changedLines pins filePaths in memory, without writing them, and returns the changes of the lines Run would
+// change. It reuses the resolutions made by Resolve, so it makes no API call. Files failing are left out: their
+// failures are those already reported by Check and ResolveAll.
+func (p *PinCommand) changedLines(ctx context.Context, filePaths []string) []Change {
+	var changed []Change
+	_, _ = rewrite.CheckPath(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      p.options.IgnoreDirs,
+		ActionFilesOnly: p.options.ActionFilesOnly,
+		MaxDepth:        p.options.MaxDepth,
+		Since:           p.options.Since,
+		Concurrency:     p.options.Concurrency,
+		PathStyle:       p.options.PathStyle,
+	}, func(ctx context.Context, path string, content string) ([]Finding, error) {
+		pinner, err := p.pinFor(path)
+		if err != nil {
+			return nil, err
+		}
+		modified, changes, err := pinner.ApplyChanges(ctx, content)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, rewrite.ChangedLines(content, modified, changes)...)
+		return nil, nil
+	})
+	return changed
+}
+
+// UnpinOptions defines options for the unpin command.
+type UnpinOptions struct {
+	IgnoreDirs []string
+	// See PinOptions.ActionFilesOnly.
+	ActionFilesOnly bool
+	// See PinOptions.MaxDepth.
+	MaxDepth int
+	// See PinOptions.Concurrency.
+	Concurrency int
+	// See PinOptions.PathStyle.
+	PathStyle PathStyle
+	// Look up a tag pointing at the commit via the GitHub API when a pinned line has no ref comment.
+	ForceAPI bool
+}
+
+// UnpinCommand is a command to replace pinned commit SHAs with the refs recorded in their comments.
+type UnpinCommand struct {
+	unpin   pin.Unpin
+	options UnpinOptions
+}
+
+// NewUnpinCommand creates a new UnpinCommand with the provided GitHub clients and options.
+// The clients are only used with UnpinOptions.ForceAPI and may be nil otherwise.
+func NewUnpinCommand(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts UnpinOptions) UnpinCommand {
+	return UnpinCommand{
+		unpin:   pin.NewUnpin(primaryClient, fallbackClient, pin.UnpinOptions{ForceAPI: opts.ForceAPI}),
+		options: opts,
+	}
+}
+
+// Run executes the unpin command with the provided context and file paths, turning
+// `owner/repo@<sha> # v4.1.1` back into `owner/repo@v4.1.1`. Lines without a recoverable ref are left untouched.
+// See PinCommand.Run for details on file handling.
+func (u *UnpinCommand) Run(ctx context.Context, filePaths []string) (Result, error) {
+	return rewrite.Rewrite(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      u.options.IgnoreDirs,
+		ActionFilesOnly: u.options.ActionFilesOnly,
+		MaxDepth:        u.options.MaxDepth,
+		Concurrency:     u.options.Concurrency,
+		PathStyle:       u.options.PathStyle,
+	}, u.unpin.Apply)
+}
+
+// UpdateOptions defines options for the update command.
+type UpdateOptions struct {
+	IgnoreDirs []string
+	// See PinOptions.ActionFilesOnly.
+	ActionFilesOnly bool
+	// See PinOptions.MaxDepth.
+	MaxDepth int
+	// See PinOptions.Concurrency.
+	Concurrency int
+	// See PinOptions.PathStyle.
+	PathStyle PathStyle
+	// Only update within the current minor line (v4.1.1 -> v4.1.x) instead of the major line (v4.1.1 -> v4.x.y).
+	SameMinor bool
+	// Only replace a SHA when the new commit is strictly newer than the pinned one by commit date.
+	ReplaceOnlyIfNewer bool
+	// Fail instead of falling back to GitHub.com when the primary (GHES) API returns 404.
+	FailOnFallback bool
+	// Total number of API retries (on 5xx and rate limit errors) allowed across the whole run. Zero means unlimited.
+	RetryBudget int
+}
+
+// UpdateCommand is a command to bump already pinned actions to the latest tag matching their version comment.
+type UpdateCommand struct {
+	update  pin.Update
+	options UpdateOptions
+}
+
+// NewUpdateCommand creates a new UpdateCommand with the provided GitHub clients and options.
+// primaryClient is required. fallbackClient (GitHub.com) is optional and used for tag resolution fallback.
+func NewUpdateCommand(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts UpdateOptions) UpdateCommand {
+	return UpdateCommand{
+		update: pin.NewUpdate(primaryClient, fallbackClient, pin.UpdateOptions{
+			SameMinor:          opts.SameMinor,
+			ReplaceOnlyIfNewer: opts.ReplaceOnlyIfNewer,
+			FailOnFallback:     opts.FailOnFallback,
+			RetryBudget:        opts.RetryBudget,
+		}),
+		options: opts,
+	}
+}
+
+// Run executes the update command with the provided context and file paths, turning
+// `owner/repo@<sha> # v4.1.1` into `owner/repo@<newer sha> # v4.2.2` when a newer matching tag exists. Pinned lines
+// without a version comment are skipped with a warning. See PinCommand.Run for details on file handling.
+func (u *UpdateCommand) Run(ctx context.Context, filePaths []string) (Result, error) {
+	return rewrite.Rewrite(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      u.options.IgnoreDirs,
+		ActionFilesOnly: u.options.ActionFilesOnly,
+		MaxDepth:        u.options.MaxDepth,
+		Concurrency:     u.options.Concurrency,
+		PathStyle:       u.options.PathStyle,
+	}, u.update.Apply)
+}
+
+// TrustEntry is the trust information of an action reference: its commit, signature verification and age.
+type TrustEntry = pin.TrustEntry
+
+// TrustReportOptions defines options for the trust report command.
+type TrustReportOptions struct {
+	IgnoreDirs []string
+	// See PinOptions.ActionFilesOnly.
+	ActionFilesOnly bool
+	// See PinOptions.MaxDepth.
+	MaxDepth int
+	// Fail instead of falling back to GitHub.com when the primary (GHES) API returns 404.
+	FailOnFallback bool
+	// Total number of API retries (on 5xx and rate limit errors) allowed across the whole run. Zero means unlimited.
+	RetryBudget int
+}
+
+// TrustReportCommand is a command to report the signature verification and age of the commits actions resolve to.
+type TrustReportCommand struct {
+	trust   pin.Trust
+	options TrustReportOptions
+}
+
+// NewTrustReportCommand creates a new TrustReportCommand with the provided GitHub clients and options.
+// primaryClient is required. fallbackClient (GitHub.com) is optional and used for resolution fallback.
+func NewTrustReportCommand(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts TrustReportOptions) TrustReportCommand {
+	return TrustReportCommand{
+		trust: pin.NewTrust(primaryClient, fallbackClient, pin.TrustOptions{
+			FailOnFallback: opts.FailOnFallback,
+			RetryBudget:    opts.RetryBudget,
+		}),
+		options: opts,
+	}
+}
+
+// Run reports one TrustEntry per distinct owner/repo@ref used in the workflow files, pinned or not, without modifying
+// any file. Entries that could be built are returned even when others fail. See PinCommand.Run for file handling.
+func (t *TrustReportCommand) Run(ctx context.Context, filePaths []string) ([]TrustEntry, error) {
+	findings, scanErr := rewrite.Check(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      t.options.IgnoreDirs,
+		ActionFilesOnly: t.options.ActionFilesOnly,
+		MaxDepth:        t.options.MaxDepth,
+	}, t.trust.Scan)
+	entries, err := t.trust.Report(ctx, findings)
+	if scanErr != nil || err != nil {
+		return entries, errors.Join(scanErr, err)
+	}
+	return entries, nil
+}
+
+// ConsolidationEntry is the single version suggested for an action used with several refs across workflows.
+type ConsolidationEntry = pin.ConsolidationEntry
+
+// ConsolidateReportOptions defines options for the consolidate report command.
+type ConsolidateReportOptions struct {
+	IgnoreDirs []string
+	// See PinOptions.ActionFilesOnly.
+	ActionFilesOnly bool
+	// See PinOptions.MaxDepth.
+	MaxDepth int
+	// Fail instead of falling back to GitHub.com when the primary (GHES) API returns 404.
+	FailOnFallback bool
+	// Total number of API retries (on 5xx and rate limit errors) allowed across the whole run. Zero means unlimited.
+	RetryBudget int
+}
+
+// ConsolidateReportCommand is a command to suggest a single version for actions used with several refs.
+type ConsolidateReportCommand struct {
+	consolidate pin.Consolidate
+	options     ConsolidateReportOptions
+}
+
+// NewConsolidateReportCommand creates a new ConsolidateReportCommand with the provided GitHub clients and options.
+// primaryClient is required. fallbackClient (GitHub.com) is optional and used for resolution fallback.
+func NewConsolidateReportCommand(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts ConsolidateReportOptions) ConsolidateReportCommand {
+	return ConsolidateReportCommand{
+		consolidate: pin.NewConsolidate(primaryClient, fallbackClient, pin.ConsolidateOptions{
+			FailOnFallback: opts.FailOnFallback,
+			RetryBudget:    opts.RetryBudget,
+		}),
+		options: opts,
+	}
+}
+
+// Run reports one ConsolidationEntry per owner/repo used with more than one ref in the workflow files, without
+// modifying any file. Entries that could be built are returned even when others fail. See PinCommand.Run for file
+// handling.
+func (c *ConsolidateReportCommand) Run(ctx context.Context, filePaths []string) ([]ConsolidationEntry, error) {
+	findings, scanErr := rewrite.Check(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      c.options.IgnoreDirs,
+		ActionFilesOnly: c.options.ActionFilesOnly,
+		MaxDepth:        c.options.MaxDepth,
+	}, c.consolidate.Scan)
+	entries, err := c.consolidate.Report(ctx, findings)
+	if scanErr != nil || err != nil {
+		return entries, errors.Join(scanErr, err)
+	}
+	return entries, nil
+}
+
+// AuditViolation is an action pinned to a commit SHA that isn't on the allowlist of the audit command.
+type AuditViolation = pin.AuditViolation
+
+// AuditOptions defines options for the audit command.
+type AuditOptions struct {
+	IgnoreDirs []string
+	// See PinOptions.ActionFilesOnly.
+	ActionFilesOnly bool
+	// See PinOptions.MaxDepth.
+	MaxDepth int
+	// The approved commit SHAs per repository. Required.
+	Allowlist *Allowlist
+}
+
+// AuditCommand is a command to report the actions pinned to commit SHAs missing from an allowlist.
+type AuditCommand struct {
+	audit   pin.Audit
+	options AuditOptions
+}
+
+// NewAuditCommand creates a new AuditCommand with the provided options. It makes no GitHub API calls.
+func NewAuditCommand(opts AuditOptions) AuditCommand {
+	return AuditCommand{
+		audit:   pin.NewAudit(opts.Allowlist),
+		options: opts,
+	}
+}
+
+// Run reports one AuditViolation per `uses:` line pinned to a commit SHA that the allowlist doesn't approve for its
+// repository, in file and line order, without modifying any file. Violations found are returned even when some files
+// fail. See PinCommand.Run for file handling.
+func (a *AuditCommand) Run(ctx context.Context, filePaths []string) ([]AuditViolation, error) {
+	findings, err := rewrite.Check(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      a.options.IgnoreDirs,
+		ActionFilesOnly: a.options.ActionFilesOnly,
+		MaxDepth:        a.options.MaxDepth,
+	}, a.audit.Scan)
+	return a.audit.Report(findings), err
 }
 
 // TimeoutOptions defines options for the timeout command.
 type TimeoutOptions struct {
-	IgnoreDirs     []string
+	IgnoreDirs []string
+	// See PinOptions.ActionFilesOnly.
+	ActionFilesOnly bool
+	// See PinOptions.MaxDepth.
+	MaxDepth int
+	// See PinOptions.Concurrency.
+	Concurrency int
+	// See PinOptions.PathStyle.
+	PathStyle      PathStyle
 	TimeoutMinutes uint64
+	// OnlyJobs limits the command to jobs whose ID matches one of the entries, exactly or as a glob (e.g. build-*).
+	// Empty means all jobs.
+	OnlyJobs []string
+	// Overwrite replaces existing timeout-minutes values that differ from TimeoutMinutes.
+	Overwrite bool
+	// CI selects how files are read: auto (the default) treats files named .gitlab-ci.yml as GitLab CI, whose jobs
+	// get `timeout: <N>m`, and other files as GitHub Actions workflows; github and gitlab force one syntax.
+	CI CI
 }
 
 // TimeoutCommand is a command to insert timeout-minutes to GitHub Actions jobs in workflow files.
@@ -68,6 +860,16 @@ func NewTimeoutCommand(opts TimeoutOptions) TimeoutCommand {
 // Run executes the timeout command with the provided context and file paths.
 // See PinCommand.Run for details on file handling.
 func (t TimeoutCommand) Run(ctx context.Context, filePaths []string) (Result, error) {
-	tt := timeout.NewTimeout(t.opts.TimeoutMinutes)
-	return rewrite.Rewrite(ctx, filePaths, t.opts.IgnoreDirs, tt.Insert)
+	tt := timeout.NewTimeoutWithOptions(t.opts.TimeoutMinutes, timeout.Options{
+		OnlyJobs:  t.opts.OnlyJobs,
+		Overwrite: t.opts.Overwrite,
+		CI:        t.opts.CI,
+	})
+	return rewrite.RewritePath(ctx, filePaths, rewrite.RewriteOptions{
+		IgnoreDirs:      t.opts.IgnoreDirs,
+		ActionFilesOnly: t.opts.ActionFilesOnly,
+		MaxDepth:        t.opts.MaxDepth,
+		Concurrency:     t.opts.Concurrency,
+		PathStyle:       t.opts.PathStyle,
+	}, tt.InsertPath)
 }
@@ -0,0 +1,393 @@
+package ghafix_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ghafix "github.com/Finatext/gha-fix"
+	"github.com/Finatext/gha-fix/internal/githubclient"
+	"github.com/Finatext/gha-fix/pin"
+)
+
+const checkoutSHA = "11bd71901bbe5b1630ceea73d27597364c9af683"
+
+type scriptedResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+// scriptedTransport answers each request path with the next scripted response for it, repeating the last one once
+// the script is exhausted, and counts the requests per path.
+type scriptedTransport struct {
+	mu       sync.Mutex
+	script   map[string][]scriptedResponse
+	requests map[string]int
+}
+
+func newScriptedTransport(script map[string][]scriptedResponse) *scriptedTransport {
+	return &scriptedTransport{script: script, requests: make(map[string]int)}
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	responses := s.script[req.URL.Path]
+	n := s.requests[req.URL.Path]
+	s.requests[req.URL.Path]++
+
+	res := scriptedResponse{status: http.StatusNotFound, body: `{"message":"Not Found"}`}
+	if len(responses) > 0 {
+		res = responses[min(n, len(responses)-1)]
+	}
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	for k, v := range res.header {
+		header[k] = v
+	}
+	return &http.Response{
+		StatusCode: res.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(res.body)),
+		Request:    req,
+	}, nil
+}
+
+func (s *scriptedTransport) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+var (
+	tagsOK = scriptedResponse{
+		status: http.StatusOK,
+		body:   `[{"name":"v4.2.2","commit":{"sha":"` + checkoutSHA + `"}}]`,
+	}
+	refOK = scriptedResponse{
+		status: http.StatusOK,
+		body:   `{"ref":"refs/tags/v4.2.2","object":{"type":"commit","sha":"` + checkoutSHA + `"}}`,
+	}
+	unavailable = scriptedResponse{status: http.StatusServiceUnavailable, body: `{"message":"Service Unavailable"}`}
+	secondary   = scriptedResponse{
+		status: http.StatusForbidden,
+		header: http.Header{"Retry-After": []string{"0"}},
+		body:   `{"message":"You have exceeded a secondary rate limit","documentation_url":"https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`,
+	}
+	forbidden = scriptedResponse{status: http.StatusForbidden, body: `{"message":"Resource not accessible by integration"}`}
+)
+
+const (
+	tagsPath = "/repos/actions/checkout/tags"
+	refPath  = "/repos/actions/checkout/git/ref/tags/v4.2.2"
+)
+
+func TestPinCommand_ScriptedTransport(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        []scriptedResponse
+		retryBudget int
+		// Also sets FailOnUnresolvable so that the API error surfaces instead of a warning.
+		wantErr      bool
+		wantRequests int
+	}{
+		{
+			name:         "Transient errors are retried",
+			tags:         []scriptedResponse{unavailable, secondary, tagsOK},
+			wantRequests: 3,
+		},
+		{
+			name:         "Forbidden without rate limiting is not retried",
+			tags:         []scriptedResponse{forbidden, tagsOK},
+			wantErr:      true,
+			wantRequests: 1,
+		},
+		{
+			name:         "Retries stop when the budget is exhausted",
+			tags:         []scriptedResponse{unavailable, unavailable, tagsOK},
+			retryBudget:  1,
+			wantErr:      true,
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newScriptedTransport(map[string][]scriptedResponse{
+				tagsPath: tt.tags,
+				refPath:  {refOK},
+			})
+			client, err := githubclient.NewClientWithTransport("token", "", transport)
+			require.NoError(t, err)
+
+			input := "steps:\n  - uses: actions/checkout@v4\n"
+			path := filepath.Join(t.TempDir(), "build.yml")
+			require.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+
+			cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{
+				RetryBudget:        tt.retryBudget,
+				MaxBackoff:         time.Millisecond,
+				FailOnUnresolvable: tt.wantErr,
+			})
+			res, err := cmd.Run(context.Background(), []string{path})
+
+			content, readErr := os.ReadFile(path)
+			require.NoError(t, readErr)
+			assert.Equal(t, tt.wantRequests, transport.count(tagsPath))
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.False(t, res.Changed)
+				assert.Equal(t, input, string(content))
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, res.Changed)
+			assert.Equal(t, "steps:\n  - uses: actions/checkout@"+checkoutSHA+" # v4.2.2\n", string(content))
+		})
+	}
+}
+
+func TestPinCommand_QualifiedRefs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Tag ref",
+			input: "steps:\n  - uses: actions/checkout@refs/tags/v4.2.2\n",
+			want:  "steps:\n  - uses: actions/checkout@" + checkoutSHA + " # v4.2.2\n",
+		},
+		{
+			name:  "Branch ref",
+			input: "steps:\n  - uses: actions/checkout@refs/heads/main\n",
+			want:  "steps:\n  - uses: actions/checkout@" + checkoutSHA + " # main\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newScriptedTransport(map[string][]scriptedResponse{
+				tagsPath: {tagsOK},
+				refPath:  {refOK},
+				"/repos/actions/checkout/commits/heads/main": {{status: http.StatusOK, body: checkoutSHA}},
+			})
+			client, err := githubclient.NewClientWithTransport("token", "", transport)
+			require.NoError(t, err)
+
+			path := filepath.Join(t.TempDir(), "build.yml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.input), 0o600))
+
+			cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{FailOnUnresolvable: true})
+			res, err := cmd.Run(context.Background(), []string{path})
+			require.NoError(t, err)
+			assert.True(t, res.Changed)
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(content))
+		})
+	}
+}
+
+func TestPinCommand_FailureClasses(t *testing.T) {
+	unauthorized := scriptedResponse{status: http.StatusUnauthorized, body: `{"message":"Bad credentials"}`}
+
+	t.Run("Rejected credentials fail the run as an API failure", func(t *testing.T) {
+		transport := newScriptedTransport(map[string][]scriptedResponse{tagsPath: {unauthorized}})
+		client, err := githubclient.NewClientWithTransport("token", "", transport)
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "build.yml")
+		require.NoError(t, os.WriteFile(path, []byte("steps:\n  - uses: actions/checkout@v4\n"), 0o600))
+
+		// Without FailOnUnresolvable: API failures are never skipped as unresolvable actions.
+		cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{})
+		res, err := cmd.Run(context.Background(), []string{path})
+		require.Error(t, err)
+		assert.True(t, ghafix.IsAPIFailure(err))
+		assert.Equal(t, 1, res.TotalCount)
+		assert.Equal(t, 1, res.FailedCount)
+	})
+
+	t.Run("Unresolvable actions fail only their files", func(t *testing.T) {
+		transport := newScriptedTransport(map[string][]scriptedResponse{tagsPath: {tagsOK}, refPath: {refOK}})
+		client, err := githubclient.NewClientWithTransport("token", "", transport)
+		require.NoError(t, err)
+
+		dir := t.TempDir()
+		good := filepath.Join(dir, "good.yml")
+		bad := filepath.Join(dir, "bad.yml")
+		require.NoError(t, os.WriteFile(good, []byte("steps:\n  - uses: actions/checkout@v4\n"), 0o600))
+		require.NoError(t, os.WriteFile(bad, []byte("steps:\n  - uses: actions/missing@v1\n"), 0o600))
+
+		cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{FailOnUnresolvable: true})
+		res, err := cmd.Run(context.Background(), []string{good, bad})
+		require.Error(t, err)
+		assert.False(t, ghafix.IsAPIFailure(err))
+		assert.True(t, res.Changed)
+		assert.Equal(t, 2, res.TotalCount)
+		assert.Equal(t, 1, res.FailedCount)
+	})
+}
+
+func TestPinCommand_DirConfig(t *testing.T) {
+	transport := newScriptedTransport(map[string][]scriptedResponse{
+		tagsPath: {tagsOK},
+		refPath:  {refOK},
+	})
+	client, err := githubclient.NewClientWithTransport("token", "", transport)
+	require.NoError(t, err)
+
+	root := t.TempDir()
+	input := "steps:\n  - uses: actions/checkout@v4\n"
+	for _, dir := range []string{"team-a", "team-b"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, dir, "ci.yml"), []byte(input), 0o600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "team-a", ".gha-fix.yaml"), []byte("pin:\n  ignore-owners: [actions]\n"), 0o600))
+	paths := []string{filepath.Join(root, "team-a", "ci.yml"), filepath.Join(root, "team-b", "ci.yml")}
+
+	cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{DirConfigRoot: root})
+	findings, err := cmd.Check(context.Background(), paths)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, paths[1], findings[0].Path)
+
+	res, err := cmd.Run(context.Background(), paths)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.FileCount)
+	for i, want := range []string{input, "steps:\n  - uses: actions/checkout@" + checkoutSHA + " # v4.2.2\n"} {
+		content, err := os.ReadFile(paths[i])
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content))
+	}
+}
+
+func TestPinCommand_ListFiles(t *testing.T) {
+	client, err := githubclient.NewClientWithTransport("", "", newScriptedTransport(nil))
+	require.NoError(t, err)
+
+	root := t.TempDir()
+	for _, name := range []string{
+		".github/workflows/ci.yml",
+		".github/workflows/release.yaml",
+		".github/workflows/notes.md",
+		"vendor/action.yml",
+		"config/app.json",
+	} {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+	}
+	t.Chdir(root)
+
+	cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{IgnoreDirs: []string{"vendor"}})
+	files, err := cmd.ListFiles(nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(".github", "workflows", "ci.yml"),
+		filepath.Join(".github", "workflows", "release.yaml"),
+	}, files, "ignore-dirs and non-YAML files are skipped")
+
+	files, err = cmd.ListFiles([]string{"missing.yml"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"missing.yml"}, files, "given files are listed as is")
+
+	_, err = cmd.Run(context.Background(), []string{"missing.yml"})
+	require.ErrorContains(t, err, "missing.yml: file does not exist")
+}
+
+func TestPinCommand_Preflight(t *testing.T) {
+	input := "steps:\n  - uses: actions/checkout@v4\n  - uses: actions/checkout@v4\n  - uses: actions/setup-go@v5\n" +
+		"  - uses: actions/cache@" + checkoutSHA + "\n"
+	path := filepath.Join(t.TempDir(), "build.yml")
+	require.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+
+	t.Run("Quota", func(t *testing.T) {
+		transport := newScriptedTransport(map[string][]scriptedResponse{
+			"/rate_limit": {{
+				status: http.StatusOK,
+				body:   `{"resources":{"core":{"limit":5000,"remaining":3,"reset":1767225600}}}`,
+			}},
+		})
+		client, err := githubclient.NewClientWithTransport("token", "", transport)
+		require.NoError(t, err)
+
+		cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{})
+		preflight, err := cmd.Preflight(context.Background(), []string{path})
+		require.NoError(t, err)
+		assert.Equal(t, 2, preflight.Resolutions)
+		assert.Equal(t, 4, preflight.EstimatedCalls)
+		require.NotNil(t, preflight.RateLimit)
+		assert.Equal(t, 5000, preflight.RateLimit.Limit)
+		assert.Equal(t, 3, preflight.RateLimit.Remaining)
+		assert.Equal(t, time.Unix(1767225600, 0).UTC(), preflight.RateLimit.Reset.UTC())
+	})
+
+	t.Run("Rate limiting disabled", func(t *testing.T) {
+		client, err := githubclient.NewClientWithTransport("token", "", newScriptedTransport(nil))
+		require.NoError(t, err)
+
+		cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{})
+		preflight, err := cmd.Preflight(context.Background(), []string{path})
+		require.NoError(t, err)
+		assert.Equal(t, 2, preflight.Resolutions)
+		assert.Nil(t, preflight.RateLimit)
+	})
+
+	t.Run("Unreadable files are left to Run", func(t *testing.T) {
+		client, err := githubclient.NewClientWithTransport("token", "", newScriptedTransport(map[string][]scriptedResponse{
+			"/rate_limit": {{status: http.StatusInternalServerError, body: `{"message":"Server Error"}`}},
+		}))
+		require.NoError(t, err)
+
+		cmd := ghafix.NewPinCommand(client, nil, ghafix.PinOptions{})
+		missing := filepath.Join(t.TempDir(), "missing.yml")
+		preflight, err := cmd.Preflight(context.Background(), []string{path, missing})
+		require.Error(t, err, "only the quota failure is returned")
+		assert.NotContains(t, err.Error(), "missing.yml")
+		assert.Equal(t, 2, preflight.Resolutions, "estimated from the readable files")
+	})
+}
+
+// staticResolver resolves from a fixed map of owner/repo@ref, as a lockfile would.
+type staticResolver map[string]ghafix.ResolvedVersion
+
+func (s staticResolver) ResolveVersion(_ context.Context, def ghafix.ActionDef) (ghafix.ResolvedVersion, error) {
+	if def.HasCommitSHA() {
+		return ghafix.ResolvedVersion{}, pin.AlreadyResolvedError
+	}
+	resolved, ok := s[def.String()]
+	if !ok {
+		return ghafix.ResolvedVersion{}, pin.RefNotFoundError
+	}
+	return resolved, nil
+}
+
+func TestPinCommand_CustomResolver(t *testing.T) {
+	input := "steps:\n  - uses: actions/checkout@v4\n  - uses: actions/setup-go@v5\n"
+	path := filepath.Join(t.TempDir(), "build.yml")
+	require.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+
+	cmd := ghafix.NewPinCommand(nil, nil, ghafix.PinOptions{Resolver: staticResolver{
+		"actions/checkout@v4": {CommitSHA: checkoutSHA, RefComment: "v4.2.2"},
+	}})
+	preflight, err := cmd.Preflight(context.Background(), []string{path})
+	require.NoError(t, err)
+	assert.Nil(t, preflight.RateLimit, "no primary client to ask")
+
+	res, err := cmd.Run(context.Background(), []string{path})
+	require.NoError(t, err, "unresolvable actions only warn")
+	assert.True(t, res.Changed)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "steps:\n  - uses: actions/checkout@"+checkoutSHA+" # v4.2.2\n  - uses: actions/setup-go@v5\n", string(content))
+}
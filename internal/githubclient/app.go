@@ -0,0 +1,165 @@
+package githubclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+)
+
+const (
+	// GitHub rejects app JWTs valid for more than 10 minutes. The issue time is backdated to allow for clock drift.
+	appJWTLifetime = 9 * time.Minute
+	appJWTBackdate = time.Minute
+	// Installation tokens are valid for an hour; renew them a bit before they expire.
+	installationTokenRenewal = 5 * time.Minute
+)
+
+// NewAppClient creates a go-github client authenticating as an installation of a GitHub App, for higher rate limits
+// than personal access tokens. Installation tokens are minted with the app's private key (PEM, PKCS #1 or #8) and
+// renewed before they expire.
+//
+// apiBaseURL is a full API base URL. If empty, DefaultAPIBaseURL is used.
+func NewAppClient(appID, installationID int64, pemKey []byte, apiBaseURL string) (*gogithub.Client, error) {
+	return NewAppClientWithTransport(appID, installationID, pemKey, apiBaseURL, nil)
+}
+
+// NewAppClientWithTransport works like NewAppClient, sending requests, including the ones minting installation
+// tokens, through transport. A nil transport uses http.DefaultTransport.
+func NewAppClientWithTransport(appID, installationID int64, pemKey []byte, apiBaseURL string, transport http.RoundTripper) (*gogithub.Client, error) {
+	return newAppClient(appID, installationID, pemKey, apiBaseURL, transport, time.Now)
+}
+
+func newAppClient(appID, installationID int64, pemKey []byte, apiBaseURL string, transport http.RoundTripper, now func() time.Time) (*gogithub.Client, error) {
+	key, err := parsePrivateKey(pemKey)
+	if err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	appClient, err := newClient(apiBaseURL, &appTransport{base: transport, appID: appID, key: key, now: now})
+	if err != nil {
+		return nil, err
+	}
+	return newClient(apiBaseURL, &installationTransport{
+		base:           transport,
+		apps:           appClient.Apps,
+		installationID: installationID,
+		now:            now,
+	})
+}
+
+func parsePrivateKey(pemKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("github app private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse github app private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("github app private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// appTransport authenticates requests as the GitHub App itself with a short-lived JWT, as required to mint
+// installation tokens.
+type appTransport struct {
+	base  http.RoundTripper
+	appID int64
+	key   *rsa.PrivateKey
+	now   func() time.Time
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.jwt()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+func (t *appTransport) jwt() (string, error) {
+	now := t.now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTBackdate).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(t.appID, 10),
+	})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "sign github app jwt")
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// installationTransport authenticates requests with an installation token of the app, minting a new one when the
+// current token is about to expire.
+type installationTransport struct {
+	base           http.RoundTripper
+	apps           *gogithub.AppsService
+	installationID int64
+	now            func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.installationToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+func (t *installationTransport) installationToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && t.now().Before(t.expiresAt.Add(-installationTokenRenewal)) {
+		return t.token, nil
+	}
+
+	token, _, err := t.apps.CreateInstallationToken(ctx, t.installationID, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "create installation token for installation %d", t.installationID)
+	}
+	t.token = token.GetToken()
+	t.expiresAt = token.GetExpiresAt().Time
+	return t.token, nil
+}
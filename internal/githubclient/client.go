@@ -1,6 +1,9 @@
 package githubclient
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -43,6 +46,101 @@ func NormalizeAPIBaseURL(raw string) (string, error) {
 //
 // apiBaseURL is a full API base URL. If empty, DefaultAPIBaseURL is used.
 func NewClient(token string, apiBaseURL string) (*gogithub.Client, error) {
+	return NewClientWithTransport(token, apiBaseURL, nil)
+}
+
+// NewClientWithTransport works like NewClient, sending requests through transport, e.g. to script API responses in
+// tests. A nil transport uses http.DefaultTransport.
+func NewClientWithTransport(token string, apiBaseURL string, transport http.RoundTripper) (*gogithub.Client, error) {
+	c, err := newClient(apiBaseURL, transport)
+	if err != nil {
+		return nil, err
+	}
+	return c.WithAuthToken(token), nil
+}
+
+// GiteaAPIPath is where Gitea and Forgejo serve their API, appended by NewGiteaClientWithTransport to API base URLs
+// without a path.
+const GiteaAPIPath = "api/v1/"
+
+// NewGiteaClientWithTransport creates a go-github client for the Gitea or Forgejo API at apiBaseURL, e.g.
+// https://gitea.example.com/ or https://gitea.example.com/api/v1/. A bare host gets GiteaAPIPath; an explicit path is
+// used verbatim. The client only suits the GitHub-compatible endpoints, see pin.GiteaRepositoryService.
+func NewGiteaClientWithTransport(token string, apiBaseURL string, transport http.RoundTripper) (*gogithub.Client, error) {
+	base, err := NormalizeAPIBaseURL(apiBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if base == "" {
+		return nil, errors.New("api server url is required for gitea")
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse api server url")
+	}
+	if u.Path == "/" {
+		base += GiteaAPIPath
+	}
+	return NewClientWithTransport(token, base, transport)
+}
+
+// TransportOptions configures the HTTP transport of the GitHub clients, see NewTransport.
+type TransportOptions struct {
+	// ProxyURL is the HTTP(S) proxy requests are sent through, e.g. http://proxy.example.com:3128. If empty, the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are honored.
+	ProxyURL string
+	// CACertPEM is a bundle of PEM encoded CA certificates trusted in addition to the system ones, e.g. the internal CA
+	// of a GHES instance.
+	CACertPEM []byte
+	// InsecureSkipVerify disables the verification of server certificates. Only meant for test environments.
+	InsecureSkipVerify bool
+}
+
+// NewTransport returns a transport configured by opts. For zero options it returns nil, i.e. http.DefaultTransport.
+func NewTransport(opts TransportOptions) (http.RoundTripper, error) {
+	if opts.ProxyURL == "" && len(opts.CACertPEM) == 0 && !opts.InsecureSkipVerify {
+		return nil, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if s := strings.TrimSpace(opts.ProxyURL); s != "" {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse proxy url")
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return nil, errors.New("proxy url must be absolute")
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, errors.New("proxy url scheme must be http, https or socks5")
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	if len(opts.CACertPEM) > 0 || opts.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: opts.InsecureSkipVerify, //nolint:gosec // Opt-in escape hatch for test environments.
+		}
+	}
+	if len(opts.CACertPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(opts.CACertPEM) {
+			return nil, errors.New("ca certificate bundle contains no PEM encoded certificate")
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	return transport, nil
+}
+
+// newClient creates an unauthenticated go-github client for apiBaseURL sending requests through transport; the
+// transport is responsible for authentication.
+func newClient(apiBaseURL string, transport http.RoundTripper) (*gogithub.Client, error) {
 	base := apiBaseURL
 	if strings.TrimSpace(base) == "" {
 		base = DefaultAPIBaseURL
@@ -55,13 +153,28 @@ func NewClient(token string, apiBaseURL string) (*gogithub.Client, error) {
 
 	// go-github uses BaseURL for API requests and UploadURL for uploads.
 	// We only need API requests for this tool, but WithEnterpriseURLs sets both consistently.
-	c := gogithub.NewClient(nil).WithAuthToken(token)
+	var httpClient *http.Client
+	if transport != nil {
+		httpClient = &http.Client{Transport: transport}
+	}
+	c := gogithub.NewClient(httpClient)
 
 	if base != DefaultAPIBaseURL {
 		c, err = c.WithEnterpriseURLs(base, base)
 		if err != nil {
 			return nil, errors.Wrap(err, "set enterprise urls")
 		}
+
+		// WithEnterpriseURLs appends api/v3/ to any base not ending with it, which breaks proxies mounting the API
+		// under another path (e.g. https://proxy/github-api/). Only a bare host gets the GHES default mount; an
+		// explicit path is used verbatim.
+		u, err := url.Parse(base)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse api server url")
+		}
+		if u.Path != "/" {
+			c.BaseURL = u
+		}
 	}
 
 	return c, nil
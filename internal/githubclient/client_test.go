@@ -1,7 +1,22 @@
 package githubclient
 
 import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -43,5 +58,209 @@ func TestNewClient(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "https://ghe.example.com/api/v3/", c.BaseURL.String())
 	})
+
+	t.Run("request urls keep path prefixes", func(t *testing.T) {
+		tests := []struct {
+			base     string
+			expected string
+		}{
+			// Bare hosts get the GHES default mount.
+			{base: "https://ghe.example.com", expected: "https://ghe.example.com/api/v3/repos/o/r/tags"},
+			{base: "https://ghe.example.com/", expected: "https://ghe.example.com/api/v3/repos/o/r/tags"},
+			// Explicit paths are used verbatim, whether or not they end with /api/v3.
+			{base: "https://proxy.example.com/gh/api/v3/", expected: "https://proxy.example.com/gh/api/v3/repos/o/r/tags"},
+			{base: "https://proxy.example.com/gh/api/v3", expected: "https://proxy.example.com/gh/api/v3/repos/o/r/tags"},
+			{base: "https://proxy.example.com/github-api/", expected: "https://proxy.example.com/github-api/repos/o/r/tags"},
+			{base: "http://proxy.example.com:8080/a/b/c", expected: "http://proxy.example.com:8080/a/b/c/repos/o/r/tags"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.base, func(t *testing.T) {
+				c, err := NewClient("t", tt.base)
+				require.NoError(t, err)
+				req, err := c.NewRequest("GET", "repos/o/r/tags", nil)
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, req.URL.String())
+			})
+		}
+	})
+}
+
+func TestNewGiteaClientWithTransport(t *testing.T) {
+	tests := []struct {
+		base     string
+		expected string
+	}{
+		// Bare hosts get the Gitea API mount.
+		{base: "https://gitea.example.com", expected: "https://gitea.example.com/api/v1/repos/o/r/tags"},
+		{base: "https://gitea.example.com/api/v1", expected: "https://gitea.example.com/api/v1/repos/o/r/tags"},
+		{base: "https://proxy.example.com/forgejo/api/v1/", expected: "https://proxy.example.com/forgejo/api/v1/repos/o/r/tags"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			c, err := NewGiteaClientWithTransport("t", tt.base, nil)
+			require.NoError(t, err)
+			req, err := c.NewRequest("GET", "repos/o/r/tags", nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, req.URL.String())
+		})
+	}
+
+	_, err := NewGiteaClientWithTransport("t", "", nil)
+	require.Error(t, err)
+}
+
+func TestNewTransport(t *testing.T) {
+	t.Run("zero options use the default transport", func(t *testing.T) {
+		transport, err := NewTransport(TransportOptions{})
+		require.NoError(t, err)
+		require.Nil(t, transport)
+	})
+
+	t.Run("rejects invalid proxy urls", func(t *testing.T) {
+		for _, raw := range []string{"proxy.example.com:3128", "ftp://proxy.example.com", "http://"} {
+			_, err := NewTransport(TransportOptions{ProxyURL: raw})
+			require.Error(t, err, raw)
+		}
+	})
+
+	t.Run("requests are routed through the proxy", func(t *testing.T) {
+		var gotHost, gotPath, gotAuth string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotHost, gotPath, gotAuth = r.Host, r.URL.Path, r.Header.Get("Authorization")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"full_name":"o/r"}`)
+		}))
+		defer proxy.Close()
+
+		transport, err := NewTransport(TransportOptions{ProxyURL: proxy.URL})
+		require.NoError(t, err)
+		c, err := NewClientWithTransport("t", "http://ghe.example.com/api/v3/", transport)
+		require.NoError(t, err)
+
+		repo, _, err := c.Repositories.Get(context.Background(), "o", "r")
+		require.NoError(t, err)
+		require.Equal(t, "o/r", repo.GetFullName())
+		require.Equal(t, "ghe.example.com", gotHost)
+		require.Equal(t, "/api/v3/repos/o/r", gotPath)
+		require.Equal(t, "Bearer t", gotAuth)
+	})
+
+	ghes := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"full_name":"o/r"}`)
+	}))
+	defer ghes.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ghes.Certificate().Raw})
+
+	getRepo := func(t *testing.T, opts TransportOptions) error {
+		t.Helper()
+		transport, err := NewTransport(opts)
+		require.NoError(t, err)
+		c, err := NewClientWithTransport("t", ghes.URL+"/api/v3/", transport)
+		require.NoError(t, err)
+		_, _, err = c.Repositories.Get(context.Background(), "o", "r")
+		return err
+	}
+
+	t.Run("ca bundle is added to the root pool", func(t *testing.T) {
+		transport, err := NewTransport(TransportOptions{CACertPEM: caPEM})
+		require.NoError(t, err)
+		config := transport.(*http.Transport).TLSClientConfig
+		require.NotNil(t, config)
+		require.False(t, config.InsecureSkipVerify)
+		_, err = ghes.Certificate().Verify(x509.VerifyOptions{Roots: config.RootCAs})
+		require.NoError(t, err)
+
+		require.NoError(t, getRepo(t, TransportOptions{CACertPEM: caPEM}))
+	})
+
+	t.Run("untrusted certificates are rejected", func(t *testing.T) {
+		require.Error(t, getRepo(t, TransportOptions{}))
+	})
+
+	t.Run("insecure skip verify accepts untrusted certificates", func(t *testing.T) {
+		require.NoError(t, getRepo(t, TransportOptions{InsecureSkipVerify: true}))
+	})
+
+	t.Run("rejects bundles without certificates", func(t *testing.T) {
+		_, err := NewTransport(TransportOptions{CACertPEM: []byte("not a certificate")})
+		require.Error(t, err)
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
 }
 
+func TestNewAppClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var minted int
+	var authorizations []string
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		respond := func(body string) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		}
+		auth := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if req.URL.Path == "/app/installations/42/access_tokens" {
+			require.Equal(t, http.MethodPost, req.Method)
+			claims := verifyJWT(t, &key.PublicKey, auth)
+			require.Equal(t, "7", claims["iss"])
+			require.Less(t, claims["iat"].(float64), float64(now.Unix()))
+			require.LessOrEqual(t, claims["exp"].(float64), float64(now.Add(10*time.Minute).Unix()))
+			minted++
+			expiresAt := now.Add(time.Hour).Format(time.RFC3339)
+			return respond(`{"token":"ghs_` + strconv.Itoa(minted) + `","expires_at":"` + expiresAt + `"}`)
+		}
+		authorizations = append(authorizations, auth)
+		return respond(`{"name":"r"}`)
+	})
+
+	c, err := newAppClient(7, 42, pemKey, "", transport, func() time.Time { return now })
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for range 2 {
+		_, _, err = c.Repositories.Get(ctx, "o", "r")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, minted, "the installation token is reused while valid")
+
+	now = now.Add(56 * time.Minute)
+	_, _, err = c.Repositories.Get(ctx, "o", "r")
+	require.NoError(t, err)
+	require.Equal(t, 2, minted, "the installation token is renewed before it expires")
+	require.Equal(t, []string{"ghs_1", "ghs_1", "ghs_2"}, authorizations)
+
+	t.Run("invalid private keys are rejected", func(t *testing.T) {
+		_, err := NewAppClient(7, 42, []byte("not a key"), "")
+		require.Error(t, err)
+	})
+}
+
+// verifyJWT checks the RS256 signature of token and returns its claims.
+func verifyJWT(t *testing.T, pub *rsa.PublicKey, token string) map[string]any {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
@@ -0,0 +1,52 @@
+package pin
+
+import "sync"
+
+// CacheKey identifies a resolution: a ref of a repository as written in a workflow.
+type CacheKey struct {
+	Owner    string
+	Repo     string
+	RefOrSHA string
+}
+
+// Cache stores resolved versions. Implementations backed by shared stores (e.g. Redis) let several processes reuse
+// resolutions; VersionResolver uses a MemoryCache unless ResolverOptions.Cache is set.
+//
+// A cache is only valid for a single API host and set of ResolverOptions, since both affect the resolution.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key CacheKey) (ResolvedVersion, bool)
+	Set(key CacheKey, val ResolvedVersion)
+}
+
+// FailureCache is implemented by caches that also remember the refs confirmed not to exist, so that they fail fast
+// without API calls in later runs too. VersionResolver keeps such failures in memory for its own lifetime regardless.
+type FailureCache interface {
+	// GetFailure returns the error of a failed resolution of key, matching its cause with errors.Is
+	// (TagNotFoundError, NoTagsFoundError or RefNotFoundError), or nil when there is none.
+	GetFailure(key CacheKey) error
+	SetFailure(key CacheKey, err error)
+}
+
+// MemoryCache is a Cache backed by a map, living as long as the process.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[CacheKey]ResolvedVersion
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[CacheKey]ResolvedVersion)}
+}
+
+func (c *MemoryCache) Get(key CacheKey) (ResolvedVersion, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.entries[key]
+	return v, ok
+}
+
+func (c *MemoryCache) Set(key CacheKey, val ResolvedVersion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = val
+}
@@ -0,0 +1,118 @@
+package pin
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	gogithub "github.com/google/go-github/v72/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// countingCache is an in-memory Cache recording its calls.
+type countingCache struct {
+	MemoryCache
+	gets, hits, sets int
+}
+
+func newCountingCache() *countingCache {
+	return &countingCache{MemoryCache: *NewMemoryCache()}
+}
+
+func (c *countingCache) Get(key CacheKey) (ResolvedVersion, bool) {
+	c.gets++
+	v, ok := c.MemoryCache.Get(key)
+	if ok {
+		c.hits++
+	}
+	return v, ok
+}
+
+func (c *countingCache) Set(key CacheKey, val ResolvedVersion) {
+	c.sets++
+	c.MemoryCache.Set(key, val)
+}
+
+func TestVersionResolver_Cache(t *testing.T) {
+	t.Run("Custom cache is consulted before the API", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("sha", &gogithub.Response{}, nil).Times(1)
+
+		cache := newCountingCache()
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: cache})
+		def := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"}
+		for range 3 {
+			result, err := resolver.ResolveVersion(context.Background(), def)
+			require.NoError(t, err)
+			assert.Equal(t, "sha", result.CommitSHA)
+		}
+
+		// The miss is looked up again once the resolution starts, in case a concurrent one just cached it.
+		assert.Equal(t, 4, cache.gets)
+		assert.Equal(t, 2, cache.hits)
+		assert.Equal(t, 1, cache.sets)
+	})
+
+	t.Run("Pre-populated cache avoids API calls", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cache := newCountingCache()
+		cache.Set(CacheKey{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"}, ResolvedVersion{CommitSHA: "sha", RefComment: "v4.2.2"})
+		resolver := NewVersionResolver(NewMockRepositoryService(ctrl), nil, ResolverOptions{Cache: cache})
+
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"})
+		require.NoError(t, err)
+		assert.Equal(t, "v4.2.2", result.RefComment)
+	})
+
+	t.Run("Concurrent resolutions share the cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("sha", &gogithub.Response{}, nil).MinTimes(1)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "missing", "main", "").
+			Return("", nil, notFoundError()).MinTimes(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"})
+				assert.NoError(t, err)
+				assert.Equal(t, "sha", result.CommitSHA)
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "missing", RefOrSHA: "main"})
+				assert.Error(t, err)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Failures are not stored", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("", nil, notFoundError())
+
+		cache := newCountingCache()
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: cache})
+		_, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"})
+		require.Error(t, err)
+		assert.Equal(t, 0, cache.sets)
+	})
+}
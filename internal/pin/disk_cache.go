@@ -0,0 +1,269 @@
+package pin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// diskCacheVersion is bumped whenever the file format changes; files of other versions are ignored.
+const diskCacheVersion = 3
+
+// DefaultNegativeTTL is how long a DiskCache remembers the refs confirmed not to exist, unless set otherwise with
+// SetNegativeTTL. It is shorter than the TTL of resolutions since a missing tag or branch can be pushed at any time.
+const DefaultNegativeTTL = time.Hour
+
+// DiskCache is a Cache persisting resolved versions across runs as a JSON file, so repeated runs don't re-hit the
+// GitHub API. Entries older than the TTL are treated as missing and dropped on Save.
+//
+// Branch resolutions are only kept in memory: branch heads move with every push, so persisting them would pin later
+// runs to stale commits, but within a run every use of a branch must pin the same commit.
+//
+// It is also a FailureCache: refs confirmed not to exist are remembered for the shorter negative TTL.
+//
+// Entries are namespaced (see CacheNamespace) so that one file can be shared between GitHub.com and GHES runs, and
+// between runs with different resolver options.
+type DiskCache struct {
+	path      string
+	namespace string
+	ttl       time.Duration
+	// TTL of failures, at most ttl; zero disables the caching of failures.
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]diskCacheEntry
+	dirty   bool
+}
+
+type diskCacheEntry struct {
+	CommitSHA  string `json:"commit_sha"`
+	RefComment string `json:"ref_comment"`
+	// WasBranch entries are left out of the file by Save.
+	WasBranch      bool      `json:"-"`
+	CanonicalOwner string    `json:"canonical_owner,omitempty"`
+	CanonicalRepo  string    `json:"canonical_repo,omitempty"`
+	ResolvedAt     time.Time `json:"resolved_at"`
+	// Failure is the error message of a ref confirmed not to exist, and FailureCause what confirmed it (see
+	// failureCauses); such entries have no resolution.
+	Failure      string `json:"failure,omitempty"`
+	FailureCause string `json:"failure_cause,omitempty"`
+}
+
+// failureCauses are the sentinel errors of the failures cached on disk, by their name in the file.
+var failureCauses = map[string]error{
+	"not_found":     RefNotFoundError,
+	"no_tags":       NoTagsFoundError,
+	"tag_not_found": TagNotFoundError,
+}
+
+type diskCacheFile struct {
+	Version int                       `json:"version"`
+	Entries map[string]diskCacheEntry `json:"entries"`
+}
+
+// DefaultDiskCachePath returns the default cache file location, e.g. ~/.cache/gha-fix/resolutions.json on Linux.
+func DefaultDiskCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return filepath.Join(dir, "gha-fix", "resolutions.json"), nil
+}
+
+// OpenDiskCache loads the cache file at path. A missing or unreadable file results in an empty cache: the cache is an
+// optimization, so it never fails a run.
+func OpenDiskCache(path, namespace string, ttl time.Duration) *DiskCache {
+	c := &DiskCache{
+		path:        path,
+		namespace:   namespace,
+		ttl:         ttl,
+		negativeTTL: min(ttl, DefaultNegativeTTL),
+		now:         time.Now,
+		entries:     make(map[string]diskCacheEntry),
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read resolution cache; starting empty", "path", path, "error", err)
+		}
+		return c
+	}
+	var f diskCacheFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		slog.Warn("failed to parse resolution cache; starting empty", "path", path, "error", err)
+		return c
+	}
+	if f.Version != diskCacheVersion {
+		slog.Debug("ignoring resolution cache of another version", "path", path, "version", f.Version)
+		return c
+	}
+	if f.Entries != nil {
+		c.entries = f.Entries
+	}
+	return c
+}
+
+// CacheNamespace returns the DiskCache namespace for resolutions made against apiBaseURL with opts.
+func CacheNamespace(apiBaseURL string, opts ResolverOptions) string {
+	// Options changing the resolution result are part of the namespace.
+	return fmt.Sprintf("%s describe=%t v0strict=%t canonical=%t prerelease=%t branches=%t latest=%t",
+		apiBaseURL, opts.ResolveDescribe, opts.V0Strict, opts.CanonicalizeNames, opts.AllowPrerelease, opts.PreferBranches,
+		opts.ResolveLatest)
+}
+
+// SetNegativeTTL sets how long refs confirmed not to exist are remembered, capped at the TTL of resolutions. Zero
+// disables the caching of failures on disk.
+func (c *DiskCache) SetNegativeTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.negativeTTL = max(min(ttl, c.ttl), 0)
+}
+
+func (c *DiskCache) entryKey(key CacheKey) string {
+	return c.namespace + " " + key.Owner + "/" + key.Repo + "@" + key.RefOrSHA
+}
+
+func (c *DiskCache) Get(key CacheKey) (ResolvedVersion, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[c.entryKey(key)]
+	if !ok || e.Failure != "" || c.expired(e) {
+		return ResolvedVersion{}, false
+	}
+	slog.Debug("using resolution from disk cache", "owner", key.Owner, "repo", key.Repo, "ref", key.RefOrSHA)
+	return ResolvedVersion{
+		CommitSHA:      e.CommitSHA,
+		RefComment:     e.RefComment,
+		WasBranch:      e.WasBranch,
+		CanonicalOwner: e.CanonicalOwner,
+		CanonicalRepo:  e.CanonicalRepo,
+	}, true
+}
+
+func (c *DiskCache) Set(key CacheKey, v ResolvedVersion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[c.entryKey(key)] = diskCacheEntry{
+		CommitSHA:      v.CommitSHA,
+		RefComment:     v.RefComment,
+		WasBranch:      v.WasBranch,
+		CanonicalOwner: v.CanonicalOwner,
+		CanonicalRepo:  v.CanonicalRepo,
+		ResolvedAt:     c.now(),
+	}
+	if !v.WasBranch {
+		c.dirty = true
+	}
+}
+
+// GetFailure returns the cached failure of key, if it is still valid. See FailureCache.
+func (c *DiskCache) GetFailure(key CacheKey) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[c.entryKey(key)]
+	cause, known := failureCauses[e.FailureCause]
+	if !ok || e.Failure == "" || !known || c.expired(e) {
+		return nil
+	}
+	slog.Debug("using failed resolution from disk cache", "owner", key.Owner, "repo", key.Repo, "ref", key.RefOrSHA)
+	return &failureError{msg: e.Failure, cause: cause}
+}
+
+// failureError is a failure restored from disk: its original message, matching its cause with errors.Is.
+type failureError struct {
+	msg   string
+	cause error
+}
+
+func (e *failureError) Error() string { return e.msg }
+func (e *failureError) Unwrap() error { return e.cause }
+
+// SetFailure records err, the failure of a ref confirmed not to exist. Other errors aren't cached.
+func (c *DiskCache) SetFailure(key CacheKey, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.negativeTTL <= 0 {
+		return
+	}
+	for name, cause := range failureCauses {
+		if errors.Is(err, cause) || (cause == RefNotFoundError && isNotFound(err)) {
+			c.entries[c.entryKey(key)] = diskCacheEntry{
+				Failure:      err.Error(),
+				FailureCause: name,
+				ResolvedAt:   c.now(),
+			}
+			c.dirty = true
+			return
+		}
+	}
+}
+
+func (c *DiskCache) expired(e diskCacheEntry) bool {
+	ttl := c.ttl
+	if e.Failure != "" {
+		ttl = c.negativeTTL
+	}
+	return c.now().Sub(e.ResolvedAt) > ttl
+}
+
+// Save writes the cache back to disk, dropping expired entries and leaving out branch resolutions. It does nothing
+// when no entry to persist was added.
+func (c *DiskCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	persisted := make(map[string]diskCacheEntry, len(c.entries))
+	for k, e := range c.entries {
+		if c.expired(e) {
+			delete(c.entries, k)
+		} else if !e.WasBranch {
+			persisted[k] = e
+		}
+	}
+
+	b, err := json.Marshal(diskCacheFile{Version: diskCacheVersion, Entries: persisted})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Write to a temporary file then rename it so that concurrent runs never read a partial file.
+	tmpFile, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+"-*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+	}()
+
+	if _, err := tmpFile.Write(b); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return errors.WithStack(err)
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,183 @@
+package pin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestDiskCache(t *testing.T) {
+	key := CacheKey{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"}
+	resolved := ResolvedVersion{CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"}
+
+	t.Run("Entries survive across runs", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "gha-fix", "resolutions.json")
+		c := OpenDiskCache(path, "https://api.github.com/", time.Hour)
+		c.Set(key, resolved)
+		require.NoError(t, c.Save())
+
+		reopened := OpenDiskCache(path, "https://api.github.com/", time.Hour)
+		got, ok := reopened.Get(key)
+		require.True(t, ok)
+		assert.Equal(t, resolved, got)
+
+		branchKey := CacheKey{Owner: "actions", Repo: "checkout", RefOrSHA: "main"}
+		branch := ResolvedVersion{CommitSHA: "85e6279cec87321a52edac9c87bce653a07cf6c2", RefComment: "main", WasBranch: true}
+		reopened.Set(branchKey, branch)
+		got, ok = reopened.Get(branchKey)
+		require.True(t, ok)
+		assert.Equal(t, branch, got)
+		require.NoError(t, reopened.Save())
+		_, ok = OpenDiskCache(path, "https://api.github.com/", time.Hour).Get(branchKey)
+		assert.False(t, ok, "branch resolutions are never persisted")
+	})
+
+	t.Run("Entries are namespaced", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "resolutions.json")
+		ns := CacheNamespace("https://api.github.com/", ResolverOptions{})
+		c := OpenDiskCache(path, ns, time.Hour)
+		c.Set(key, resolved)
+		require.NoError(t, c.Save())
+
+		_, ok := OpenDiskCache(path, ns, time.Hour).Get(key)
+		assert.True(t, ok)
+		_, ok = OpenDiskCache(path, CacheNamespace("https://ghes.example.com/api/v3/", ResolverOptions{}), time.Hour).Get(key)
+		assert.False(t, ok)
+		_, ok = OpenDiskCache(path, CacheNamespace("https://api.github.com/", ResolverOptions{V0Strict: true}), time.Hour).Get(key)
+		assert.False(t, ok)
+	})
+
+	t.Run("Expired entries are ignored and dropped on save", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "resolutions.json")
+		now := time.Now()
+		c := OpenDiskCache(path, "ns", time.Hour)
+		c.now = func() time.Time { return now }
+		c.Set(key, resolved)
+		c.Set(CacheKey{Owner: "actions", Repo: "setup-go", RefOrSHA: "v5"}, resolved)
+
+		now = now.Add(2 * time.Hour)
+		_, ok := c.Get(key)
+		assert.False(t, ok)
+		require.NoError(t, c.Save())
+		assert.Empty(t, OpenDiskCache(path, "ns", time.Hour).entries)
+	})
+
+	t.Run("Failures survive across runs", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "resolutions.json")
+		missing := CacheKey{Owner: "actions", Repo: "checkout", RefOrSHA: "v9"}
+		c := OpenDiskCache(path, "ns", time.Hour)
+		c.SetFailure(missing, errors.Wrap(TagNotFoundError, "no tag v9"))
+		c.SetFailure(key, errors.New("connection reset"))
+		require.NoError(t, c.Save())
+
+		reopened := OpenDiskCache(path, "ns", time.Hour)
+		err := reopened.GetFailure(missing)
+		require.ErrorIs(t, err, TagNotFoundError)
+		assert.Equal(t, "no tag v9: specified tag not found", err.Error())
+		_, ok := reopened.Get(missing)
+		assert.False(t, ok)
+		assert.NoError(t, reopened.GetFailure(key))
+	})
+
+	t.Run("Failures expire before resolutions", func(t *testing.T) {
+		missing := CacheKey{Owner: "actions", Repo: "checkout", RefOrSHA: "v9"}
+		now := time.Now()
+		c := OpenDiskCache(filepath.Join(t.TempDir(), "resolutions.json"), "ns", 24*time.Hour)
+		c.now = func() time.Time { return now }
+		c.Set(key, resolved)
+		c.SetFailure(missing, TagNotFoundError)
+
+		now = now.Add(2 * time.Hour)
+		assert.NoError(t, c.GetFailure(missing))
+		_, ok := c.Get(key)
+		assert.True(t, ok)
+	})
+
+	t.Run("Zero negative TTL disables failure caching", func(t *testing.T) {
+		c := OpenDiskCache(filepath.Join(t.TempDir(), "resolutions.json"), "ns", time.Hour)
+		c.SetNegativeTTL(0)
+		c.SetFailure(key, TagNotFoundError)
+		assert.NoError(t, c.GetFailure(key))
+		assert.Empty(t, c.entries)
+	})
+
+	t.Run("Corrupt file starts empty", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "resolutions.json")
+		require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+		c := OpenDiskCache(path, "ns", time.Hour)
+		assert.Empty(t, c.entries)
+	})
+}
+
+func TestVersionResolver_DiskCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolutions.json")
+	tag := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"}
+	branch := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"}
+
+	// First run resolves via the API and persists the tag resolution. The branch is resolved once and then served
+	// from memory, so every use of it within the run pins the same commit.
+	ctrl := gomock.NewController(t)
+	mockRepo := NewMockRepositoryService(ctrl)
+	mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+		Return([]*gogithub.RepositoryTag{createTag("v4.2.2", "sha")}, &gogithub.Response{}, nil).Times(1)
+	mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+		Return("branch-sha", &gogithub.Response{}, nil).Times(1)
+	cache := OpenDiskCache(path, "ns", time.Hour)
+	resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: cache})
+	_, err := resolver.ResolveVersion(context.Background(), tag)
+	require.NoError(t, err)
+	first, err := resolver.ResolveVersion(context.Background(), branch)
+	require.NoError(t, err)
+	second, err := resolver.ResolveVersion(context.Background(), branch)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	require.NoError(t, cache.Save())
+	ctrl.Finish()
+
+	// Second run serves the tag from disk without any API call, but resolves the branch again.
+	ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo = NewMockRepositoryService(ctrl)
+	mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+		Return("new-branch-sha", &gogithub.Response{}, nil).Times(1)
+	resolver = NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: OpenDiskCache(path, "ns", time.Hour)})
+	result, err := resolver.ResolveVersion(context.Background(), tag)
+	require.NoError(t, err)
+	assert.Equal(t, "sha", result.CommitSHA)
+	assert.Equal(t, "v4.2.2", result.RefComment)
+	result, err = resolver.ResolveVersion(context.Background(), branch)
+	require.NoError(t, err)
+	assert.Equal(t, "new-branch-sha", result.CommitSHA)
+}
+
+func TestVersionResolver_DiskNegativeCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolutions.json")
+	def := ActionDef{Owner: "org", Repo: "missing", RefOrSHA: "main"}
+
+	// First run confirms the ref doesn't exist and persists the failure.
+	ctrl := gomock.NewController(t)
+	mockRepo := NewMockRepositoryService(ctrl)
+	mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "missing", "main", "").
+		Return("", nil, notFoundError()).Times(1)
+	cache := OpenDiskCache(path, "ns", time.Hour)
+	resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: cache})
+	_, err := resolver.ResolveVersion(context.Background(), def)
+	require.Error(t, err)
+	require.NoError(t, cache.Save())
+	ctrl.Finish()
+
+	// Second run fails from disk without any API call.
+	ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	resolver = NewVersionResolver(NewMockRepositoryService(ctrl), nil, ResolverOptions{Cache: OpenDiskCache(path, "ns", time.Hour)})
+	_, err = resolver.ResolveVersion(context.Background(), def)
+	require.ErrorIs(t, err, RefNotFoundError)
+}
@@ -0,0 +1,98 @@
+package pin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+)
+
+// GiteaRepositoryService implements RepositoryService against the API of Gitea and Forgejo (/api/v1/), which is
+// close enough to GitHub's for its responses to decode into the go-github types. The differences are the endpoints:
+//
+//   - ListTags: /repos/{owner}/{repo}/tags, paginated with limit instead of per_page. Tags list the commit they
+//     point at, annotated tags already peeled, so no GitService is needed.
+//   - GetCommitSHA1 and GetCommit: /repos/{owner}/{repo}/git/commits/{ref}, which accepts branches, tags and SHAs.
+//     Gitea has no SHA media type, so the commit is fetched without its files and stats.
+//   - Get: /repos/{owner}/{repo}, as on GitHub.
+//
+// The client must have its BaseURL set to the API root, e.g. https://gitea.example.com/api/v1/. Errors are go-github
+// errors, e.g. *gogithub.ErrorResponse for 404, so they are handled like GitHub's.
+type GiteaRepositoryService struct {
+	client *gogithub.Client
+}
+
+// NewGiteaRepositoryService creates a RepositoryService sending Gitea API requests with client.
+func NewGiteaRepositoryService(client *gogithub.Client) *GiteaRepositoryService {
+	return &GiteaRepositoryService{client: client}
+}
+
+func (s *GiteaRepositoryService) ListTags(ctx context.Context, owner string, repo string, opts *gogithub.ListOptions) ([]*gogithub.RepositoryTag, *gogithub.Response, error) {
+	query := url.Values{}
+	if opts != nil {
+		if opts.Page > 0 {
+			query.Set("page", fmt.Sprint(opts.Page))
+		}
+		if opts.PerPage > 0 {
+			query.Set("limit", fmt.Sprint(opts.PerPage))
+		}
+	}
+	path := s.repoPath(owner, repo) + "/tags"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var tags []*gogithub.RepositoryTag
+	resp, err := s.get(ctx, path, &tags)
+	if err != nil {
+		return nil, resp, err
+	}
+	return tags, resp, nil
+}
+
+func (s *GiteaRepositoryService) GetCommitSHA1(ctx context.Context, owner, repo, ref, _ string) (string, *gogithub.Response, error) {
+	commit, resp, err := s.getCommit(ctx, owner, repo, ref)
+	if err != nil {
+		return "", resp, err
+	}
+	return commit.GetSHA(), resp, nil
+}
+
+func (s *GiteaRepositoryService) Get(ctx context.Context, owner, repo string) (*gogithub.Repository, *gogithub.Response, error) {
+	var repository gogithub.Repository
+	resp, err := s.get(ctx, s.repoPath(owner, repo), &repository)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &repository, resp, nil
+}
+
+func (s *GiteaRepositoryService) GetCommit(ctx context.Context, owner, repo, sha string, _ *gogithub.ListOptions) (*gogithub.RepositoryCommit, *gogithub.Response, error) {
+	return s.getCommit(ctx, owner, repo, sha)
+}
+
+func (s *GiteaRepositoryService) getCommit(ctx context.Context, owner, repo, ref string) (*gogithub.RepositoryCommit, *gogithub.Response, error) {
+	var commit gogithub.RepositoryCommit
+	path := s.repoPath(owner, repo) + "/git/commits/" + url.PathEscape(ref) + "?stat=false&files=false"
+	resp, err := s.get(ctx, path, &commit)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &commit, resp, nil
+}
+
+func (s *GiteaRepositoryService) repoPath(owner, repo string) string {
+	return "repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// get sends a GET request for path, relative to the API root, decoding the JSON response into v.
+func (s *GiteaRepositoryService) get(ctx context.Context, path string, v any) (*gogithub.Response, error) {
+	req, err := s.client.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp, err := s.client.Do(ctx, req, v)
+	return resp, errors.WithStack(err)
+}
@@ -0,0 +1,92 @@
+package pin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	gogithub "github.com/google/go-github/v72/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGiteaServer serves the Gitea API endpoints of actions/checkout used by GiteaRepositoryService.
+func newGiteaServer(t *testing.T) *GiteaRepositoryService {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/repos/actions/checkout/tags", func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.URL.Query().Get("limit"), "Gitea pages with limit")
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		// Annotated tags are listed with the commit they point at; id is the tag object.
+		_, _ = w.Write([]byte(`[{"name":"v4.2.2","id":"c3a1bd43c9e9c71e3d2b7b0e8e0a1a0d8d0a1a0d","commit":{"sha":"` + sha + `"}},` +
+			`{"name":"v4.1.0","commit":{"sha":"d35c59abb061a4a6fb18e82ac0862c26744d6ab5"}}]`))
+	})
+	mux.HandleFunc("GET /api/v1/repos/actions/checkout/git/commits/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.PathValue("ref"); ref != "main" && ref != sha {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"object does not exist"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"sha":"` + sha + `","commit":{"committer":{"date":"2024-10-23T14:46:00Z"},` +
+			`"verification":{"verified":true,"reason":""}}}`))
+	})
+	mux.HandleFunc("GET /api/v1/repos/actions/checkout", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"checkout","full_name":"actions/checkout","owner":{"login":"actions"},"default_branch":"main"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := gogithub.NewClient(server.Client())
+	base, err := url.Parse(server.URL + "/api/v1/")
+	require.NoError(t, err)
+	client.BaseURL = base
+	return NewGiteaRepositoryService(client)
+}
+
+func TestGiteaRepositoryService(t *testing.T) {
+	svc := newGiteaServer(t)
+	ctx := context.Background()
+
+	tags, _, err := svc.ListTags(ctx, "actions", "checkout", &gogithub.ListOptions{Page: 1, PerPage: 50})
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "v4.2.2", tags[0].GetName())
+	assert.Equal(t, "11bd71901bbe5b1630ceea73d27597364c9af683", tags[0].GetCommit().GetSHA())
+
+	sha, _, err := svc.GetCommitSHA1(ctx, "actions", "checkout", "main", "")
+	require.NoError(t, err)
+	assert.Equal(t, "11bd71901bbe5b1630ceea73d27597364c9af683", sha)
+
+	_, _, err = svc.GetCommitSHA1(ctx, "actions", "checkout", "missing", "")
+	require.Error(t, err)
+	assert.True(t, hasStatus(err, http.StatusNotFound))
+
+	commit, _, err := svc.GetCommit(ctx, "actions", "checkout", sha, nil)
+	require.NoError(t, err)
+	assert.True(t, commit.GetCommit().GetVerification().GetVerified())
+	assert.Equal(t, 2024, commit.GetCommit().GetCommitter().GetDate().Year())
+
+	repo, _, err := svc.Get(ctx, "actions", "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, "actions", repo.GetOwner().GetLogin())
+	assert.Equal(t, "main", repo.GetDefaultBranch())
+}
+
+func TestVersionResolver_Gitea(t *testing.T) {
+	resolver := NewVersionResolver(newGiteaServer(t), nil, ResolverOptions{})
+	ctx := context.Background()
+
+	resolved, err := resolver.ResolveVersion(ctx, ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"})
+	require.NoError(t, err)
+	assert.Equal(t, ResolvedVersion{CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"}, resolved)
+
+	resolved, err = resolver.ResolveVersion(ctx, ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"})
+	require.NoError(t, err)
+	assert.Equal(t, "11bd71901bbe5b1630ceea73d27597364c9af683", resolved.CommitSHA)
+	assert.True(t, resolved.WasBranch)
+}
@@ -0,0 +1,74 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Finatext/gha-fix/internal/pin (interfaces: GitService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=./mock_git_service.go -package=pin github.com/Finatext/gha-fix/internal/pin GitService
+//
+
+// Package pin is a generated GoMock package.
+package pin
+
+import (
+	context "context"
+	reflect "reflect"
+
+	github "github.com/google/go-github/v72/github"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockGitService is a mock of GitService interface.
+type MockGitService struct {
+	ctrl     *gomock.Controller
+	recorder *MockGitServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockGitServiceMockRecorder is the mock recorder for MockGitService.
+type MockGitServiceMockRecorder struct {
+	mock *MockGitService
+}
+
+// NewMockGitService creates a new mock instance.
+func NewMockGitService(ctrl *gomock.Controller) *MockGitService {
+	mock := &MockGitService{ctrl: ctrl}
+	mock.recorder = &MockGitServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGitService) EXPECT() *MockGitServiceMockRecorder {
+	return m.recorder
+}
+
+// GetRef mocks base method.
+func (m *MockGitService) GetRef(ctx context.Context, owner, repo, ref string) (*github.Reference, *github.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRef", ctx, owner, repo, ref)
+	ret0, _ := ret[0].(*github.Reference)
+	ret1, _ := ret[1].(*github.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetRef indicates an expected call of GetRef.
+func (mr *MockGitServiceMockRecorder) GetRef(ctx, owner, repo, ref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRef", reflect.TypeOf((*MockGitService)(nil).GetRef), ctx, owner, repo, ref)
+}
+
+// GetTag mocks base method.
+func (m *MockGitService) GetTag(ctx context.Context, owner, repo, sha string) (*github.Tag, *github.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTag", ctx, owner, repo, sha)
+	ret0, _ := ret[0].(*github.Tag)
+	ret1, _ := ret[1].(*github.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetTag indicates an expected call of GetTag.
+func (mr *MockGitServiceMockRecorder) GetTag(ctx, owner, repo, sha any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTag", reflect.TypeOf((*MockGitService)(nil).GetTag), ctx, owner, repo, sha)
+}
@@ -41,6 +41,38 @@ func (m *MockRepositoryService) EXPECT() *MockRepositoryServiceMockRecorder {
 	return m.recorder
 }
 
+// Get mocks base method.
+func (m *MockRepositoryService) Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, owner, repo)
+	ret0, _ := ret[0].(*github.Repository)
+	ret1, _ := ret[1].(*github.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockRepositoryServiceMockRecorder) Get(ctx, owner, repo any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRepositoryService)(nil).Get), ctx, owner, repo)
+}
+
+// GetCommit mocks base method.
+func (m *MockRepositoryService) GetCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) (*github.RepositoryCommit, *github.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCommit", ctx, owner, repo, sha, opts)
+	ret0, _ := ret[0].(*github.RepositoryCommit)
+	ret1, _ := ret[1].(*github.Response)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetCommit indicates an expected call of GetCommit.
+func (mr *MockRepositoryServiceMockRecorder) GetCommit(ctx, owner, repo, sha, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommit", reflect.TypeOf((*MockRepositoryService)(nil).GetCommit), ctx, owner, repo, sha, opts)
+}
+
 // GetCommitSHA1 mocks base method.
 func (m *MockRepositoryService) GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *github.Response, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,39 @@
+package pin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+)
+
+// RateLimitService is the part of the GitHub rate limit API used to check the quota before a run.
+type RateLimitService interface {
+	Get(ctx context.Context) (*gogithub.RateLimits, *gogithub.Response, error)
+}
+
+// RateLimit is the REST API (core) quota of a client: Remaining calls out of Limit until Reset.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// CoreRateLimit fetches the REST API quota of the client behind svc. It reports false, with no error, when the API
+// server doesn't rate limit (GHES with rate limiting disabled answers 404), in which case there is no quota to check.
+func CoreRateLimit(ctx context.Context, svc RateLimitService) (RateLimit, bool, error) {
+	limits, _, err := svc.Get(ctx)
+	if hasStatus(err, http.StatusNotFound) {
+		return RateLimit{}, false, nil
+	}
+	if err != nil {
+		return RateLimit{}, false, errors.Wrap(err, "failed to get rate limit")
+	}
+	core := limits.GetCore()
+	if core == nil {
+		return RateLimit{}, false, nil
+	}
+	return RateLimit{Limit: core.Limit, Remaining: core.Remaining, Reset: core.Reset.Time}, true, nil
+}
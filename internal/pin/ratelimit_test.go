@@ -0,0 +1,48 @@
+package pin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	gogithub "github.com/google/go-github/v72/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRateLimitService struct {
+	limits *gogithub.RateLimits
+	err    error
+}
+
+func (f *fakeRateLimitService) Get(context.Context) (*gogithub.RateLimits, *gogithub.Response, error) {
+	return f.limits, &gogithub.Response{}, f.err
+}
+
+func TestCoreRateLimit(t *testing.T) {
+	reset := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Core quota", func(t *testing.T) {
+		svc := &fakeRateLimitService{limits: &gogithub.RateLimits{
+			Core:   &gogithub.Rate{Limit: 5000, Remaining: 42, Reset: gogithub.Timestamp{Time: reset}},
+			Search: &gogithub.Rate{Limit: 30, Remaining: 30},
+		}}
+		got, ok, err := CoreRateLimit(context.Background(), svc)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, RateLimit{Limit: 5000, Remaining: 42, Reset: reset}, got)
+	})
+
+	t.Run("Rate limiting disabled", func(t *testing.T) {
+		_, ok, err := CoreRateLimit(context.Background(), &fakeRateLimitService{err: notFoundError()})
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("API failure", func(t *testing.T) {
+		_, _, err := CoreRateLimit(context.Background(), &fakeRateLimitService{err: apiError(http.StatusUnauthorized)})
+		require.Error(t, err)
+		assert.True(t, IsAPIFailure(err))
+	})
+}
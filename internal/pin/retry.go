@@ -0,0 +1,263 @@
+package pin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+)
+
+const (
+	// Number of retries for a single API call before its error is surfaced.
+	defaultMaxRetries = 3
+	// Initial wait between retries; doubled on each subsequent retry.
+	defaultRetryBackoff = time.Second
+	// Longest single wait between retries.
+	defaultMaxRetryBackoff = time.Minute
+)
+
+// RetryOptions configures how a RetryingRepositoryService retries a single call.
+type RetryOptions struct {
+	// Retries per call before the error is surfaced. Zero uses the default (3); negative disables retries.
+	MaxRetries int
+	// Longest single wait between retries. Exponential backoff is capped at this value, and when GitHub asks to wait
+	// longer (Retry-After or rate limit reset) the call gives up instead of hanging. Zero uses the default (1m).
+	MaxBackoff time.Duration
+}
+
+// RetryBudget is the number of retries allowed across all API calls of a run. It is shared by every
+// retrying RepositoryService so that a flaky host can't cause thousands of retries in aggregate.
+//
+// A nil *RetryBudget is unlimited.
+type RetryBudget struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+// NewRetryBudget creates a budget allowing n retries in total. n <= 0 means unlimited (returns nil).
+func NewRetryBudget(n int) *RetryBudget {
+	if n <= 0 {
+		return nil
+	}
+	return &RetryBudget{remaining: n}
+}
+
+// take consumes one retry from the budget, returning false when the budget is exhausted.
+func (b *RetryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// Remaining returns the number of retries left, or -1 when the budget is unlimited.
+func (b *RetryBudget) Remaining() int {
+	if b == nil {
+		return -1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining
+}
+
+// retrier retries calls failing with transient errors (5xx responses and rate limiting) with exponential backoff.
+//
+// Rate limit errors honor the wait GitHub asks for: the Retry-After of secondary rate limits and the reset time of
+// primary rate limits.
+type retrier struct {
+	budget     *RetryBudget
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	now        func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+func newRetrier(budget *RetryBudget, opts RetryOptions) retrier {
+	maxRetries := opts.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxRetryBackoff
+	}
+	return retrier{
+		budget:     budget,
+		maxRetries: max(maxRetries, 0),
+		backoff:    defaultRetryBackoff,
+		maxBackoff: maxBackoff,
+		now:        time.Now,
+		sleep:      sleepContext,
+	}
+}
+
+// RetryingRepositoryService wraps a RepositoryService and retries calls failing with transient errors
+// (5xx responses and rate limiting) with exponential backoff.
+type RetryingRepositoryService struct {
+	retrier
+	svc RepositoryService
+}
+
+// NewRetryingRepositoryService wraps svc with retries drawn from budget. Pass the same budget to every wrapper
+// used in a run to cap retries globally.
+func NewRetryingRepositoryService(svc RepositoryService, budget *RetryBudget, opts RetryOptions) *RetryingRepositoryService {
+	return &RetryingRepositoryService{retrier: newRetrier(budget, opts), svc: svc}
+}
+
+func (r *RetryingRepositoryService) ListTags(ctx context.Context, owner string, repo string, opts *gogithub.ListOptions) ([]*gogithub.RepositoryTag, *gogithub.Response, error) {
+	var tags []*gogithub.RepositoryTag
+	var resp *gogithub.Response
+	err := r.do(ctx, func() error {
+		var err error
+		tags, resp, err = r.svc.ListTags(ctx, owner, repo, opts)
+		return err
+	})
+	return tags, resp, err
+}
+
+func (r *RetryingRepositoryService) GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gogithub.Response, error) {
+	var sha string
+	var resp *gogithub.Response
+	err := r.do(ctx, func() error {
+		var err error
+		sha, resp, err = r.svc.GetCommitSHA1(ctx, owner, repo, ref, lastSHA)
+		return err
+	})
+	return sha, resp, err
+}
+
+func (r *RetryingRepositoryService) Get(ctx context.Context, owner, repo string) (*gogithub.Repository, *gogithub.Response, error) {
+	var repository *gogithub.Repository
+	var resp *gogithub.Response
+	err := r.do(ctx, func() error {
+		var err error
+		repository, resp, err = r.svc.Get(ctx, owner, repo)
+		return err
+	})
+	return repository, resp, err
+}
+
+func (r *RetryingRepositoryService) GetCommit(ctx context.Context, owner, repo, sha string, opts *gogithub.ListOptions) (*gogithub.RepositoryCommit, *gogithub.Response, error) {
+	var commit *gogithub.RepositoryCommit
+	var resp *gogithub.Response
+	err := r.do(ctx, func() error {
+		var err error
+		commit, resp, err = r.svc.GetCommit(ctx, owner, repo, sha, opts)
+		return err
+	})
+	return commit, resp, err
+}
+
+// RetryingGitService is the GitService counterpart of RetryingRepositoryService.
+type RetryingGitService struct {
+	retrier
+	svc GitService
+}
+
+// NewRetryingGitService wraps svc with retries drawn from budget, as NewRetryingRepositoryService.
+func NewRetryingGitService(svc GitService, budget *RetryBudget, opts RetryOptions) *RetryingGitService {
+	return &RetryingGitService{retrier: newRetrier(budget, opts), svc: svc}
+}
+
+func (r *RetryingGitService) GetRef(ctx context.Context, owner, repo, ref string) (*gogithub.Reference, *gogithub.Response, error) {
+	var reference *gogithub.Reference
+	var resp *gogithub.Response
+	err := r.do(ctx, func() error {
+		var err error
+		reference, resp, err = r.svc.GetRef(ctx, owner, repo, ref)
+		return err
+	})
+	return reference, resp, err
+}
+
+func (r *RetryingGitService) GetTag(ctx context.Context, owner, repo, sha string) (*gogithub.Tag, *gogithub.Response, error) {
+	var tag *gogithub.Tag
+	var resp *gogithub.Response
+	err := r.do(ctx, func() error {
+		var err error
+		tag, resp, err = r.svc.GetTag(ctx, owner, repo, sha)
+		return err
+	})
+	return tag, resp, err
+}
+
+func (r *retrier) do(ctx context.Context, call func() error) error {
+	backoff := r.backoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || !isTransient(err) || attempt >= r.maxRetries {
+			return err
+		}
+
+		wait := min(backoff, r.maxBackoff)
+		if requested, ok := r.requestedWait(err); ok {
+			if requested > r.maxBackoff {
+				slog.Debug("GitHub asked to wait longer than the max backoff; not retrying",
+					"wait", requested, "max_backoff", r.maxBackoff, "error", err)
+				return err
+			}
+			wait = requested
+		}
+		if !r.budget.take() {
+			slog.Debug("retry budget exhausted; not retrying", "error", err)
+			return err
+		}
+
+		slog.Debug("retrying transient API error", "attempt", attempt+1, "wait", wait, "error", err)
+		if err := r.sleep(ctx, wait); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// requestedWait returns how long GitHub asked to wait before retrying err, if it did.
+func (r *retrier) requestedWait(err error) (time.Duration, bool) {
+	var abuseErr *gogithub.AbuseRateLimitError
+	if errors.As(err, &abuseErr) && abuseErr.RetryAfter != nil {
+		return *abuseErr.RetryAfter, true
+	}
+	var rateLimitErr *gogithub.RateLimitError
+	if errors.As(err, &rateLimitErr) && !rateLimitErr.Rate.Reset.IsZero() {
+		// The reset time has a one second resolution; wait a bit past it.
+		return max(rateLimitErr.Rate.Reset.Sub(r.now()), 0) + time.Second, true
+	}
+	return 0, false
+}
+
+// isTransient reports whether err is worth retrying: rate limiting or a server-side failure.
+func isTransient(err error) bool {
+	var rateLimitErr *gogithub.RateLimitError
+	var abuseErr *gogithub.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return true
+	}
+
+	var ghErr *gogithub.ErrorResponse
+	if errors.As(err, &ghErr) {
+		return ghErr.Response != nil && ghErr.Response.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	case <-t.C:
+		return nil
+	}
+}
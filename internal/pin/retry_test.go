@@ -0,0 +1,204 @@
+package pin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	gogithub "github.com/google/go-github/v72/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func newTestRetryingService(svc RepositoryService, budget *RetryBudget) *RetryingRepositoryService {
+	r := NewRetryingRepositoryService(svc, budget, RetryOptions{})
+	r.sleep = func(context.Context, time.Duration) error { return nil }
+	return r
+}
+
+func TestRetryingRepositoryService(t *testing.T) {
+	t.Run("Retries transient errors until success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		gomock.InOrder(
+			mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+				Return("", nil, apiError(http.StatusBadGateway)),
+			mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+				Return("sha", &gogithub.Response{}, nil),
+		)
+
+		svc := newTestRetryingService(mockRepo, nil)
+		sha, _, err := svc.GetCommitSHA1(context.Background(), "actions", "checkout", "main", "")
+		require.NoError(t, err)
+		assert.Equal(t, "sha", sha)
+	})
+
+	t.Run("Does not retry non-transient errors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(nil, nil, notFoundError()).Times(1)
+
+		svc := newTestRetryingService(mockRepo, nil)
+		_, _, err := svc.ListTags(context.Background(), "actions", "checkout", &gogithub.ListOptions{})
+		require.Error(t, err)
+	})
+
+	t.Run("Gives up after the per-call retry limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(nil, nil, apiError(http.StatusServiceUnavailable)).Times(defaultMaxRetries + 1)
+
+		svc := newTestRetryingService(mockRepo, nil)
+		_, _, err := svc.ListTags(context.Background(), "actions", "checkout", &gogithub.ListOptions{})
+		require.Error(t, err)
+	})
+}
+
+func TestRetryingRepositoryService_RateLimit(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newService := func(svc RepositoryService, opts RetryOptions) (*RetryingRepositoryService, *[]time.Duration) {
+		var waits []time.Duration
+		r := NewRetryingRepositoryService(svc, nil, opts)
+		r.now = func() time.Time { return now }
+		r.sleep = func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		}
+		return r, &waits
+	}
+	abuseError := func(retryAfter time.Duration) error {
+		return &gogithub.AbuseRateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}, RetryAfter: &retryAfter}
+	}
+	rateLimitError := func(reset time.Time) error {
+		return &gogithub.RateLimitError{
+			Response: &http.Response{StatusCode: http.StatusForbidden},
+			Rate:     gogithub.Rate{Reset: gogithub.Timestamp{Time: reset}},
+		}
+	}
+
+	t.Run("Honors Retry-After of secondary rate limits", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		gomock.InOrder(
+			mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+				Return("", nil, abuseError(30*time.Second)),
+			mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+				Return("sha", &gogithub.Response{}, nil),
+		)
+
+		svc, waits := newService(mockRepo, RetryOptions{})
+		sha, _, err := svc.GetCommitSHA1(context.Background(), "actions", "checkout", "main", "")
+		require.NoError(t, err)
+		assert.Equal(t, "sha", sha)
+		assert.Equal(t, []time.Duration{30 * time.Second}, *waits)
+	})
+
+	t.Run("Waits until the primary rate limit resets", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		gomock.InOrder(
+			mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+				Return(nil, nil, rateLimitError(now.Add(10*time.Second))),
+			mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+				Return(nil, &gogithub.Response{}, nil),
+		)
+
+		svc, waits := newService(mockRepo, RetryOptions{})
+		_, _, err := svc.ListTags(context.Background(), "actions", "checkout", &gogithub.ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{11 * time.Second}, *waits)
+	})
+
+	t.Run("Gives up when asked to wait longer than the max backoff", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(nil, nil, rateLimitError(now.Add(time.Hour))).Times(1)
+
+		svc, waits := newService(mockRepo, RetryOptions{MaxBackoff: 5 * time.Minute})
+		_, _, err := svc.ListTags(context.Background(), "actions", "checkout", &gogithub.ListOptions{})
+		require.Error(t, err)
+		assert.Empty(t, *waits)
+	})
+
+	t.Run("Exponential backoff is capped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("", nil, apiError(http.StatusBadGateway)).Times(5 + 1)
+
+		svc, waits := newService(mockRepo, RetryOptions{MaxRetries: 5, MaxBackoff: 3 * time.Second})
+		_, _, err := svc.GetCommitSHA1(context.Background(), "actions", "checkout", "main", "")
+		require.Error(t, err)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second}, *waits)
+	})
+
+	t.Run("Negative max retries disables retries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("", nil, abuseError(time.Second)).Times(1)
+
+		svc, waits := newService(mockRepo, RetryOptions{MaxRetries: -1})
+		_, _, err := svc.GetCommitSHA1(context.Background(), "actions", "checkout", "main", "")
+		require.Error(t, err)
+		assert.Empty(t, *waits)
+	})
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Run("Budget caps total retries across calls and services", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl)
+		// Budget of 2: the first call uses both retries (3 attempts), every later call gets a single attempt.
+		primary.EXPECT().GetCommitSHA1(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").
+			Return("", nil, apiError(http.StatusInternalServerError)).Times(3 + 1)
+		fallback.EXPECT().ListTags(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, nil, apiError(http.StatusInternalServerError)).Times(1)
+
+		budget := NewRetryBudget(2)
+		primarySvc := newTestRetryingService(primary, budget)
+		fallbackSvc := newTestRetryingService(fallback, budget)
+
+		_, _, err := primarySvc.GetCommitSHA1(context.Background(), "a", "b", "main", "")
+		require.Error(t, err)
+		assert.Equal(t, 0, budget.Remaining())
+
+		_, _, err = primarySvc.GetCommitSHA1(context.Background(), "a", "c", "main", "")
+		require.Error(t, err)
+
+		_, _, err = fallbackSvc.ListTags(context.Background(), "a", "b", &gogithub.ListOptions{})
+		require.Error(t, err)
+	})
+
+	t.Run("Non-positive budget is unlimited", func(t *testing.T) {
+		budget := NewRetryBudget(0)
+		assert.Equal(t, -1, budget.Remaining())
+		for range 100 {
+			assert.True(t, budget.take())
+		}
+	})
+}
@@ -1,14 +1,25 @@
 package pin
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/cockroachdb/errors"
 	gogithub "github.com/google/go-github/v72/github"
+	"golang.org/x/sync/singleflight"
 )
 
 type ActionDef struct {
@@ -18,6 +29,25 @@ type ActionDef struct {
 	RefOrSHA string
 }
 
+// String returns the reference as written in a workflow, e.g. "actions/checkout@v4" or "org/repo/path@main". A
+// reference without a ref (see HasRef) has no "@".
+func (a ActionDef) String() string {
+	repoPath := a.Repo
+	if a.Path != "" {
+		repoPath += "/" + a.Path
+	}
+	if !a.HasRef() {
+		return a.Owner + "/" + repoPath
+	}
+	return a.Owner + "/" + repoPath + "@" + a.RefOrSHA
+}
+
+// HasRef reports whether the reference names a ref. References written without `@ref` (e.g. `uses: actions/checkout`)
+// are invalid in workflows, but resolve to the default branch of the repository.
+func (a ActionDef) HasRef() bool {
+	return a.RefOrSHA != ""
+}
+
 // Check the ref is a commit SHA.
 func (a ActionDef) HasCommitSHA() bool {
 	if len(a.RefOrSHA) != 40 {
@@ -32,6 +62,15 @@ func (a ActionDef) HasCommitSHA() bool {
 	return true
 }
 
+// abbreviatedSHAPattern matches abbreviated commit SHAs: 7 to 39 hex digits, as printed by git log --oneline.
+var abbreviatedSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,39}$`)
+
+// HasAbbreviatedSHA reports whether the ref looks like an abbreviated commit SHA (e.g. 11bd719). Such refs can also
+// be branch or tag names, see VersionResolver.ResolveVersion.
+func (a ActionDef) HasAbbreviatedSHA() bool {
+	return abbreviatedSHAPattern.MatchString(a.RefOrSHA)
+}
+
 // IsReusableWorkflow determines if this action is a reusable workflow.
 // Reusable workflows have file extensions in their path (e.g., .yml, .yaml).
 // Composite actions do not have extensions in their path.
@@ -47,6 +86,28 @@ func (a ActionDef) IsReusableWorkflow() bool {
 	return strings.Contains(lastPart, ".")
 }
 
+// numericRefPattern matches refs made of version numbers only, such as 4 or 4.1.
+var numericRefPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,2}$`)
+
+// IsNumericRef reports whether the ref is numeric only (e.g. 4 or 4.1). Such refs parse as versions but are also
+// valid branch names, see ResolverOptions.PreferBranches.
+func (a ActionDef) IsNumericRef() bool {
+	return numericRefPattern.MatchString(a.RefOrSHA)
+}
+
+// describePattern matches `git describe` outputs such as v4.1.1-3-gabcdef0.
+var describePattern = regexp.MustCompile(`^.+-[0-9]+-g([0-9a-fA-F]{7,40})$`)
+
+// DescribeSHA extracts the abbreviated commit SHA embedded in a `git describe` style ref
+// (e.g. "abcdef0" from "v4.1.1-3-gabcdef0").
+func (a ActionDef) DescribeSHA() (string, bool) {
+	matches := describePattern.FindStringSubmatch(a.RefOrSHA)
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
 // Extract version representation from ref.
 // Version like string = 2.3.4, v2.3.4, v2.3.4-beta, v2.3.4+build, 2.3.4-beta, 2.3.4+build
 //
@@ -59,6 +120,11 @@ func (a ActionDef) VersionTag() *semver.Version {
 type ResolvedVersion struct {
 	CommitSHA  string
 	RefComment string
+	// The ref was resolved as a branch, so CommitSHA is its current head, a moving target to refresh with `update`.
+	WasBranch bool
+	// Canonical owner/repo names as returned by the API. Only set when ResolverOptions.CanonicalizeNames is enabled.
+	CanonicalOwner string
+	CanonicalRepo  string
 }
 
 //go:generate mockgen -destination=./mock_repository_service.go -package=pin github.com/Finatext/gha-fix/internal/pin RepositoryService
@@ -70,64 +136,364 @@ type RepositoryService interface {
 	// Although the documentation states that the `:ref` must be prefixed with `tags/` or `heads/`,
 	// the GitHub API currently accepts unprefixed tags and branch names (e.g., /repos/OWNER/REPO/commits/main).
 	GetCommitSHA1(ctx context.Context, owner, repo, ref, lastSHA string) (string, *gogithub.Response, error)
+	// https://docs.github.com/en/rest/repos/repos?apiVersion=2022-11-28#get-a-repository
+	Get(ctx context.Context, owner, repo string) (*gogithub.Repository, *gogithub.Response, error)
+	// https://docs.github.com/en/rest/commits/commits?apiVersion=2022-11-28#get-a-commit
+	GetCommit(ctx context.Context, owner, repo, sha string, opts *gogithub.ListOptions) (*gogithub.RepositoryCommit, *gogithub.Response, error)
 }
 
-// Cache key for storing resolved versions
-type cacheKey struct {
-	Owner    string
-	Repo     string
-	RefOrSHA string
+// GitService is the part of the Git Data API used to peel annotated tags to commits.
+//
+//go:generate mockgen -destination=./mock_git_service.go -package=pin github.com/Finatext/gha-fix/internal/pin GitService
+type GitService interface {
+	// https://docs.github.com/en/rest/git/refs?apiVersion=2022-11-28#get-a-reference
+	GetRef(ctx context.Context, owner, repo, ref string) (*gogithub.Reference, *gogithub.Response, error)
+	// https://docs.github.com/en/rest/git/tags?apiVersion=2022-11-28#get-a-tag
+	GetTag(ctx context.Context, owner, repo, sha string) (*gogithub.Tag, *gogithub.Response, error)
 }
 
+// ResolverOptions customizes how VersionResolver resolves refs.
+type ResolverOptions struct {
+	// ResolveDescribe treats `git describe` outputs (e.g. v4.1.1-3-gabcdef0) as commits and expands the embedded
+	// abbreviated SHA, instead of parsing them as semver pre-releases.
+	ResolveDescribe bool
+	// V0Strict treats every v0 minor as a breaking line: for major 0 an explicitly written minor (including 0, as in
+	// v0.0) must match, and a bare v0 resolves within the highest existing v0.y line.
+	V0Strict bool
+	// FailOnFallback returns FallbackNotAllowedError instead of retrying against GitHub.com when the primary API
+	// returns 404, guaranteeing every resolution happens on the primary (e.g. GHES) host.
+	FailOnFallback bool
+	// FallbackOnForbidden also retries against GitHub.com when the primary API denies access (403), e.g. a GHES
+	// token without access to a mirrored repository. By default a 403 fails with NoAccessError.
+	FallbackOnForbidden bool
+	// FallbackStatuses are the HTTP statuses of the primary API retried against GitHub.com, e.g. 500 for a GHES
+	// instance failing on mirrored actions. Empty means 404 only. A 401 never falls back: a rejected token fails every
+	// call, so the run fails instead. See ValidateFallbackStatuses.
+	FallbackStatuses []int
+	// CanonicalizeNames looks up the repository and fills ResolvedVersion.CanonicalOwner/CanonicalRepo with the
+	// casing GitHub reports, which may differ from the casing written in the workflow.
+	CanonicalizeNames bool
+	// AllowPrerelease lets version refs resolve to pre-release tags (e.g. v4 to v4.1.0-rc.1 when no v4 release
+	// exists), ordered by semver precedence (1.0.0-alpha < 1.0.0-alpha.1 < 1.0.0-beta < 1.0.0). Releases are still
+	// preferred unless the ref is a pre-release itself (e.g. v2-rc), and exact versions only match themselves.
+	AllowPrerelease bool
+	// PreferBranches resolves numeric-only refs (e.g. 4) as the branch of that name when it exists, instead of as the
+	// latest matching version tag (e.g. 4.1.2). Refs without such a branch still resolve as versions.
+	PreferBranches bool
+	// ResolveLatest resolves the ref LatestRef to the newest release tag across all majors (e.g. v5.2.0), instead of
+	// a branch or tag named latest. Version refs such as v4 still resolve within their major.
+	ResolveLatest bool
+	// Cache stores resolved versions, e.g. a DiskCache to reuse resolutions across runs. Defaults to a MemoryCache.
+	Cache Cache
+	// GitService and FallbackGitService (GitHub.com) peel resolved tags to the commit they point at, so that an
+	// annotated tag never pins to a tag object SHA. When nil, the commit SHA from the tag listing is trusted.
+	GitService         GitService
+	FallbackGitService GitService
+	// AssertResolveOncePerKey fails a resolution with DuplicateResolutionError when its cache key already reached the
+	// API earlier in the lifetime of the resolver, proving that the cache is effective. A debugging aid for
+	// over-fetching, also enabled by setting the ResolveOncePerKeyEnv environment variable.
+	AssertResolveOncePerKey bool
+}
+
+// ResolveOncePerKeyEnv is the environment variable enabling ResolverOptions.AssertResolveOncePerKey when set to a
+// non-empty value, e.g. GHA_FIX_RESOLVE_ONCE_PER_KEY=1 gha-fix pin.
+const ResolveOncePerKeyEnv = "GHA_FIX_RESOLVE_ONCE_PER_KEY"
+
+// VersionResolver resolves action refs to commit SHAs. It is safe for concurrent use, so a single resolver can be
+// shared by goroutines processing different files.
 type VersionResolver struct {
 	repoService         RepositoryService
 	fallbackRepoService RepositoryService
-	cache               map[cacheKey]ResolvedVersion
+	opts                ResolverOptions
+	cache               Cache
+	// Errors for refs confirmed not to exist, so each unresolvable ref is only looked up once per run.
+	negativeCache   map[CacheKey]error
+	negativeCacheMu sync.Mutex
+	// Collapses concurrent resolutions of the same key into one, since they'd all miss the cache until the first ends.
+	inflight singleflight.Group
+	// Keys that reached the API, tracked with AssertResolveOncePerKey.
+	resolvedKeys   map[CacheKey]bool
+	resolvedKeysMu sync.Mutex
+	// Counters reported by Stats.
+	resolutions    atomic.Int64
+	cacheHits      atomic.Int64
+	apiCalls       atomic.Int64
+	listTagsCalls  atomic.Int64
+	commitSHACalls atomic.Int64
+	fallbacks      atomic.Int64
+}
+
+// ResolverStats counts the work of a VersionResolver, see VersionResolver.Stats.
+type ResolverStats struct {
+	// Resolutions is the number of refs resolved successfully, from the cache or through the API.
+	Resolutions int
+	// CacheHits is the number of resolutions served by the cache.
+	CacheHits int
+	// APICalls is the number of GitHub API calls made, including ListTagsCalls, GetCommitSHA1Calls and the calls
+	// retried against GitHub.com. Retries of a failed call by a retrying service count as one call.
+	APICalls int
+	// ListTagsCalls is the number of tag listing pages fetched.
+	ListTagsCalls int
+	// GetCommitSHA1Calls is the number of branch and commit lookups.
+	GetCommitSHA1Calls int
+	// Fallbacks is the number of calls retried against the GitHub.com fallback after the primary API failed.
+	Fallbacks int
+}
+
+// Stats returns the statistics of the resolver so far. It is safe to call while resolutions are in progress.
+func (r *VersionResolver) Stats() ResolverStats {
+	return ResolverStats{
+		Resolutions:        int(r.resolutions.Load()),
+		CacheHits:          int(r.cacheHits.Load()),
+		APICalls:           int(r.apiCalls.Load()),
+		ListTagsCalls:      int(r.listTagsCalls.Load()),
+		GetCommitSHA1Calls: int(r.commitSHACalls.Load()),
+		Fallbacks:          int(r.fallbacks.Load()),
+	}
 }
 
-func NewVersionResolver(repoService RepositoryService, fallbackRepoService RepositoryService) VersionResolver {
+func NewVersionResolver(repoService RepositoryService, fallbackRepoService RepositoryService, opts ResolverOptions) VersionResolver {
+	cache := opts.Cache
+	if cache == nil {
+		cache = NewMemoryCache()
+	}
+	if os.Getenv(ResolveOncePerKeyEnv) != "" {
+		opts.AssertResolveOncePerKey = true
+	}
 	return VersionResolver{
 		repoService:         repoService,
 		fallbackRepoService: fallbackRepoService,
-		cache:               make(map[cacheKey]ResolvedVersion),
+		opts:                opts,
+		cache:               cache,
+		negativeCache:       make(map[CacheKey]error),
+		resolvedKeys:        make(map[CacheKey]bool),
 	}
 }
 
 var AlreadyResolvedError = errors.New("already resolved")
 
+// DuplicateResolutionError is returned with AssertResolveOncePerKey when a cache key reaches the API a second time.
+var DuplicateResolutionError = errors.New("ref was already resolved through the API; the cache missed it")
+
 func (r *VersionResolver) ResolveVersion(ctx context.Context, def ActionDef) (ResolvedVersion, error) {
 	if def.HasCommitSHA() {
 		return ResolvedVersion{}, AlreadyResolvedError
 	}
 
-	key := cacheKey{
+	key := CacheKey{
 		Owner:    def.Owner,
 		Repo:     def.Repo,
 		RefOrSHA: def.RefOrSHA,
 	}
 
-	if cachedVersion, ok := r.cache[key]; ok {
+	if cachedVersion, ok := r.cache.Get(key); ok {
+		r.cacheHits.Add(1)
+		r.resolutions.Add(1)
+		return cachedVersion, nil
+	}
+
+	// Callers joining a resolution in flight share its result, including an error caused by the context of the
+	// caller that started it.
+	v, err, _ := r.inflight.Do(key.Owner+"/"+key.Repo+"@"+key.RefOrSHA, func() (any, error) {
+		return r.resolveUncached(ctx, key, def)
+	})
+	if err != nil {
+		return ResolvedVersion{}, err
+	}
+	r.resolutions.Add(1)
+	return v.(ResolvedVersion), nil
+}
+
+// resolveUncached resolves key through the API and caches the result. Only one call per key runs at a time.
+func (r *VersionResolver) resolveUncached(ctx context.Context, key CacheKey, def ActionDef) (ResolvedVersion, error) {
+	// A resolution of key may have ended between the cache lookup of the caller and the start of this one.
+	if cachedVersion, ok := r.cache.Get(key); ok {
+		r.cacheHits.Add(1)
 		return cachedVersion, nil
 	}
+	if err := r.cachedFailure(key); err != nil {
+		slog.Debug("ref is known to be unresolvable; skipping API calls", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+		return ResolvedVersion{}, err
+	}
+	if r.opts.AssertResolveOncePerKey {
+		if err := r.markResolved(key); err != nil {
+			return ResolvedVersion{}, err
+		}
+	}
+
+	resolved, err := r.resolveWithNames(ctx, def)
+	if err != nil {
+		if isForbidden(err) {
+			err = errors.Wrapf(NoAccessError, "%s/%s: %v", def.Owner, def.Repo, err)
+		}
+		if isUnresolvable(err) {
+			r.cacheFailure(key, err)
+		}
+		return ResolvedVersion{}, err
+	}
+
+	r.cache.Set(key, resolved)
+	return resolved, nil
+}
+
+// cachedFailure returns the error of a previous resolution of key that confirmed the ref doesn't exist, if any, from
+// this resolver or from the cache when it is a FailureCache.
+func (r *VersionResolver) cachedFailure(key CacheKey) error {
+	r.negativeCacheMu.Lock()
+	defer r.negativeCacheMu.Unlock()
+	if err := r.negativeCache[key]; err != nil {
+		return err
+	}
+	if fc, ok := r.cache.(FailureCache); ok {
+		if err := fc.GetFailure(key); err != nil {
+			r.negativeCache[key] = err
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheFailure records err, confirming that the ref of key doesn't exist.
+func (r *VersionResolver) cacheFailure(key CacheKey, err error) {
+	r.negativeCacheMu.Lock()
+	r.negativeCache[key] = err
+	r.negativeCacheMu.Unlock()
+	if fc, ok := r.cache.(FailureCache); ok {
+		fc.SetFailure(key, err)
+	}
+}
+
+// markResolved records that key reaches the API, failing if it already did.
+func (r *VersionResolver) markResolved(key CacheKey) error {
+	r.resolvedKeysMu.Lock()
+	defer r.resolvedKeysMu.Unlock()
+	if r.resolvedKeys[key] {
+		return errors.Wrapf(DuplicateResolutionError, "%s/%s@%s", key.Owner, key.Repo, key.RefOrSHA)
+	}
+	r.resolvedKeys[key] = true
+	return nil
+}
+
+// resolveWithNames resolves def and, with CanonicalizeNames, fills in the canonical owner/repo names.
+func (r *VersionResolver) resolveWithNames(ctx context.Context, def ActionDef) (ResolvedVersion, error) {
+	resolved, err := r.resolve(ctx, def)
+	if err != nil {
+		return ResolvedVersion{}, err
+	}
+
+	if r.opts.CanonicalizeNames {
+		repo, err := r.getRepository(ctx, def.Owner, def.Repo)
+		if err != nil {
+			return ResolvedVersion{}, err
+		}
+		resolved.CanonicalOwner = repo.GetOwner().GetLogin()
+		resolved.CanonicalRepo = repo.GetName()
+	}
+	return resolved, nil
+}
+
+// isUnresolvable reports whether err confirms that a ref doesn't exist, as opposed to a failure that may succeed when
+// retried later (rate limits, server errors, ...). A 404 is only final once the fallback, if any, also returned 404.
+func isUnresolvable(err error) bool {
+	return isNotFound(err) || errors.Is(err, RefNotFoundError) || errors.Is(err, NoTagsFoundError) ||
+		errors.Is(err, TagNotFoundError)
+}
+
+// resolve resolves def to a commit SHA without consulting the cache.
+func (r *VersionResolver) resolve(ctx context.Context, def ActionDef) (ResolvedVersion, error) {
+	if !def.HasRef() {
+		return r.resolveDefaultBranch(ctx, def)
+	}
+
+	// Fully qualified refs name their kind explicitly, so resolve them by the short name without guessing.
+	if branch, ok := strings.CutPrefix(def.RefOrSHA, "refs/heads/"); ok {
+		slog.Debug("fetching commit SHA for qualified branch", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+		sha, err := r.getCommitSHA(ctx, def.Owner, def.Repo, "heads/"+branch)
+		if err != nil {
+			return ResolvedVersion{}, err
+		}
+		return ResolvedVersion{CommitSHA: sha, RefComment: branch, WasBranch: true}, nil
+	}
+	if tag, ok := strings.CutPrefix(def.RefOrSHA, "refs/tags/"); ok {
+		def.RefOrSHA = tag
+		if def.VersionTag() == nil {
+			slog.Debug("fetching commit SHA for qualified tag", "owner", def.Owner, "repo", def.Repo, "ref", tag)
+			sha, err := r.getCommitSHA(ctx, def.Owner, def.Repo, "tags/"+tag)
+			if err != nil {
+				return ResolvedVersion{}, err
+			}
+			return ResolvedVersion{CommitSHA: sha, RefComment: tag}, nil
+		}
+	}
+
+	// `git describe` outputs parse as semver pre-releases, so they must be handled before the version tag path.
+	if r.opts.ResolveDescribe {
+		if shortSHA, ok := def.DescribeSHA(); ok {
+			slog.Debug("expanding commit SHA from git describe ref", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+			sha, err := r.getCommitSHA(ctx, def.Owner, def.Repo, shortSHA)
+			if err != nil {
+				return ResolvedVersion{}, err
+			}
+			return ResolvedVersion{CommitSHA: sha, RefComment: def.RefOrSHA}, nil
+		}
+	}
+
+	// Abbreviated SHAs would otherwise be looked up as branches, or as versions when they are all digits.
+	// refSHA is the commit of the branch or tag named like an abbreviated SHA, reused when it resolves as a branch.
+	var refSHA string
+	if def.HasAbbreviatedSHA() {
+		resolved, expanded, err := r.resolveAbbreviatedSHA(ctx, def)
+		switch {
+		case err == nil && expanded:
+			return resolved, nil
+		case err == nil:
+			slog.Debug("abbreviated SHA names a ref; resolving it as a ref", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+			refSHA = resolved.CommitSHA
+		case !isNotFound(err) && !hasStatus(err, http.StatusUnprocessableEntity) && !errors.Is(err, FallbackNotAllowedError):
+			return ResolvedVersion{}, err
+		default:
+			slog.Debug("no commit matches abbreviated SHA; resolving it as a ref", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+		}
+	}
+
+	if r.opts.PreferBranches && def.IsNumericRef() {
+		slog.Debug("looking up branch for numeric ref", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+		sha, err := r.getCommitSHA(ctx, def.Owner, def.Repo, "heads/"+def.RefOrSHA)
+		if err == nil {
+			return ResolvedVersion{CommitSHA: sha, RefComment: def.RefOrSHA, WasBranch: true}, nil
+		}
+		if !isNotFound(err) && !errors.Is(err, FallbackNotAllowedError) {
+			return ResolvedVersion{}, err
+		}
+		slog.Debug("no branch matches numeric ref; resolving it as a version", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+	}
+
+	if r.opts.ResolveLatest && def.RefOrSHA == LatestRef {
+		slog.Debug("resolving newest tag across all majors", "owner", def.Owner, "repo", def.Repo)
+		tags, err := r.listSemverTagsAll(ctx, def.Owner, def.Repo)
+		if err != nil {
+			return ResolvedVersion{}, errors.Wrapf(err, "failed to resolve %s for %s/%s", def.RefOrSHA, def.Owner, def.Repo)
+		}
+		newest, err := findNewestTag(tags, r.opts)
+		if err != nil {
+			return ResolvedVersion{}, errors.Wrapf(err, "failed to resolve %s for %s/%s", def.RefOrSHA, def.Owner, def.Repo)
+		}
+		return r.resolveTag(ctx, def, newest)
+	}
 
 	version := def.VersionTag()
 
 	// The ref is not a version tag, so treat it as a branch name.
 	if version == nil {
-		slog.Debug("fetching commit SHA for branch", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
-		// inside ResolveVersion, branch path (version == nil)
-		sha, _, err := r.repoService.GetCommitSHA1(ctx, def.Owner, def.Repo, def.RefOrSHA, "")
-		if err != nil && r.fallbackRepoService != nil && isNotFound(err) {
-			slog.Debug("GHES API returned 404 for commit; falling back to GitHub.com",
-				"owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
-			sha, _, err = r.fallbackRepoService.GetCommitSHA1(ctx, def.Owner, def.Repo, def.RefOrSHA, "")
+		if refSHA != "" {
+			return ResolvedVersion{CommitSHA: refSHA, RefComment: def.RefOrSHA, WasBranch: true}, nil
 		}
+		slog.Debug("fetching commit SHA for branch", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+		sha, err := r.getCommitSHA(ctx, def.Owner, def.Repo, def.RefOrSHA)
 		if err != nil {
-			return ResolvedVersion{}, errors.Wrapf(err, "failed to get commit SHA for %s/%s@%s", def.Owner, def.Repo, def.RefOrSHA)
+			return ResolvedVersion{}, err
 		}
-		resolved := ResolvedVersion{CommitSHA: sha, RefComment: def.RefOrSHA}
-		r.cache[key] = resolved
-		return resolved, nil
+		return ResolvedVersion{CommitSHA: sha, RefComment: def.RefOrSHA, WasBranch: true}, nil
 	}
 
 	tags, err := r.listSemverTagsAll(ctx, def.Owner, def.Repo)
@@ -135,17 +501,281 @@ func (r *VersionResolver) ResolveVersion(ctx context.Context, def ActionDef) (Re
 		return ResolvedVersion{}, errors.Wrapf(err, "failed to resolve version %s for %s/%s", def.RefOrSHA, def.Owner, def.Repo)
 	}
 
-	latest, err := findLatestTag(*version, tags)
+	latest, err := findLatestTag(*version, tags, r.opts)
 	if err != nil {
 		return ResolvedVersion{}, errors.Wrapf(err, "failed to resolve version %s for %s/%s", def.RefOrSHA, def.Owner, def.Repo)
 	}
+	return r.resolveTag(ctx, def, latest)
+}
+
+// resolveTag returns the commit of latest, a listed tag of the repository of def.
+func (r *VersionResolver) resolveTag(ctx context.Context, def ActionDef, latest semverTag) (ResolvedVersion, error) {
+	sha := latest.gogithubTag.GetCommit().GetSHA()
+	// Some GHES responses omit the commit in the tag listing; look the tag up directly instead of pinning to "".
+	if sha == "" {
+		slog.Debug("listed tag has no commit SHA; resolving tag via commits API",
+			"owner", def.Owner, "repo", def.Repo, "tag", latest.gogithubTag.GetName())
+		var err error
+		sha, err = r.getCommitSHA(ctx, def.Owner, def.Repo, latest.gogithubTag.GetName())
+		if err != nil {
+			return ResolvedVersion{}, err
+		}
+	}
+	sha, err := r.peelTag(ctx, def.Owner, def.Repo, latest.gogithubTag.GetName(), sha)
+	if err != nil {
+		return ResolvedVersion{}, err
+	}
 
-	resolved := ResolvedVersion{
-		CommitSHA:  latest.gogithubTag.GetCommit().GetSHA(),
+	return ResolvedVersion{
+		CommitSHA:  sha,
 		RefComment: latest.gogithubTag.GetName(),
+	}, nil
+}
+
+// resolveAbbreviatedSHA expands the abbreviated commit SHA of def to the full SHA. The comment is the highest tag
+// pointing at the commit, or the abbreviated SHA when there is none. The API resolves branch and tag names before
+// commits, so when the commit doesn't start with the ref, the ref names a branch or tag, e.g. a date tag 20240815:
+// it then returns the commit of that ref without expanding, for resolve to resolve the ref as such.
+func (r *VersionResolver) resolveAbbreviatedSHA(ctx context.Context, def ActionDef) (ResolvedVersion, bool, error) {
+	slog.Debug("expanding abbreviated commit SHA", "owner", def.Owner, "repo", def.Repo, "ref", def.RefOrSHA)
+	sha, err := r.getCommitSHA(ctx, def.Owner, def.Repo, def.RefOrSHA)
+	if err != nil {
+		return ResolvedVersion{}, false, err
 	}
-	r.cache[key] = resolved
-	return resolved, nil
+	if !strings.HasPrefix(strings.ToLower(sha), strings.ToLower(def.RefOrSHA)) {
+		return ResolvedVersion{CommitSHA: sha}, false, nil
+	}
+
+	comment := def.RefOrSHA
+	tag, err := r.FindTagForSHA(ctx, ActionDef{Owner: def.Owner, Repo: def.Repo, RefOrSHA: sha})
+	switch {
+	case err == nil:
+		comment = tag
+	case !errors.Is(err, TagForSHANotFoundError):
+		return ResolvedVersion{}, false, err
+	}
+	return ResolvedVersion{CommitSHA: sha, RefComment: comment}, true, nil
+}
+
+// resolveDefaultBranch resolves a reference without a ref to the head of the default branch of its repository.
+func (r *VersionResolver) resolveDefaultBranch(ctx context.Context, def ActionDef) (ResolvedVersion, error) {
+	slog.Debug("looking up default branch for reference without ref", "owner", def.Owner, "repo", def.Repo)
+	repo, err := r.getRepository(ctx, def.Owner, def.Repo)
+	if err != nil {
+		return ResolvedVersion{}, err
+	}
+	branch := repo.GetDefaultBranch()
+	if branch == "" {
+		return ResolvedVersion{}, errors.Newf("repository %s/%s has no default branch", def.Owner, def.Repo)
+	}
+	sha, err := r.getCommitSHA(ctx, def.Owner, def.Repo, "heads/"+branch)
+	if err != nil {
+		return ResolvedVersion{}, err
+	}
+	return ResolvedVersion{CommitSHA: sha, RefComment: branch, WasBranch: true}, nil
+}
+
+// TagForSHANotFoundError is returned by FindTagForSHA when no tag points at the commit.
+var TagForSHANotFoundError = errors.New("no tag points at the commit")
+
+// FindTagForSHA returns the name of a tag pointing at def's commit SHA, preferring the highest semver tag when several
+// do (e.g. v4.2.2 over v4).
+func (r *VersionResolver) FindTagForSHA(ctx context.Context, def ActionDef) (string, error) {
+	tags, err := r.listTagsAll(ctx, def.Owner, def.Repo)
+	if err != nil {
+		return "", err
+	}
+
+	var found string
+	var foundVersion *semver.Version
+	for _, tag := range tags {
+		if !strings.EqualFold(tag.GetCommit().GetSHA(), def.RefOrSHA) {
+			continue
+		}
+		v, err := semver.NewVersion(tag.GetName())
+		if err != nil {
+			// Only fall back to a non-semver tag when no semver tag matches.
+			if found == "" {
+				found = tag.GetName()
+			}
+			continue
+		}
+		if foundVersion == nil || v.GreaterThan(foundVersion) {
+			found, foundVersion = tag.GetName(), v
+		}
+	}
+
+	if found == "" {
+		return "", errors.Wrapf(TagForSHANotFoundError, "%s/%s@%s", def.Owner, def.Repo, def.RefOrSHA)
+	}
+	return found, nil
+}
+
+// CommitInfo is the metadata of a commit relevant to supply-chain triage.
+type CommitInfo struct {
+	// Committer date.
+	Date time.Time
+	// Whether GitHub verified the commit signature.
+	Verified bool
+	// GitHub's verification reason, e.g. "valid", "unsigned" or "unknown_key".
+	VerificationReason string
+}
+
+// CommitInfo fetches the metadata of the commit sha, falling back to GitHub.com when the primary API returns 404.
+func (r *VersionResolver) CommitInfo(ctx context.Context, owner, repo, sha string) (CommitInfo, error) {
+	r.apiCalls.Add(1)
+	commit, _, err := r.repoService.GetCommit(ctx, owner, repo, sha, nil)
+	if r.shouldFallback(err) {
+		if r.opts.FailOnFallback {
+			return CommitInfo{}, errors.Wrapf(FallbackNotAllowedError, "%s/%s@%s", owner, repo, sha)
+		}
+		logFallback(err, "commit", "owner", owner, "repo", repo, "sha", sha)
+		r.countFallback()
+		commit, _, err = r.fallbackRepoService.GetCommit(ctx, owner, repo, sha, nil)
+	}
+	if err != nil {
+		return CommitInfo{}, errors.Wrapf(err, "failed to get commit %s/%s@%s", owner, repo, sha)
+	}
+	verification := commit.GetCommit().GetVerification()
+	return CommitInfo{
+		Date:               commit.GetCommit().GetCommitter().GetDate().Time,
+		Verified:           verification.GetVerified(),
+		VerificationReason: verification.GetReason(),
+	}, nil
+}
+
+// VerifyCommit checks that sha is a commit of owner/repo, falling back to GitHub.com like CommitInfo. A repository or
+// commit the API doesn't know (404, or 422 for an unknown SHA) fails with CommitNotFoundError.
+func (r *VersionResolver) VerifyCommit(ctx context.Context, owner, repo, sha string) error {
+	_, err := r.CommitInfo(ctx, owner, repo, sha)
+	if isNotFound(err) || hasStatus(err, http.StatusUnprocessableEntity) {
+		return errors.Wrapf(CommitNotFoundError, "%s/%s@%s", owner, repo, sha)
+	}
+	return err
+}
+
+// CommitDate returns the committer date of the commit sha. See CommitInfo.
+func (r *VersionResolver) CommitDate(ctx context.Context, owner, repo, sha string) (time.Time, error) {
+	info, err := r.CommitInfo(ctx, owner, repo, sha)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.Date, nil
+}
+
+// Annotated tags pointing at annotated tags are legal in git; stop following them at some point.
+const maxTagPeelDepth = 10
+
+// TagNotCommitError is returned when a tag does not (eventually) point at a commit, e.g. a tag of a tree.
+var TagNotCommitError = errors.New("tag does not point at a commit")
+
+// peelTag returns the commit SHA the tag points at, dereferencing annotated tag objects. listedSHA is the commit SHA
+// reported by the tag listing, returned as is when no GitService is configured.
+func (r *VersionResolver) peelTag(ctx context.Context, owner, repo, tag, listedSHA string) (string, error) {
+	if r.opts.GitService == nil {
+		return listedSHA, nil
+	}
+
+	ref, err := r.getGitRef(ctx, owner, repo, "tags/"+tag)
+	if err != nil {
+		return "", err
+	}
+	obj := ref.GetObject()
+	for depth := 0; obj.GetType() == "tag"; depth++ {
+		if depth >= maxTagPeelDepth {
+			return "", errors.Wrapf(TagNotCommitError, "%s/%s@%s: too many nested tags", owner, repo, tag)
+		}
+		slog.Debug("peeling annotated tag", "owner", owner, "repo", repo, "tag", tag, "object", obj.GetSHA())
+		tagObj, err := r.getGitTag(ctx, owner, repo, obj.GetSHA())
+		if err != nil {
+			return "", err
+		}
+		obj = tagObj.GetObject()
+	}
+	if obj.GetType() != "commit" {
+		return "", errors.Wrapf(TagNotCommitError, "%s/%s@%s points at a %s", owner, repo, tag, obj.GetType())
+	}
+
+	if !strings.EqualFold(obj.GetSHA(), listedSHA) {
+		slog.Debug("tag listing reported a non-commit SHA; using the peeled commit",
+			"owner", owner, "repo", repo, "tag", tag, "listed", listedSHA, "commit", obj.GetSHA())
+	}
+	return obj.GetSHA(), nil
+}
+
+// getGitRef fetches a git reference, falling back to GitHub.com when the primary API returns 404.
+func (r *VersionResolver) getGitRef(ctx context.Context, owner, repo, ref string) (*gogithub.Reference, error) {
+	r.apiCalls.Add(1)
+	reference, _, err := r.opts.GitService.GetRef(ctx, owner, repo, ref)
+	if err != nil && r.opts.FallbackGitService != nil && r.fallbackStatus(err) {
+		if r.opts.FailOnFallback {
+			return nil, errors.Wrapf(FallbackNotAllowedError, "%s/%s@%s", owner, repo, ref)
+		}
+		logFallback(err, "ref", "owner", owner, "repo", repo, "ref", ref)
+		r.countFallback()
+		reference, _, err = r.opts.FallbackGitService.GetRef(ctx, owner, repo, ref)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get ref %s for %s/%s", ref, owner, repo)
+	}
+	return reference, nil
+}
+
+// getGitTag fetches an annotated tag object, falling back to GitHub.com when the primary API returns 404.
+func (r *VersionResolver) getGitTag(ctx context.Context, owner, repo, sha string) (*gogithub.Tag, error) {
+	r.apiCalls.Add(1)
+	tag, _, err := r.opts.GitService.GetTag(ctx, owner, repo, sha)
+	if err != nil && r.opts.FallbackGitService != nil && r.fallbackStatus(err) {
+		if r.opts.FailOnFallback {
+			return nil, errors.Wrapf(FallbackNotAllowedError, "%s/%s tag object %s", owner, repo, sha)
+		}
+		logFallback(err, "tag object", "owner", owner, "repo", repo, "sha", sha)
+		r.countFallback()
+		tag, _, err = r.opts.FallbackGitService.GetTag(ctx, owner, repo, sha)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tag object %s for %s/%s", sha, owner, repo)
+	}
+	return tag, nil
+}
+
+// getCommitSHA resolves ref (a branch name or a possibly abbreviated commit SHA) to a full commit SHA, falling back
+// to GitHub.com when the primary API returns 404.
+func (r *VersionResolver) getCommitSHA(ctx context.Context, owner, repo, ref string) (string, error) {
+	r.apiCalls.Add(1)
+	r.commitSHACalls.Add(1)
+	sha, _, err := r.repoService.GetCommitSHA1(ctx, owner, repo, ref, "")
+	if r.shouldFallback(err) {
+		if r.opts.FailOnFallback {
+			return "", errors.Wrapf(FallbackNotAllowedError, "%s/%s@%s", owner, repo, ref)
+		}
+		logFallback(err, "commit", "owner", owner, "repo", repo, "ref", ref)
+		r.countFallback()
+		r.commitSHACalls.Add(1)
+		sha, _, err = r.fallbackRepoService.GetCommitSHA1(ctx, owner, repo, ref, "")
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get commit SHA for %s/%s@%s", owner, repo, ref)
+	}
+	return sha, nil
+}
+
+// getRepository fetches repository metadata, falling back to GitHub.com when the primary API returns 404.
+func (r *VersionResolver) getRepository(ctx context.Context, owner, repo string) (*gogithub.Repository, error) {
+	r.apiCalls.Add(1)
+	repository, _, err := r.repoService.Get(ctx, owner, repo)
+	if r.shouldFallback(err) {
+		if r.opts.FailOnFallback {
+			return nil, errors.Wrapf(FallbackNotAllowedError, "%s/%s", owner, repo)
+		}
+		logFallback(err, "repository", "owner", owner, "repo", repo)
+		r.countFallback()
+		repository, _, err = r.fallbackRepoService.Get(ctx, owner, repo)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get repository %s/%s", owner, repo)
+	}
+	return repository, nil
 }
 
 type semverTag struct {
@@ -180,6 +810,8 @@ func (r *VersionResolver) listTagsAll(ctx context.Context, owner, repo string) (
 
 		for {
 			slog.Debug("fetching tags for version resolution", "owner", owner, "repo", repo, "page", opts.Page)
+			r.apiCalls.Add(1)
+			r.listTagsCalls.Add(1)
 			tags, resp, err := svc.ListTags(ctx, owner, repo, opts)
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed to list tags for %s/%s", owner, repo)
@@ -206,24 +838,129 @@ func (r *VersionResolver) listTagsAll(ctx context.Context, owner, repo string) (
 		return tags, nil
 	}
 
-	if r.fallbackRepoService != nil && isNotFound(err) {
+	if r.shouldFallback(err) {
+		if r.opts.FailOnFallback {
+			return nil, errors.Wrapf(FallbackNotAllowedError, "%s/%s", owner, repo)
+		}
 		// Log both attempts for clarity when GHES misses tags and we retry against GitHub.com.
-		slog.Debug("GHES returned 404; falling back to GitHub.com", "owner", owner, "repo", repo)
+		logFallback(err, "tags", "owner", owner, "repo", repo)
+		r.fallbacks.Add(1)
 		return fetchAll(r.fallbackRepoService)
 	}
 
 	return nil, err
 }
 
+// FallbackNotAllowedError is returned when a resolution would fall back to GitHub.com but FailOnFallback is set.
+var FallbackNotAllowedError = errors.New("resolution requires GitHub.com fallback, which is disabled by fail-on-fallback")
+
+// NoAccessError is returned when the token is denied access (403) to a repository, as opposed to the repository or
+// ref not existing (404).
+var NoAccessError = errors.New("the GitHub token has no access to the repository; " +
+	"grant it read access to the repository contents (fine-grained tokens are restricted to selected repositories)")
+
+// countFallback records a call retried against the GitHub.com fallback, which is also an API call.
+func (r *VersionResolver) countFallback() {
+	r.fallbacks.Add(1)
+	r.apiCalls.Add(1)
+}
+
+// shouldFallback reports whether a failed primary API call should be retried against the GitHub.com fallback.
+func (r *VersionResolver) shouldFallback(err error) bool {
+	return err != nil && r.fallbackRepoService != nil && r.fallbackStatus(err)
+}
+
+// fallbackStatus reports whether err is an API response calling for the GitHub.com fallback: one of FallbackStatuses
+// (404 by default), and 403 with FallbackOnForbidden. A 401 never does.
+func (r *VersionResolver) fallbackStatus(err error) bool {
+	status := statusCode(err)
+	switch {
+	case status == 0 || status == http.StatusUnauthorized:
+		return false
+	case status == http.StatusForbidden && r.opts.FallbackOnForbidden:
+		return true
+	case len(r.opts.FallbackStatuses) == 0:
+		return status == http.StatusNotFound
+	default:
+		return slices.Contains(r.opts.FallbackStatuses, status)
+	}
+}
+
+// ValidateFallbackStatuses checks that statuses, see ResolverOptions.FallbackStatuses, are HTTP error statuses that
+// can call for the fallback. 401 is rejected since a rejected token isn't specific to an action, as is 429 since rate
+// limits are retried against the primary API.
+func ValidateFallbackStatuses(statuses []int) error {
+	for _, status := range statuses {
+		switch {
+		case status == http.StatusUnauthorized:
+			return errors.New("fallback status 401 is not allowed: a rejected token must fail the run, not fall back")
+		case status == http.StatusTooManyRequests:
+			return errors.New("fallback status 429 is not allowed: rate limits are retried against the primary API")
+		case status < 400 || status > 599:
+			return errors.Newf("invalid fallback status %d: must be an HTTP error status (400-599)", status)
+		}
+	}
+	return nil
+}
+
+// logFallback logs that the primary API call fetching what is retried against GitHub.com, with the status that caused
+// the fallback. 404s, actions missing from GHES, are routine; other statuses hint at a problem of the primary API.
+func logFallback(err error, what string, attrs ...any) {
+	status := statusCode(err)
+	attrs = append(attrs, "status", status)
+	msg := fmt.Sprintf("primary API returned %d %s for %s; falling back to GitHub.com", status, http.StatusText(status), what)
+	if status == http.StatusNotFound {
+		slog.Debug(msg, attrs...)
+	} else {
+		slog.Info(msg, attrs...)
+	}
+}
+
+// IsAPIFailure reports whether err is a failure of the GitHub API itself rather than of a specific action: rejected
+// credentials (401), rate limiting, server errors or an unreachable API, including calls aborted because the run was
+// canceled or timed out. Such failures affect the whole run.
+func IsAPIFailure(err error) bool {
+	if isTransient(err) || hasStatus(err, http.StatusUnauthorized) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
 func isNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// isForbidden reports whether err is a 403 denying access. Rate limits are also answered with 403 but surface as
+// distinct error types, so they don't match.
+func isForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+func hasStatus(err error, status int) bool {
+	return statusCode(err) == status
+}
+
+// statusCode returns the HTTP status of the GitHub API response of err, or 0 when err isn't an API response.
+func statusCode(err error) int {
 	var ghErr *gogithub.ErrorResponse
-	if errors.As(err, &ghErr) {
-		return ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound
+	if errors.As(err, &ghErr) && ghErr.Response != nil {
+		return ghErr.Response.StatusCode
 	}
-	return false
+	return 0
 }
 
+// RefNotFoundError marks the failures restored from a FailureCache that were caused by a 404 of the API.
+var RefNotFoundError = errors.New("ref or repository not found")
+
+// LatestRef is the ref resolved to the newest release tag with ResolverOptions.ResolveLatest.
+const LatestRef = "latest"
+
 var NoTagsFoundError = errors.New("repository has no tags")
+var CommitNotFoundError = errors.New("commit not found")
 var TagNotFoundError = errors.New("specified tag not found")
 
 // Find the latest tag for the given version tag following semantic versioning rules.
@@ -233,8 +970,13 @@ var TagNotFoundError = errors.New("specified tag not found")
 // - v4.1 converts to latest v4.1.z (e.g., v4.1.2)
 // - v4.1.2 converts to latest v4.1.2 (if not found, retuns an error)
 //
-// This ignores pre-release tags and build metadata.
-func findLatestTag(definedVersion semver.Version, tags []semverTag) (semverTag, error) {
+// This ignores pre-release tags and build metadata, unless opts.AllowPrerelease: pre-releases then match when no
+// release does, or always when definedVersion is a pre-release itself.
+//
+// With opts.V0Strict, major version 0 is handled specially since every v0 minor may be breaking:
+// - v0 converts to latest v0.y.z within the highest existing v0.y line
+// - v0.0 converts to latest v0.0.z (by default the minor 0 is treated as unspecified)
+func findLatestTag(definedVersion semver.Version, tags []semverTag, opts ResolverOptions) (semverTag, error) {
 	if len(tags) == 0 {
 		return semverTag{}, NoTagsFoundError
 	}
@@ -250,9 +992,20 @@ func findLatestTag(definedVersion semver.Version, tags []semverTag) (semverTag,
 		exactVersion = true
 	}
 
+	minorSpecified := definedVersion.Minor() != 0
+	v0Strict := opts.V0Strict && definedVersion.Major() == 0
+	if v0Strict && len(parts) >= 2 {
+		minorSpecified = true
+	}
+
 	for _, tag := range tags {
-		// Skip prerelease tags
-		if tag.version.Prerelease() != "" {
+		if opts.AllowPrerelease && exactVersion {
+			// An exact ref (v1.0.0 or v1.0.0-rc.1) never resolves to another pre-release of the same patch.
+			if tag.version.Prerelease() != definedVersion.Prerelease() {
+				continue
+			}
+		} else if !opts.AllowPrerelease && tag.version.Prerelease() != "" {
+			// Skip prerelease tags
 			continue
 		}
 
@@ -262,7 +1015,7 @@ func findLatestTag(definedVersion semver.Version, tags []semverTag) (semverTag,
 		}
 
 		// If minor version specified in definedVersion, it must match
-		if definedVersion.Minor() != 0 && tag.version.Minor() != definedVersion.Minor() {
+		if minorSpecified && tag.version.Minor() != definedVersion.Minor() {
 			continue
 		}
 
@@ -275,16 +1028,121 @@ func findLatestTag(definedVersion semver.Version, tags []semverTag) (semverTag,
 	}
 
 	if len(matchingTags) == 0 {
-		return semverTag{}, errors.Newf("no matching tags found for version %s", definedVersion.String())
+		return semverTag{}, errors.Wrapf(TagNotFoundError, "no matching tags found for version %s", definedVersion.String())
+	}
+
+	// Releases win over newer pre-releases, unless the ref asks for a pre-release.
+	if opts.AllowPrerelease && definedVersion.Prerelease() == "" {
+		var releases []semverTag
+		for _, tag := range matchingTags {
+			if tag.version.Prerelease() == "" {
+				releases = append(releases, tag)
+			}
+		}
+		if len(releases) > 0 {
+			matchingTags = releases
+		}
+	}
+
+	// A bare v0 under the strict policy stays within a single minor line: the highest one.
+	if v0Strict && !minorSpecified {
+		highestMinor := matchingTags[0].version.Minor()
+		for _, tag := range matchingTags[1:] {
+			highestMinor = max(highestMinor, tag.version.Minor())
+		}
+		var sameLine []semverTag
+		for _, tag := range matchingTags {
+			if tag.version.Minor() == highestMinor {
+				sameLine = append(sameLine, tag)
+			}
+		}
+		matchingTags = sameLine
 	}
 
 	// Find the highest version tag
 	highestTag := matchingTags[0]
 	for _, tag := range matchingTags[1:] {
-		if tag.version.GreaterThan(&highestTag.version) {
+		if compareTags(tag, highestTag) > 0 {
 			highestTag = tag
 		}
 	}
 
 	return highestTag, nil
 }
+
+// findNewestTag returns the newest release tag across all majors, for LatestRef. Pre-release tags are ignored,
+// unless opts.AllowPrerelease and the repository has no release.
+func findNewestTag(tags []semverTag, opts ResolverOptions) (semverTag, error) {
+	if len(tags) == 0 {
+		return semverTag{}, NoTagsFoundError
+	}
+	var newest *semverTag
+	for i, tag := range tags {
+		stable := tag.version.Prerelease() == ""
+		if !stable && !opts.AllowPrerelease {
+			continue
+		}
+		switch {
+		case newest == nil:
+			newest = &tags[i]
+		case stable != (newest.version.Prerelease() == ""):
+			// Releases win over pre-releases regardless of precedence.
+			if stable {
+				newest = &tags[i]
+			}
+		case compareTags(tag, *newest) > 0:
+			newest = &tags[i]
+		}
+	}
+	if newest == nil {
+		return semverTag{}, errors.Wrap(TagNotFoundError, "no release tags found")
+	}
+	return *newest, nil
+}
+
+// compareTags orders tags by semver precedence, including pre-release precedence
+// (1.0.0-alpha < 1.0.0-alpha.1 < 1.0.0-beta < 1.0.0-beta.2 < 1.0.0-beta.11 < 1.0.0-rc.1 < 1.0.0).
+// Semver precedence ignores build metadata, so tags differing only in it are ordered by compareMetadata, a later
+// build of a release winning (1.0.0 < 1.0.0+build.2 < 1.0.0+build.11). Remaining ties (v1.0.0 and 1.0.0) are ordered
+// by name so that the result doesn't depend on the order the API lists tags in.
+//
+// Calendar versions such as 2024.08.15 parse as major 2024, minor 8 and patch 15, so they are ordered by date.
+func compareTags(a, b semverTag) int {
+	if c := a.version.Compare(&b.version); c != 0 {
+		return c
+	}
+	if c := compareMetadata(a.version.Metadata(), b.version.Metadata()); c != 0 {
+		return c
+	}
+	return strings.Compare(a.gogithubTag.GetName(), b.gogithubTag.GetName())
+}
+
+// compareMetadata orders build metadata like pre-release identifiers: dot-separated identifiers compared in turn,
+// numerically when both are numeric, numeric ones lower than others, and a longer list higher when all preceding
+// identifiers are equal. No metadata is the lowest.
+func compareMetadata(a, b string) int {
+	if a == "" || b == "" {
+		return strings.Compare(a, b)
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return len(as) - len(bs)
+}
+
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmp.Compare(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
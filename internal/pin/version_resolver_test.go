@@ -2,9 +2,14 @@ package pin
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/cockroachdb/errors"
 	gogithub "github.com/google/go-github/v72/github"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,7 +28,7 @@ func TestVersionResolver_ResolveVersion(t *testing.T) {
 			GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
 			Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil).Times(1)
 
-		resolver := NewVersionResolver(mockRepo, nil)
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
 
 		// First call should hit the API
 		def := ActionDef{
@@ -60,7 +65,7 @@ func TestVersionResolver_ResolveVersion(t *testing.T) {
 			ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
 			Return(tags, &gogithub.Response{NextPage: 0}, nil).Times(1)
 
-		resolver := NewVersionResolver(mockRepo, nil)
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
 
 		def := ActionDef{
 			Owner:    "actions",
@@ -102,6 +107,26 @@ func TestVersionResolver_ResolveVersion(t *testing.T) {
 			expected: ResolvedVersion{
 				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
 				RefComment: "main",
+				WasBranch:  true,
+			},
+		},
+		{
+			name: "Resolve commit SHA for branch with slash",
+			actionDef: ActionDef{
+				Owner:    "owner",
+				Repo:     "repo",
+				Path:     "sub",
+				RefOrSHA: "feature/foo",
+			},
+			mockSetup: func(mock *MockRepositoryService) {
+				mock.EXPECT().
+					GetCommitSHA1(gomock.Any(), "owner", "repo", "feature/foo", "").
+					Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil)
+			},
+			expected: ResolvedVersion{
+				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment: "feature/foo",
+				WasBranch:  true,
 			},
 		},
 		{
@@ -161,19 +186,997 @@ func TestVersionResolver_ResolveVersion(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			mockRepo := NewMockRepositoryService(ctrl)
-			if tt.mockSetup != nil {
-				tt.mockSetup(mockRepo)
-			}
-
-			resolver := NewVersionResolver(mockRepo, nil)
-
-			result, err := resolver.ResolveVersion(context.Background(), tt.actionDef)
-			require.NoError(t, err)
-			assert.Equal(t, tt.expected.CommitSHA, result.CommitSHA)
-			assert.Equal(t, tt.expected.RefComment, result.RefComment)
+			mockRepo := NewMockRepositoryService(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockRepo)
+			}
+
+			resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+
+			result, err := resolver.ResolveVersion(context.Background(), tt.actionDef)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected.CommitSHA, result.CommitSHA)
+			assert.Equal(t, tt.expected.RefComment, result.RefComment)
+		})
+	}
+}
+
+func TestVersionResolver_ResolveDescribe(t *testing.T) {
+	def := ActionDef{
+		Owner:    "actions",
+		Repo:     "checkout",
+		RefOrSHA: "v4.1.1-3-gabcdef0",
+	}
+
+	t.Run("Expands embedded SHA when enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().
+			GetCommitSHA1(gomock.Any(), "actions", "checkout", "abcdef0", "").
+			Return("abcdef01bbe5b1630ceea73d27597364c9af6831", &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{ResolveDescribe: true})
+
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, "abcdef01bbe5b1630ceea73d27597364c9af6831", result.CommitSHA)
+		assert.Equal(t, "v4.1.1-3-gabcdef0", result.RefComment)
+	})
+
+	t.Run("Resolves as semver tag when disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().
+			ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{
+				createTag("v4.1.1", "sha1"),
+				createTag("v4.2.0", "sha2"),
+			}, &gogithub.Response{NextPage: 0}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, "sha1", result.CommitSHA)
+		assert.Equal(t, "v4.1.1", result.RefComment)
+	})
+}
+
+func TestVersionResolver_PreferBranches(t *testing.T) {
+	def := ActionDef{Owner: "org", Repo: "action", RefOrSHA: "4"}
+	tags := []*gogithub.RepositoryTag{
+		createTag("v4.1.0", "sha-tag-410"),
+		createTag("v4.1.2", "sha-tag-412"),
+	}
+
+	t.Run("Tags preferred by default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "org", "action", gomock.Any()).
+			Return(tags, &gogithub.Response{NextPage: 0}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: "sha-tag-412", RefComment: "v4.1.2"}, result)
+	})
+
+	t.Run("Branch preferred", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "action", "heads/4", "").
+			Return("sha-branch-4", &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{PreferBranches: true})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: "sha-branch-4", RefComment: "4", WasBranch: true}, result)
+	})
+
+	t.Run("Branch preferred but missing resolves as a version", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "action", "heads/4.1", "").
+			Return("", nil, notFoundError())
+		mockRepo.EXPECT().ListTags(gomock.Any(), "org", "action", gomock.Any()).
+			Return(tags, &gogithub.Response{NextPage: 0}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{PreferBranches: true})
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "action", RefOrSHA: "4.1"})
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: "sha-tag-412", RefComment: "v4.1.2"}, result)
+	})
+
+	t.Run("Branch preferred only applies to numeric refs", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "org", "action", gomock.Any()).
+			Return(tags, &gogithub.Response{NextPage: 0}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{PreferBranches: true})
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "action", RefOrSHA: "v4"})
+		require.NoError(t, err)
+		assert.Equal(t, "sha-tag-412", result.CommitSHA)
+	})
+
+	t.Run("Branch lookup errors other than 404 fail", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "action", "heads/4", "").
+			Return("", nil, fmt.Errorf("boom"))
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{PreferBranches: true})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.Error(t, err)
+	})
+}
+
+func TestActionDef_IsNumericRef(t *testing.T) {
+	for ref, expected := range map[string]bool{
+		"4": true, "4.1": true, "4.1.2": true, "10": true,
+		"v4": false, "4.1.2.3": false, "4-beta": false, "main": false, "4.x": false,
+	} {
+		def := ActionDef{RefOrSHA: ref}
+		assert.Equal(t, expected, def.IsNumericRef(), ref)
+	}
+	// Numeric refs still parse as versions.
+	v := ActionDef{RefOrSHA: "4"}.VersionTag()
+	require.NotNil(t, v)
+	assert.Equal(t, "4.0.0", v.String())
+}
+
+func TestActionDef_DescribeSHA(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected string
+		ok       bool
+	}{
+		{ref: "v4.1.1-3-gabcdef0", expected: "abcdef0", ok: true},
+		{ref: "4.1.1-12-g0123456789ab", expected: "0123456789ab", ok: true},
+		{ref: "release-2024-1-gABCDEF1", expected: "ABCDEF1", ok: true},
+		{ref: "v4.1.1", ok: false},
+		{ref: "v4.1.1-rc.1", ok: false},
+		{ref: "v4.1.1-3-gxyz1234", ok: false},
+		{ref: "main", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, ok := ActionDef{RefOrSHA: tt.ref}.DescribeSHA()
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestActionDef_HasAbbreviatedSHA(t *testing.T) {
+	for ref, expected := range map[string]bool{
+		"11bd719":      true,
+		"11bd71901bbe": true,
+		"11BD71901BBE5B1630CEEA73D27597364C9AF68": true,
+		"1234567": true,
+		"11bd71901bbe5b1630ceea73d27597364c9af683": false, // Full SHA
+		"11bd71":  false,
+		"v4.1.1":  false,
+		"main":    false,
+		"11bd71g": false,
+	} {
+		def := ActionDef{RefOrSHA: ref}
+		assert.Equal(t, expected, def.HasAbbreviatedSHA(), ref)
+	}
+}
+
+func TestVersionResolver_AbbreviatedSHA(t *testing.T) {
+	const full = "11bd71901bbe5b1630ceea73d27597364c9af683"
+
+	for _, ref := range []string{"11bd719", "11bd71901bbe"} {
+		t.Run("Expands "+ref+" with the tag of the commit", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := NewMockRepositoryService(ctrl)
+			mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", ref, "").
+				Return(full, &gogithub.Response{}, nil).Times(1)
+			mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+				Return([]*gogithub.RepositoryTag{createTag("v4", full), createTag("v4.2.2", full), createTag("v4.2.1", "other")},
+					&gogithub.Response{NextPage: 0}, nil).Times(1)
+
+			resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+			result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: ref})
+			require.NoError(t, err)
+			assert.Equal(t, ResolvedVersion{CommitSHA: full, RefComment: "v4.2.2"}, result)
+		})
+	}
+
+	t.Run("Untagged commit keeps the abbreviated SHA as comment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "11bd719", "").
+			Return(full, &gogithub.Response{}, nil).Times(1)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(nil, &gogithub.Response{NextPage: 0}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "11bd719"})
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: full, RefComment: "11bd719"}, result)
+	})
+
+	t.Run("Branch named like a SHA", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "repo", "deadbeef", "").
+			Return(full, &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "repo", RefOrSHA: "deadbeef"})
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: full, RefComment: "deadbeef", WasBranch: true}, result)
+	})
+
+	t.Run("Tag named like a SHA resolves as a tag", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "repo", "20240815", "").
+			Return(full, &gogithub.Response{}, nil).Times(1)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "org", "repo", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("20240815", full), createTag("20240701", "other")},
+				&gogithub.Response{NextPage: 0}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "repo", RefOrSHA: "20240815"})
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: full, RefComment: "20240815"}, result)
+	})
+
+	t.Run("Numeric ref without a matching commit resolves as a version", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "org", "repo", "2024081", "").
+			Return("", nil, apiError(http.StatusUnprocessableEntity)).Times(1)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "org", "repo", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("2024081.0.1", full)}, &gogithub.Response{NextPage: 0}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "repo", RefOrSHA: "2024081"})
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: full, RefComment: "2024081.0.1"}, result)
+	})
+}
+
+func TestVersionResolver_TagWithoutCommitSHA(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := NewMockRepositoryService(ctrl)
+	name := "v4.1.1"
+	mockRepo.EXPECT().
+		ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+		Return([]*gogithub.RepositoryTag{
+			createTag("v4.0.0", "sha1"),
+			{Name: &name}, // no commit in the listing
+		}, &gogithub.Response{NextPage: 0}, nil)
+	mockRepo.EXPECT().
+		GetCommitSHA1(gomock.Any(), "actions", "checkout", "v4.1.1", "").
+		Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil).Times(1)
+
+	resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+
+	result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"})
+	require.NoError(t, err)
+	assert.Equal(t, "11bd71901bbe5b1630ceea73d27597364c9af683", result.CommitSHA)
+	assert.Equal(t, "v4.1.1", result.RefComment)
+}
+
+func TestVersionResolver_ContextDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// A hung connection only returns once the context is done, like the HTTP client does.
+	slow := NewMockRepositoryService(ctrl)
+	slow.EXPECT().GetCommitSHA1(gomock.Any(), "org", "action", "main", "").
+		DoAndReturn(func(ctx context.Context, _, _, _, _ string) (string, *gogithub.Response, error) {
+			<-ctx.Done()
+			return "", nil, ctx.Err()
+		}).Times(1)
+	resolver := NewVersionResolver(NewRetryingRepositoryService(slow, nil, RetryOptions{}), nil, ResolverOptions{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := resolver.ResolveVersion(ctx, ActionDef{Owner: "org", Repo: "action", RefOrSHA: "main"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "org/action@main")
+	assert.True(t, IsAPIFailure(err), "aborted calls fail the run instead of the action")
+
+	// The aborted resolution isn't remembered as a failure of the ref.
+	assert.NoError(t, resolver.cachedFailure(CacheKey{Owner: "org", Repo: "action", RefOrSHA: "main"}))
+}
+
+func TestVersionResolver_NegativeCache(t *testing.T) {
+	t.Run("Branch missing on primary and fallback is looked up once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl)
+		primary.EXPECT().GetCommitSHA1(gomock.Any(), "org", "missing", "main", "").
+			Return("", nil, notFoundError()).Times(1)
+		fallback.EXPECT().GetCommitSHA1(gomock.Any(), "org", "missing", "main", "").
+			Return("", nil, notFoundError()).Times(1)
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{})
+		def := ActionDef{Owner: "org", Repo: "missing", RefOrSHA: "main"}
+		for range 3 {
+			_, err := resolver.ResolveVersion(context.Background(), def)
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("Missing version tag is looked up once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("v4.0.0", "sha1")}, &gogithub.Response{NextPage: 0}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		def := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v9"}
+		for range 2 {
+			_, err := resolver.ResolveVersion(context.Background(), def)
+			require.ErrorIs(t, err, TagNotFoundError)
+		}
+	})
+
+	t.Run("Transient errors are not cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		gomock.InOrder(
+			mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+				Return("", nil, apiError(http.StatusBadGateway)),
+			mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+				Return("sha", &gogithub.Response{}, nil),
+		)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		def := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"}
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.Error(t, err)
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, "sha", result.CommitSHA)
+	})
+}
+
+func TestVersionResolver_ConcurrentResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const repos = 10
+	mockRepo := NewMockRepositoryService(ctrl)
+	for i := range repos {
+		repo := fmt.Sprintf("repo%d", i)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "org", repo, gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("v1.0.0", "old"), createTag("v1.2.0", repo+"-sha")},
+				&gogithub.Response{NextPage: 0}, nil).
+			Times(1)
+	}
+
+	resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+	var wg sync.WaitGroup
+	for range 10 {
+		for i := range repos {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				repo := fmt.Sprintf("repo%d", i)
+				result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: repo, RefOrSHA: "v1"})
+				assert.NoError(t, err)
+				assert.Equal(t, repo+"-sha", result.CommitSHA)
+				assert.Equal(t, "v1.2.0", result.RefComment)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestVersionResolver_SingleflightResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := NewMockRepositoryService(ctrl)
+	mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+		DoAndReturn(func(context.Context, string, string, *gogithub.ListOptions) ([]*gogithub.RepositoryTag, *gogithub.Response, error) {
+			// Keep the listing in flight while the other goroutines miss the cache.
+			time.Sleep(50 * time.Millisecond)
+			return []*gogithub.RepositoryTag{createTag("v4.1.1", "sha1")}, &gogithub.Response{NextPage: 0}, nil
+		}).
+		Times(1)
+
+	resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{AssertResolveOncePerKey: true})
+	const goroutines = 50
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"})
+			assert.NoError(t, err)
+			assert.Equal(t, "sha1", result.CommitSHA)
+		}()
+	}
+	wg.Wait()
+
+	stats := resolver.Stats()
+	assert.Equal(t, goroutines, stats.Resolutions)
+	assert.Equal(t, 1, stats.ListTagsCalls)
+}
+
+func TestVersionResolver_PeelTag(t *testing.T) {
+	def := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"}
+	commitSHA := "11bd71901bbe5b1630ceea73d27597364c9af683"
+	tagObjectSHA := "c3bd8b7b1ab2e1a5b1b3c2e1f0a9d8c7b6a5f4e3"
+	gitObject := func(typ, sha string) *gogithub.GitObject {
+		return &gogithub.GitObject{Type: &typ, SHA: &sha}
+	}
+
+	t.Run("Annotated tag is peeled to the commit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("v4.2.2", tagObjectSHA)}, &gogithub.Response{NextPage: 0}, nil)
+		mockGit := NewMockGitService(ctrl)
+		mockGit.EXPECT().GetRef(gomock.Any(), "actions", "checkout", "tags/v4.2.2").
+			Return(&gogithub.Reference{Object: gitObject("tag", tagObjectSHA)}, &gogithub.Response{}, nil)
+		mockGit.EXPECT().GetTag(gomock.Any(), "actions", "checkout", tagObjectSHA).
+			Return(&gogithub.Tag{Object: gitObject("commit", commitSHA)}, &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{GitService: mockGit})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, commitSHA, result.CommitSHA)
+		assert.Equal(t, "v4.2.2", result.RefComment)
+	})
+
+	t.Run("Lightweight tag needs no tag object lookup", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("v4.2.2", commitSHA)}, &gogithub.Response{NextPage: 0}, nil)
+		mockGit := NewMockGitService(ctrl)
+		mockGit.EXPECT().GetRef(gomock.Any(), "actions", "checkout", "tags/v4.2.2").
+			Return(&gogithub.Reference{Object: gitObject("commit", commitSHA)}, &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{GitService: mockGit})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, commitSHA, result.CommitSHA)
+	})
+
+	t.Run("Tag of a tree is an error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("v4.2.2", tagObjectSHA)}, &gogithub.Response{NextPage: 0}, nil)
+		mockGit := NewMockGitService(ctrl)
+		mockGit.EXPECT().GetRef(gomock.Any(), "actions", "checkout", "tags/v4.2.2").
+			Return(&gogithub.Reference{Object: gitObject("tag", tagObjectSHA)}, &gogithub.Response{}, nil)
+		mockGit.EXPECT().GetTag(gomock.Any(), "actions", "checkout", tagObjectSHA).
+			Return(&gogithub.Tag{Object: gitObject("tree", commitSHA)}, &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{GitService: mockGit})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.ErrorIs(t, err, TagNotCommitError)
+	})
+}
+
+func TestVersionResolver_FindTagForSHA(t *testing.T) {
+	sha := "11bd71901bbe5b1630ceea73d27597364c9af683"
+	tags := []*gogithub.RepositoryTag{
+		createTag("latest", sha),
+		createTag("v4", sha),
+		createTag("v4.2.2", sha),
+		createTag("v4.2.1", "other"),
+	}
+
+	t.Run("Prefers the highest semver tag at the commit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(tags, &gogithub.Response{NextPage: 0}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		tag, err := resolver.FindTagForSHA(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: sha})
+		require.NoError(t, err)
+		assert.Equal(t, "v4.2.2", tag)
+	})
+
+	t.Run("No tag at the commit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(tags, &gogithub.Response{NextPage: 0}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		_, err := resolver.FindTagForSHA(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "8843d7f53bd34e3b78f2acee556ba5d53feae7c4"})
+		require.ErrorIs(t, err, TagForSHANotFoundError)
+	})
+}
+
+func TestVersionResolver_CommitDate(t *testing.T) {
+	sha := "11bd71901bbe5b1630ceea73d27597364c9af683"
+	date := time.Date(2024, 10, 23, 14, 46, 0, 0, time.UTC)
+	commit := &gogithub.RepositoryCommit{
+		Commit: &gogithub.Commit{Committer: &gogithub.CommitAuthor{Date: &gogithub.Timestamp{Time: date}}},
+	}
+
+	t.Run("Returns the committer date", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommit(gomock.Any(), "actions", "checkout", sha, gomock.Any()).
+			Return(commit, &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		got, err := resolver.CommitDate(context.Background(), "actions", "checkout", sha)
+		require.NoError(t, err)
+		assert.True(t, date.Equal(got))
+	})
+
+	t.Run("Falls back on 404", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl)
+		primary.EXPECT().GetCommit(gomock.Any(), "actions", "checkout", sha, gomock.Any()).
+			Return(nil, nil, notFoundError())
+		fallback.EXPECT().GetCommit(gomock.Any(), "actions", "checkout", sha, gomock.Any()).
+			Return(commit, &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{})
+		got, err := resolver.CommitDate(context.Background(), "actions", "checkout", sha)
+		require.NoError(t, err)
+		assert.True(t, date.Equal(got))
+	})
+}
+
+func TestVersionResolver_CommitInfo(t *testing.T) {
+	sha := "11bd71901bbe5b1630ceea73d27597364c9af683"
+	date := time.Date(2024, 10, 23, 14, 46, 0, 0, time.UTC)
+	verified, reason := true, "valid"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := NewMockRepositoryService(ctrl)
+	mockRepo.EXPECT().GetCommit(gomock.Any(), "actions", "checkout", sha, gomock.Any()).
+		Return(&gogithub.RepositoryCommit{Commit: &gogithub.Commit{
+			Committer:    &gogithub.CommitAuthor{Date: &gogithub.Timestamp{Time: date}},
+			Verification: &gogithub.SignatureVerification{Verified: &verified, Reason: &reason},
+		}}, &gogithub.Response{}, nil)
+
+	resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+	info, err := resolver.CommitInfo(context.Background(), "actions", "checkout", sha)
+	require.NoError(t, err)
+	assert.True(t, date.Equal(info.Date))
+	assert.True(t, info.Verified)
+	assert.Equal(t, "valid", info.VerificationReason)
+}
+
+func TestVersionResolver_VerifyCommit(t *testing.T) {
+	sha := "11bd71901bbe5b1630ceea73d27597364c9af683"
+	tests := []struct {
+		name        string
+		err         error
+		notFound    bool
+		expectError bool
+	}{
+		{name: "Existing commit"},
+		{name: "Unknown SHA", err: apiError(http.StatusUnprocessableEntity), notFound: true, expectError: true},
+		{name: "Unknown repository", err: notFoundError(), notFound: true, expectError: true},
+		{name: "Server error", err: apiError(http.StatusInternalServerError), expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := NewMockRepositoryService(ctrl)
+			var commit *gogithub.RepositoryCommit
+			if tt.err == nil {
+				commit = &gogithub.RepositoryCommit{Commit: &gogithub.Commit{}}
+			}
+			mockRepo.EXPECT().GetCommit(gomock.Any(), "actions", "checkout", sha, gomock.Any()).
+				Return(commit, &gogithub.Response{}, tt.err)
+
+			resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+			err := resolver.VerifyCommit(context.Background(), "actions", "checkout", sha)
+			if !tt.expectError {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.notFound, errors.Is(err, CommitNotFoundError))
+		})
+	}
+}
+
+func TestVersionResolver_CanonicalizeNames(t *testing.T) {
+	def := ActionDef{Owner: "Actions", Repo: "Checkout", RefOrSHA: "main"}
+	login, name := "actions", "checkout"
+
+	t.Run("Fills canonical names when enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().
+			GetCommitSHA1(gomock.Any(), "Actions", "Checkout", "main", "").
+			Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil)
+		mockRepo.EXPECT().
+			Get(gomock.Any(), "Actions", "Checkout").
+			Return(&gogithub.Repository{Name: &name, Owner: &gogithub.User{Login: &login}}, &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{CanonicalizeNames: true})
+
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, "actions", result.CanonicalOwner)
+		assert.Equal(t, "checkout", result.CanonicalRepo)
+
+		// Cached together with the resolution
+		_, err = resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+	})
+
+	t.Run("No repository lookup when disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().
+			GetCommitSHA1(gomock.Any(), "Actions", "Checkout", "main", "").
+			Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Empty(t, result.CanonicalOwner)
+		assert.Empty(t, result.CanonicalRepo)
+	})
+}
+
+func TestVersionResolver_ResolveLatest(t *testing.T) {
+	tags := []*gogithub.RepositoryTag{
+		createTag("v6.0.0-rc.1", "sha-rc"),
+		createTag("v5.2.0", "sha-5"),
+		createTag("v4.10.1", "sha-4"),
+	}
+	def := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "latest"}
+
+	t.Run("Newest release across majors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(tags, &gogithub.Response{NextPage: 0}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{ResolveLatest: true})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: "sha-5", RefComment: "v5.2.0"}, result)
+	})
+
+	t.Run("Branch named latest without the option", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "latest", "").
+			Return("sha-branch", &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: "sha-branch", RefComment: "latest", WasBranch: true}, result)
+	})
+}
+
+func TestVersionResolver_DefaultBranch(t *testing.T) {
+	def := ActionDef{Owner: "actions", Repo: "checkout"}
+	assert.Equal(t, "actions/checkout", def.String())
+
+	t.Run("Resolves the head of the default branch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		branch := "trunk"
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().
+			Get(gomock.Any(), "actions", "checkout").
+			Return(&gogithub.Repository{DefaultBranch: &branch}, &gogithub.Response{}, nil).Times(1)
+		mockRepo.EXPECT().
+			GetCommitSHA1(gomock.Any(), "actions", "checkout", "heads/trunk", "").
+			Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "trunk", WasBranch: true}, result)
+
+		// Cached like any other ref
+		_, err = resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+	})
+
+	t.Run("Repository without default branch fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().
+			Get(gomock.Any(), "actions", "checkout").
+			Return(&gogithub.Repository{}, &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.ErrorContains(t, err, "has no default branch")
+	})
+}
+
+func TestVersionResolver_Forbidden(t *testing.T) {
+	def := ActionDef{Owner: "org", Repo: "private-action", RefOrSHA: "v1"}
+
+	t.Run("403 is reported as no access without falling back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl) // no expectations: any call fails the test
+		primary.EXPECT().ListTags(gomock.Any(), "org", "private-action", gomock.Any()).
+			Return(nil, nil, apiError(http.StatusForbidden))
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.ErrorIs(t, err, NoAccessError)
+		assert.Contains(t, err.Error(), "org/private-action")
+		assert.False(t, isNotFound(err))
+	})
+
+	t.Run("404 is not reported as no access", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		primary.EXPECT().ListTags(gomock.Any(), "org", "private-action", gomock.Any()).
+			Return(nil, nil, notFoundError())
+
+		resolver := NewVersionResolver(primary, nil, ResolverOptions{})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, NoAccessError)
+		assert.True(t, isNotFound(err))
+	})
+
+	t.Run("403 on a branch is reported as no access", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		primary.EXPECT().GetCommitSHA1(gomock.Any(), "org", "private-action", "main", "").
+			Return("", nil, apiError(http.StatusForbidden))
+
+		resolver := NewVersionResolver(primary, nil, ResolverOptions{})
+		_, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "private-action", RefOrSHA: "main"})
+		require.ErrorIs(t, err, NoAccessError)
+	})
+
+	t.Run("403 is not cached as unresolvable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		primary.EXPECT().ListTags(gomock.Any(), "org", "private-action", gomock.Any()).
+			Return(nil, nil, apiError(http.StatusForbidden)).Times(2)
+
+		resolver := NewVersionResolver(primary, nil, ResolverOptions{})
+		for range 2 {
+			_, err := resolver.ResolveVersion(context.Background(), def)
+			require.ErrorIs(t, err, NoAccessError)
+		}
+	})
+
+	t.Run("FallbackOnForbidden retries against GitHub.com", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl)
+		primary.EXPECT().ListTags(gomock.Any(), "org", "private-action", gomock.Any()).
+			Return(nil, nil, apiError(http.StatusForbidden))
+		fallback.EXPECT().ListTags(gomock.Any(), "org", "private-action", gomock.Any()).
+			Return([]*gogithub.RepositoryTag{createTag("v1.2.0", "sha120")}, &gogithub.Response{NextPage: 0}, nil)
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{FallbackOnForbidden: true})
+		result, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		assert.Equal(t, ResolvedVersion{CommitSHA: "sha120", RefComment: "v1.2.0"}, result)
+	})
+
+	t.Run("FallbackOnForbidden still honors FailOnFallback", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl)
+		primary.EXPECT().ListTags(gomock.Any(), "org", "private-action", gomock.Any()).
+			Return(nil, nil, apiError(http.StatusForbidden))
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{FallbackOnForbidden: true, FailOnFallback: true})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.ErrorIs(t, err, FallbackNotAllowedError)
+	})
+}
+
+func TestVersionResolver_ShouldFallback(t *testing.T) {
+	rateLimitErr := &gogithub.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}}
+
+	tests := []struct {
+		name     string
+		opts     ResolverOptions
+		err      error
+		expected bool
+	}{
+		{"No error", ResolverOptions{}, nil, false},
+		{"Not an API response", ResolverOptions{}, errors.New("boom"), false},
+		{"404 by default", ResolverOptions{}, notFoundError(), true},
+		{"Wrapped 404", ResolverOptions{}, errors.Wrap(notFoundError(), "context"), true},
+		{"403 by default", ResolverOptions{}, apiError(http.StatusForbidden), false},
+		{"403 with FallbackOnForbidden", ResolverOptions{FallbackOnForbidden: true}, apiError(http.StatusForbidden), true},
+		{"403 in FallbackStatuses", ResolverOptions{FallbackStatuses: []int{404, 403}}, apiError(http.StatusForbidden), true},
+		{"Rate limit with FallbackOnForbidden", ResolverOptions{FallbackOnForbidden: true}, rateLimitErr, false},
+		{"500 by default", ResolverOptions{}, apiError(http.StatusInternalServerError), false},
+		{"500 in FallbackStatuses", ResolverOptions{FallbackStatuses: []int{500}}, apiError(http.StatusInternalServerError), true},
+		{"404 missing from FallbackStatuses", ResolverOptions{FallbackStatuses: []int{500}}, notFoundError(), false},
+		{"401 never", ResolverOptions{FallbackStatuses: []int{401}, FallbackOnForbidden: true}, apiError(http.StatusUnauthorized), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			resolver := NewVersionResolver(NewMockRepositoryService(ctrl), NewMockRepositoryService(ctrl), tt.opts)
+			assert.Equal(t, tt.expected, resolver.shouldFallback(tt.err))
 		})
 	}
+
+	t.Run("No fallback service", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		resolver := NewVersionResolver(NewMockRepositoryService(ctrl), nil, ResolverOptions{})
+		assert.False(t, resolver.shouldFallback(notFoundError()))
+	})
+
+	t.Run("Server error falls back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		primary.EXPECT().GetCommitSHA1(gomock.Any(), "org", "action", "main", "").
+			Return("", nil, apiError(http.StatusInternalServerError))
+		fallback := NewMockRepositoryService(ctrl)
+		fallback.EXPECT().GetCommitSHA1(gomock.Any(), "org", "action", "main", "").
+			Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{FallbackStatuses: []int{404, 500}})
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "org", Repo: "action", RefOrSHA: "main"})
+		require.NoError(t, err)
+		assert.Equal(t, "11bd71901bbe5b1630ceea73d27597364c9af683", result.CommitSHA)
+		assert.Equal(t, 1, resolver.Stats().Fallbacks)
+	})
+}
+
+func TestValidateFallbackStatuses(t *testing.T) {
+	require.NoError(t, ValidateFallbackStatuses(nil))
+	require.NoError(t, ValidateFallbackStatuses([]int{403, 404, 500, 502}))
+	require.ErrorContains(t, ValidateFallbackStatuses([]int{404, 401}), "401 is not allowed")
+	require.ErrorContains(t, ValidateFallbackStatuses([]int{429}), "429 is not allowed")
+	require.ErrorContains(t, ValidateFallbackStatuses([]int{200}), "invalid fallback status 200")
+}
+
+func TestVersionResolver_FailOnFallback(t *testing.T) {
+	t.Run("Tag listing 404 errors instead of falling back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl) // no expectations: any call fails the test
+		primary.EXPECT().
+			ListTags(gomock.Any(), "actions", "checkout", gomock.Any()).
+			Return(nil, nil, notFoundError())
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{FailOnFallback: true})
+
+		_, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"})
+		require.ErrorIs(t, err, FallbackNotAllowedError)
+		assert.Contains(t, err.Error(), "actions/checkout")
+	})
+
+	t.Run("Branch 404 errors instead of falling back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl)
+		primary.EXPECT().
+			GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("", nil, notFoundError())
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{FailOnFallback: true})
+
+		_, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"})
+		require.ErrorIs(t, err, FallbackNotAllowedError)
+		assert.Contains(t, err.Error(), "actions/checkout@main")
+	})
+
+	t.Run("Falls back when the option is disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockRepositoryService(ctrl)
+		fallback := NewMockRepositoryService(ctrl)
+		primary.EXPECT().
+			GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("", nil, notFoundError())
+		fallback.EXPECT().
+			GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return("11bd71901bbe5b1630ceea73d27597364c9af683", &gogithub.Response{}, nil)
+
+		resolver := NewVersionResolver(primary, fallback, ResolverOptions{})
+
+		result, err := resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"})
+		require.NoError(t, err)
+		assert.Equal(t, "11bd71901bbe5b1630ceea73d27597364c9af683", result.CommitSHA)
+	})
 }
 
 func TestVersionResolver_listSemverTagsAll(t *testing.T) {
@@ -197,7 +1200,7 @@ func TestVersionResolver_listSemverTagsAll(t *testing.T) {
 			createTag("not-semver", "sha4"), // This should be filtered out
 		}, &gogithub.Response{NextPage: 0}, nil)
 
-	resolver := NewVersionResolver(mockRepo, nil)
+	resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{})
 
 	tags, err := resolver.listSemverTagsAll(context.Background(), "owner", "repo")
 
@@ -212,11 +1215,13 @@ func TestVersionResolver_listSemverTagsAll(t *testing.T) {
 
 func TestFindLatestTag(t *testing.T) {
 	tests := []struct {
-		name          string
-		version       string
-		tags          []string
-		expectedTag   string
-		expectedError bool
+		name            string
+		version         string
+		tags            []string
+		expectedTag     string
+		expectedError   bool
+		v0Strict        bool
+		allowPrerelease bool
 	}{
 		{
 			name:        "Find latest v4 tag",
@@ -272,6 +1277,138 @@ func TestFindLatestTag(t *testing.T) {
 			tags:          []string{"v1.0.0-alpha.1", "v1.0.0-beta.1", "v1.0.0-rc.1"},
 			expectedError: true,
 		},
+		{
+			name:        "v0 resolves across minors by default",
+			version:     "v0",
+			tags:        []string{"v0.2.5", "v0.3.0", "v0.3.4", "v1.0.0"},
+			expectedTag: "v0.3.4",
+		},
+		{
+			name:        "v0 resolves within the highest v0.y under v0-strict",
+			version:     "v0",
+			tags:        []string{"v0.2.5", "v0.3.0", "v0.3.4", "v0.4.0-rc.1", "v1.0.0"},
+			expectedTag: "v0.3.4",
+			v0Strict:    true,
+		},
+		{
+			name:        "v0.3 resolves within v0.3 by default",
+			version:     "v0.3",
+			tags:        []string{"v0.2.5", "v0.3.0", "v0.3.4", "v0.4.0"},
+			expectedTag: "v0.3.4",
+		},
+		{
+			name:        "v0.3 resolves within v0.3 under v0-strict",
+			version:     "v0.3",
+			tags:        []string{"v0.2.5", "v0.3.0", "v0.3.4", "v0.4.0"},
+			expectedTag: "v0.3.4",
+			v0Strict:    true,
+		},
+		{
+			name:        "v0.0 resolves across minors by default",
+			version:     "v0.0",
+			tags:        []string{"v0.0.1", "v0.0.9", "v0.1.0"},
+			expectedTag: "v0.1.0",
+		},
+		{
+			name:        "v0.0 stays within v0.0 under v0-strict",
+			version:     "v0.0",
+			tags:        []string{"v0.0.1", "v0.0.9", "v0.1.0"},
+			expectedTag: "v0.0.9",
+			v0Strict:    true,
+		},
+		{
+			name:        "v0-strict does not affect major versions above 0",
+			version:     "v4.0",
+			tags:        []string{"v4.0.1", "v4.1.0"},
+			expectedTag: "v4.1.0",
+			v0Strict:    true,
+		},
+		{
+			name:        "Latest build of an exact version",
+			version:     "v1.2.3",
+			tags:        []string{"v1.2.3+build.5", "v1.2.3+build.10", "v1.2.3", "v1.2.4-rc.1+build.20"},
+			expectedTag: "v1.2.3+build.10",
+		},
+		{
+			name:        "Build metadata doesn't outrank a newer version",
+			version:     "v1",
+			tags:        []string{"v1.2.3+build.99", "v1.2.4", "v1.2.4+build.1"},
+			expectedTag: "v1.2.4+build.1",
+		},
+		{
+			name:        "Calendar versions by year",
+			version:     "2024",
+			tags:        []string{"2023.12.31", "2024.1.15", "2024.08.15", "2024.8.9", "2024.10.1", "2025.01.01"},
+			expectedTag: "2024.10.1",
+		},
+		{
+			name:        "Calendar versions by month",
+			version:     "2024.08",
+			tags:        []string{"2024.07.31", "2024.08.02", "2024.08.15", "2024.8.9", "2024.09.01"},
+			expectedTag: "2024.08.15",
+		},
+		{
+			name:        "Exact calendar version",
+			version:     "v2024.08.15",
+			tags:        []string{"v2024.08.14", "v2024.08.15", "v2024.08.16"},
+			expectedTag: "v2024.08.15",
+		},
+		{
+			name:            "Only prereleases available",
+			version:         "v2",
+			tags:            []string{"v1.9.0", "v2.0.0-rc.1", "v2.0.0-rc.3", "v2.0.0-beta.4"},
+			expectedTag:     "v2.0.0-rc.3",
+			allowPrerelease: true,
+		},
+		{
+			name:            "Only prereleases available without allow-prerelease",
+			version:         "v2",
+			tags:            []string{"v1.9.0", "v2.0.0-rc.1", "v2.0.0-rc.3"},
+			expectedError:   true,
+			allowPrerelease: false,
+		},
+		{
+			name:            "Release preferred over newer prerelease",
+			version:         "v4",
+			tags:            []string{"v4.0.0", "v4.1.0-rc.1", "v4.1.0-beta.2", "v5.0.0-alpha"},
+			expectedTag:     "v4.0.0",
+			allowPrerelease: true,
+		},
+		{
+			name:            "Prerelease constraint prefers newer prerelease",
+			version:         "v4-rc",
+			tags:            []string{"v4.0.0", "v4.1.0-rc.1", "v4.1.0-beta.2", "v5.0.0-alpha"},
+			expectedTag:     "v4.1.0-rc.1",
+			allowPrerelease: true,
+		},
+		{
+			name:            "Release takes precedence over its prereleases",
+			version:         "v2.1",
+			tags:            []string{"v2.1.0-alpha.1", "v2.1.0-rc.2", "v2.1.0", "v2.1.0-beta.11"},
+			expectedTag:     "v2.1.0",
+			allowPrerelease: true,
+		},
+		{
+			name:            "Numeric prerelease identifiers compare numerically",
+			version:         "v1",
+			tags:            []string{"v1.0.0-beta.2", "v1.0.0-beta.11", "v1.0.0-beta.9"},
+			expectedTag:     "v1.0.0-beta.11",
+			allowPrerelease: true,
+		},
+		{
+			name:            "Exact release never resolves to a prerelease",
+			version:         "v1.0.0",
+			tags:            []string{"v1.0.0-alpha.1", "v1.0.0-beta.1", "v1.0.0-rc.1"},
+			expectedError:   true,
+			allowPrerelease: true,
+		},
+		{
+			name:            "Exact prerelease resolves to itself",
+			version:         "v1.0.0-beta.1",
+			tags:            []string{"v1.0.0-alpha.1", "v1.0.0-beta.1", "v1.0.0-rc.1", "v1.0.0"},
+			expectedTag:     "v1.0.0-beta.1",
+			allowPrerelease: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -293,7 +1430,7 @@ func TestFindLatestTag(t *testing.T) {
 			}
 
 			// Find latest tag
-			result, err := findLatestTag(*version, tags)
+			result, err := findLatestTag(*version, tags, ResolverOptions{V0Strict: tt.v0Strict, AllowPrerelease: tt.allowPrerelease})
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -384,6 +1521,18 @@ func TestActionDef_IsReusableWorkflow(t *testing.T) {
 	}
 }
 
+// Helper function to create a GitHub API error with the given status code
+func apiError(status int) error {
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://api.github.com/", nil)
+	return &gogithub.ErrorResponse{
+		Response: &http.Response{StatusCode: status, Request: req},
+	}
+}
+
+func notFoundError() error {
+	return apiError(http.StatusNotFound)
+}
+
 // Helper function to create a tag
 func createTag(name, sha string) *gogithub.RepositoryTag {
 	return &gogithub.RepositoryTag{
@@ -393,3 +1542,218 @@ func createTag(name, sha string) *gogithub.RepositoryTag {
 		},
 	}
 }
+
+func TestFindNewestTag(t *testing.T) {
+	tests := []struct {
+		name            string
+		tags            []string
+		expectedTag     string
+		expectedError   bool
+		allowPrerelease bool
+	}{
+		{
+			name:        "Newest release across majors",
+			tags:        []string{"v3.9.9", "v5.2.0", "v4.10.1", "v5.1.7"},
+			expectedTag: "v5.2.0",
+		},
+		{
+			name:        "Prereleases are excluded",
+			tags:        []string{"v4.10.1", "v5.2.0", "v6.0.0-rc.1", "v6.0.0-beta.2"},
+			expectedTag: "v5.2.0",
+		},
+		{
+			name:          "Only prereleases",
+			tags:          []string{"v6.0.0-rc.1", "v6.0.0-beta.2"},
+			expectedError: true,
+		},
+		{
+			name:            "Only prereleases with allow-prerelease",
+			tags:            []string{"v6.0.0-rc.1", "v6.0.0-beta.2"},
+			expectedTag:     "v6.0.0-rc.1",
+			allowPrerelease: true,
+		},
+		{
+			name:            "Release preferred over newer prerelease with allow-prerelease",
+			tags:            []string{"v5.2.0", "v6.0.0-rc.1"},
+			expectedTag:     "v5.2.0",
+			allowPrerelease: true,
+		},
+		{
+			name:          "No tags",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tags []semverTag
+			for _, name := range tt.tags {
+				v, err := semver.NewVersion(name)
+				require.NoError(t, err)
+				tags = append(tags, semverTag{gogithubTag: gogithub.RepositoryTag{Name: &name}, version: *v})
+			}
+
+			result, err := findNewestTag(tags, ResolverOptions{AllowPrerelease: tt.allowPrerelease})
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedTag, *result.gogithubTag.Name)
+		})
+	}
+}
+
+func TestCompareTags(t *testing.T) {
+	// Ascending precedence per https://semver.org/#spec-item-11, plus the repo's name tie-break.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"v1.0.0",
+		"v1.0.0+build.1",
+		"v1.0.0+build.2",
+		"v1.0.0+build.11",
+		"v1.0.0+build.11.1",
+		"v1.0.0+exp.sha.5114f85",
+		"1.0.1-0",
+		"1.0.1-alpha",
+		"1.0.1",
+		"1.1.0-rc.1",
+		"2.0.0-0.3.7",
+		"2.0.0-x.7.z.92",
+		"2.0.0",
+	}
+
+	tags := make([]semverTag, 0, len(ordered))
+	for _, name := range ordered {
+		v, err := semver.NewVersion(name)
+		require.NoError(t, err, name)
+		tags = append(tags, semverTag{gogithubTag: gogithub.RepositoryTag{Name: &name}, version: *v})
+	}
+
+	for i := range tags {
+		for j := range tags {
+			got := compareTags(tags[i], tags[j])
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			assert.Equal(t, want, got, "compareTags(%s, %s)", ordered[i], ordered[j])
+		}
+	}
+}
+
+// noCache is a Cache that never stores anything, so every resolution misses.
+type noCache struct{}
+
+func (noCache) Get(CacheKey) (ResolvedVersion, bool) { return ResolvedVersion{}, false }
+func (noCache) Set(CacheKey, ResolvedVersion)        {}
+
+func TestVersionResolver_AssertResolveOncePerKey(t *testing.T) {
+	def := ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "main"}
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+
+	t.Run("Cache hits pass", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return(sha, &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{AssertResolveOncePerKey: true})
+		for range 3 {
+			_, err := resolver.ResolveVersion(context.Background(), def)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Cache misses fail", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return(sha, &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: noCache{}, AssertResolveOncePerKey: true})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		_, err = resolver.ResolveVersion(context.Background(), def)
+		require.ErrorIs(t, err, DuplicateResolutionError)
+		assert.Contains(t, err.Error(), "actions/checkout@main")
+
+		// Other keys are unaffected.
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "develop", "").
+			Return(sha, &gogithub.Response{}, nil).Times(1)
+		_, err = resolver.ResolveVersion(context.Background(), ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "develop"})
+		require.NoError(t, err)
+	})
+
+	t.Run("Cache misses pass when disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return(sha, &gogithub.Response{}, nil).Times(2)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: noCache{}})
+		for range 2 {
+			_, err := resolver.ResolveVersion(context.Background(), def)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Enabled by the environment variable", func(t *testing.T) {
+		t.Setenv(ResolveOncePerKeyEnv, "1")
+		ctrl := gomock.NewController(t)
+		mockRepo := NewMockRepositoryService(ctrl)
+		mockRepo.EXPECT().GetCommitSHA1(gomock.Any(), "actions", "checkout", "main", "").
+			Return(sha, &gogithub.Response{}, nil).Times(1)
+
+		resolver := NewVersionResolver(mockRepo, nil, ResolverOptions{Cache: noCache{}})
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+		_, err = resolver.ResolveVersion(context.Background(), def)
+		require.ErrorIs(t, err, DuplicateResolutionError)
+	})
+}
+
+func TestVersionResolver_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	primary := NewMockRepositoryService(ctrl)
+	fallback := NewMockRepositoryService(ctrl)
+	primary.EXPECT().ListTags(gomock.Any(), "org", "action", gomock.Any()).
+		Return(nil, nil, notFoundError()).Times(1)
+	fallback.EXPECT().ListTags(gomock.Any(), "org", "action", gomock.Any()).
+		Return([]*gogithub.RepositoryTag{createTag("v4.1.1", "sha1")}, &gogithub.Response{NextPage: 0}, nil).Times(1)
+	primary.EXPECT().GetCommitSHA1(gomock.Any(), "org", "action", "main", "").
+		Return("sha2", &gogithub.Response{}, nil).Times(1)
+
+	resolver := NewVersionResolver(primary, fallback, ResolverOptions{})
+	defs := []ActionDef{
+		{Owner: "org", Repo: "action", RefOrSHA: "v4"},
+		{Owner: "org", Repo: "action", RefOrSHA: "main"},
+	}
+	for _, def := range defs {
+		_, err := resolver.ResolveVersion(context.Background(), def)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, ResolverStats{Resolutions: 2, APICalls: 3, ListTagsCalls: 2, GetCommitSHA1Calls: 1, Fallbacks: 1}, resolver.Stats())
+
+	// Cache hits are counted exactly under concurrent resolutions.
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := resolver.ResolveVersion(context.Background(), defs[i%2])
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, ResolverStats{Resolutions: 102, CacheHits: 100, APICalls: 3, ListTagsCalls: 2, GetCommitSHA1Calls: 1, Fallbacks: 1}, resolver.Stats())
+}
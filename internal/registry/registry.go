@@ -0,0 +1,246 @@
+// Package registry resolves container image tags to manifest digests through the OCI distribution (Docker registry
+// v2) API, to pin `docker://` action references.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	dockerHubDomain   = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// manifestMediaTypes are accepted for manifests, so that multi-platform images resolve to their index digest, which
+// is what `docker pull` resolves the tag to.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference, e.g. ghcr.io/org/image:1.2.3.
+type Reference struct {
+	// Name as written, without tag and digest, e.g. ghcr.io/org/image or alpine.
+	Name string
+	// Registry host, e.g. ghcr.io or docker.io.
+	Registry string
+	// Repository within the registry, e.g. org/image or library/alpine.
+	Repository string
+	// Tag, "latest" when the reference has none.
+	Tag string
+	// Digest, e.g. sha256:..., when the reference is already pinned.
+	Digest string
+}
+
+// ParseReference parses an image reference as written after `docker://`.
+func ParseReference(s string) (Reference, error) {
+	if s == "" || strings.ContainsAny(s, " \t") {
+		return Reference{}, errors.Newf("invalid image reference %q", s)
+	}
+	name, digest, _ := strings.Cut(s, "@")
+	ref := Reference{Digest: digest, Tag: "latest"}
+	// A tag follows the last colon of the last path component; earlier colons belong to a registry port.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if name == "" || ref.Tag == "" {
+		return Reference{}, errors.Newf("invalid image reference %q", s)
+	}
+	ref.Name = name
+
+	// The first component is a registry host only if it looks like one; otherwise the image is on Docker Hub.
+	first, rest, ok := strings.Cut(name, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, ref.Repository = first, rest
+	} else {
+		ref.Registry, ref.Repository = dockerHubDomain, name
+	}
+	if ref.Registry == dockerHubDomain && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return ref, nil
+}
+
+// Credentials authenticate to a registry. Password may be an access token.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client resolves image tags to digests. It authenticates anonymously unless credentials are configured, and
+// follows the bearer token challenges registries such as Docker Hub and GHCR answer with.
+type Client struct {
+	httpClient  *http.Client
+	credentials Credentials
+}
+
+// NewClient creates a registry client. httpClient defaults to http.DefaultClient. Empty credentials authenticate
+// anonymously, which works for public images.
+func NewClient(httpClient *http.Client, credentials Credentials) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{httpClient: httpClient, credentials: credentials}
+}
+
+// Digest returns the manifest digest the tag of ref currently points to.
+func (c *Client) Digest(ctx context.Context, ref Reference) (string, error) {
+	host := ref.Registry
+	if host == dockerHubDomain {
+		host = dockerHubRegistry
+	}
+	manifestURL := "https://" + host + "/v2/" + ref.Repository + "/manifests/" + url.PathEscape(ref.Tag)
+
+	// HEAD is enough when the registry returns the digest header, and isn't counted against Docker Hub pull limits.
+	resp, err := c.do(ctx, http.MethodHead, manifestURL, ref)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Some registries omit the header; the digest is then the SHA-256 of the manifest as served.
+	resp, err = c.do(ctx, http.MethodGet, manifestURL, ref)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", errors.Wrapf(err, "failed to read manifest of %s", ref.Name)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// do sends a manifest request, answering the registry's authentication challenge once if needed.
+func (c *Client) do(ctx context.Context, method, manifestURL string, ref Reference) (*http.Response, error) {
+	resp, err := c.send(ctx, method, manifestURL, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+		authorization, err := c.authorize(ctx, challenge)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to authenticate to %s", ref.Registry)
+		}
+		if resp, err = c.send(ctx, method, manifestURL, authorization); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, errors.Newf("failed to get manifest of %s:%s: %s", ref.Name, ref.Tag, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, method, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := c.httpClient.Do(req)
+	return resp, errors.WithStack(err)
+}
+
+// challengeParamPattern matches the key="value" parameters of a WWW-Authenticate header.
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize returns the Authorization header value answering a WWW-Authenticate challenge.
+func (c *Client) authorize(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.credentials.Username == "" && c.credentials.Password == "" {
+			return "", errors.New("registry requires credentials")
+		}
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, params)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", errors.Newf("unsupported authentication challenge %q", challenge)
+	}
+}
+
+// fetchToken requests a bearer token from the realm of a challenge, with the configured credentials if any.
+func (c *Client) fetchToken(ctx context.Context, challengeParams string) (string, error) {
+	params := make(map[string]string)
+	for _, m := range challengeParamPattern.FindAllStringSubmatch(challengeParams, -1) {
+		params[m[1]] = m[2]
+	}
+	realm := params["realm"]
+	if realm == "" {
+		return "", errors.New("bearer challenge has no realm")
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid token realm")
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if v := params[key]; v != "" {
+			query.Set(key, v)
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if c.credentials.Username != "" || c.credentials.Password != "" {
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Newf("token request failed: %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", errors.Wrap(err, "invalid token response")
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("token response has no token")
+}
@@ -0,0 +1,143 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Reference
+	}{
+		{
+			input:    "ghcr.io/org/image:1.2.3",
+			expected: Reference{Name: "ghcr.io/org/image", Registry: "ghcr.io", Repository: "org/image", Tag: "1.2.3"},
+		},
+		{
+			input:    "alpine:3.19",
+			expected: Reference{Name: "alpine", Registry: "docker.io", Repository: "library/alpine", Tag: "3.19"},
+		},
+		{
+			input:    "org/image",
+			expected: Reference{Name: "org/image", Registry: "docker.io", Repository: "org/image", Tag: "latest"},
+		},
+		{
+			input:    "localhost:5000/image:dev",
+			expected: Reference{Name: "localhost:5000/image", Registry: "localhost:5000", Repository: "image", Tag: "dev"},
+		},
+		{
+			input: "ghcr.io/org/image:1.2.3@sha256:abc",
+			expected: Reference{
+				Name: "ghcr.io/org/image", Registry: "ghcr.io", Repository: "org/image", Tag: "1.2.3", Digest: "sha256:abc",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseReference(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	for _, s := range []string{"", "image:", ":tag", "has space"} {
+		_, err := ParseReference(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestClient_Digest(t *testing.T) {
+	const digest = "sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"
+
+	t.Run("Bearer token challenge", func(t *testing.T) {
+		var server *httptest.Server
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/token":
+				assert.Equal(t, "registry", r.URL.Query().Get("service"))
+				assert.Equal(t, "repository:org/image:pull", r.URL.Query().Get("scope"))
+				user, pass, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, "user", user)
+				assert.Equal(t, "secret", pass)
+				_, _ = w.Write([]byte(`{"token":"t0ken"}`))
+			case "/v2/org/image/manifests/1.2.3":
+				assert.Equal(t, http.MethodHead, r.Method)
+				assert.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+				if r.Header.Get("Authorization") != "Bearer t0ken" {
+					w.Header().Set("WWW-Authenticate",
+						`Bearer realm="`+server.URL+`/token",service="registry",scope="repository:org/image:pull"`)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("Docker-Content-Digest", digest)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		c := NewClient(server.Client(), Credentials{Username: "user", Password: "secret"})
+		got, err := c.Digest(context.Background(), testReference(t, server, "org/image:1.2.3"))
+		require.NoError(t, err)
+		assert.Equal(t, digest, got)
+	})
+
+	t.Run("Digest computed from the manifest", func(t *testing.T) {
+		manifest := []byte(`{"schemaVersion":2}`)
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(manifest)
+			}
+		}))
+		defer server.Close()
+
+		c := NewClient(server.Client(), Credentials{})
+		got, err := c.Digest(context.Background(), testReference(t, server, "image:dev"))
+		require.NoError(t, err)
+		sum := sha256.Sum256(manifest)
+		assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), got)
+	})
+
+	t.Run("Missing tag", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+
+		c := NewClient(server.Client(), Credentials{})
+		_, err := c.Digest(context.Background(), testReference(t, server, "image:missing"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "404")
+	})
+
+	t.Run("Basic challenge without credentials", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		c := NewClient(server.Client(), Credentials{})
+		_, err := c.Digest(context.Background(), testReference(t, server, "image:dev"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "requires credentials")
+	})
+}
+
+// testReference parses an image reference on the registry served by server.
+func testReference(t *testing.T, server *httptest.Server, image string) Reference {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	ref, err := ParseReference(u.Host + "/" + image)
+	require.NoError(t, err)
+	return ref
+}
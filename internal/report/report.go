@@ -0,0 +1,39 @@
+// Package report serializes the changes made by a rewrite for machines, e.g. PR bots and audit logs.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+type jsonReport struct {
+	Changed   bool                  `json:"changed"`
+	FileCount int                   `json:"file_count"`
+	Files     []rewrite.FileChanges `json:"files"`
+}
+
+// WriteJSON writes res as an indented JSON object listing the changed lines of each changed file.
+func WriteJSON(w io.Writer, res rewrite.RewriteResult) error {
+	files := res.Files
+	if files == nil {
+		files = []rewrite.FileChanges{} // Always an array, never null
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(jsonReport{Changed: res.Changed, FileCount: res.FileCount, Files: files})
+	return errors.WithStack(err)
+}
+
+// WriteFile writes a report with write to path atomically: the file is replaced only once the whole report is written.
+func WriteFile(path string, write func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+	return errors.Wrapf(rewrite.WriteFileAtomic(path, buf.String()), "failed to write report to %s", path)
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+func TestWriteJSON(t *testing.T) {
+	t.Run("Changes", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteJSON(&buf, rewrite.RewriteResult{
+			Changed:   true,
+			FileCount: 1,
+			Files: []rewrite.FileChanges{{
+				Path: ".github/workflows/ci.yml",
+				Changes: []rewrite.Change{
+					{Line: 7, Owner: "actions", Repo: "checkout", FromRef: "v4", ToSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", ResolvedComment: "v4.2.2"},
+					{Line: 9, Owner: "oasdiff", Repo: "oasdiff-action", Path: "diff", FromRef: "v0", ToSHA: "1c611ffb1253a72924624aa4fb662e302b3565d3", ResolvedComment: "v0.0.21"},
+				},
+			}},
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+  "changed": true,
+  "file_count": 1,
+  "files": [
+    {
+      "file": ".github/workflows/ci.yml",
+      "changes": [
+        {"line_number": 7, "owner": "actions", "repo": "checkout", "from_ref": "v4", "to_sha": "11bd71901bbe5b1630ceea73d27597364c9af683", "resolved_comment": "v4.2.2"},
+        {"line_number": 9, "owner": "oasdiff", "repo": "oasdiff-action", "path": "diff", "from_ref": "v0", "to_sha": "1c611ffb1253a72924624aa4fb662e302b3565d3", "resolved_comment": "v0.0.21"}
+      ]
+    }
+  ]
+}`, buf.String())
+	})
+
+	t.Run("No changes", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteJSON(&buf, rewrite.RewriteResult{}))
+		assert.JSONEq(t, `{"changed": false, "file_count": 0, "files": []}`, buf.String())
+	})
+}
+
+func TestWriteFile(t *testing.T) {
+	res := rewrite.RewriteResult{
+		Changed:   true,
+		FileCount: 1,
+		Files: []rewrite.FileChanges{{
+			Path:    ".github/workflows/ci.yml",
+			Changes: []rewrite.Change{{Line: 7, Owner: "actions", Repo: "checkout", FromRef: "v4", ToSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", ResolvedComment: "v4.2.2"}},
+		}},
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "report.json")
+		require.NoError(t, os.WriteFile(path, []byte("stale"), 0o600))
+
+		require.NoError(t, WriteFile(path, func(w io.Writer) error { return WriteJSON(w, res) }))
+
+		var expected bytes.Buffer
+		require.NoError(t, WriteJSON(&expected, res))
+		got, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), string(got))
+	})
+
+	t.Run("Failed report leaves the file untouched", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "report.json")
+		require.NoError(t, os.WriteFile(path, []byte("previous"), 0o600))
+
+		err := WriteFile(path, func(w io.Writer) error {
+			_, _ = io.WriteString(w, "partial")
+			return assert.AnError
+		})
+		require.ErrorIs(t, err, assert.AnError)
+
+		got, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "previous", string(got))
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "no temporary file is left behind")
+	})
+
+	t.Run("Missing directory", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing", "report.json")
+		err := WriteFile(path, func(w io.Writer) error { return WriteJSON(w, res) })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), path)
+	})
+}
@@ -0,0 +1,38 @@
+package rewrite
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// defaultDiffContext is the number of unchanged lines shown around each change, as with `diff -u` and `git diff`.
+const defaultDiffContext = 3
+
+// unifiedDiff returns the unified diff between the original and modified content of filePath, with git-style
+// a/ and b/ file headers so the output can be applied with `git apply`. Each hunk shows contextLines unchanged lines
+// around the changes.
+func unifiedDiff(filePath, original, modified string, contextLines int) (string, error) {
+	name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(filePath)), "/")
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(original),
+		B:        splitLines(modified),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  contextLines,
+	})
+	return diff, errors.WithStack(err)
+}
+
+// splitLines splits s into lines keeping their line endings. Unlike difflib.SplitLines, it adds no empty line at the
+// end, and marks a missing final newline the way diff does.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	return lines
+}
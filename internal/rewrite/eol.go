@@ -0,0 +1,46 @@
+package rewrite
+
+import (
+	"context"
+	"strings"
+)
+
+// preserveLineEndings makes f, which splits and joins lines on "\n", keep the line endings of content:
+//   - When CRLF is the dominant line ending, f runs on the content with LF endings and its result is converted back
+//     to CRLF. Once such a file changes, lone LF endings in it become CRLF too.
+//   - The result ends with a newline exactly when content does, so a fix never adds or drops the final newline.
+func preserveLineEndings(f fixFunc) fixFunc {
+	return func(ctx context.Context, path string, content string) (string, bool, []Change, error) {
+		crlf := isCRLF(content)
+		input := content
+		if crlf {
+			input = strings.ReplaceAll(content, "\r\n", "\n")
+		}
+		modified, changed, changes, err := f(ctx, path, input)
+		if err != nil || !changed {
+			return content, changed, changes, err
+		}
+		modified = matchFinalNewline(input, modified)
+		if crlf {
+			modified = strings.ReplaceAll(modified, "\n", "\r\n")
+		}
+		return modified, changed, changes, nil
+	}
+}
+
+// isCRLF reports whether most lines of content end with CRLF rather than a lone LF.
+func isCRLF(content string) bool {
+	crlf := strings.Count(content, "\r\n")
+	return crlf > 0 && crlf*2 >= strings.Count(content, "\n")
+}
+
+// matchFinalNewline adds or removes the final newline of modified to match original.
+func matchFinalNewline(original, modified string) string {
+	switch {
+	case strings.HasSuffix(original, "\n") && !strings.HasSuffix(modified, "\n") && modified != "":
+		return modified + "\n"
+	case !strings.HasSuffix(original, "\n"):
+		return strings.TrimRight(modified, "\n")
+	}
+	return modified
+}
@@ -0,0 +1,55 @@
+package rewrite
+
+import (
+	"bytes"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// filterChangedSince keeps the discovered workflowPaths that changed since the git ref since, in the working tree or
+// in commits after it. When the current directory isn't in a git work tree (or git isn't installed), every path is
+// kept with a warning, so a full scan still happens; an unknown ref fails.
+func filterChangedSince(workflowPaths []string, since string) ([]string, error) {
+	if err := exec.Command("git", "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		slog.Warn("not a git repository; ignoring --since and processing every workflow file", "since", since, "reason", err)
+		return workflowPaths, nil
+	}
+	changed, err := changedFiles(since)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range workflowPaths {
+		if changed[filepath.Clean(path)] {
+			paths = append(paths, path)
+		}
+	}
+	slog.Debug("filtered workflow files changed in git", "since", since, "changed", len(changed), "count", len(paths))
+	return paths, nil
+}
+
+// changedFiles returns the files under the current directory, relative to it, that were added, modified or renamed
+// since the git ref since. Deleted files have nothing left to process and are omitted.
+func changedFiles(since string) (map[string]bool, error) {
+	if strings.HasPrefix(since, "-") {
+		return nil, errors.Newf("invalid git ref %q", since)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "diff", "--name-only", "--relative", "--diff-filter=d", "-z", since, "--")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list files changed since %s: %s", since, strings.TrimSpace(stderr.String()))
+	}
+	changed := make(map[string]bool)
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			changed[filepath.Clean(filepath.FromSlash(name))] = true
+		}
+	}
+	return changed, nil
+}
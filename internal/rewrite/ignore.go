@@ -0,0 +1,150 @@
+package rewrite
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// IgnoreFileName is the name of the gitignore-style files excluding paths from workflow file discovery. Each file
+// applies to its directory and the directories below it.
+const IgnoreFileName = ".gha-fix-ignore"
+
+// ignoreRule is a pattern of an ignore file, following gitignore semantics: `*`, `?` and `[...]` don't match `/`,
+// `**` matches any number of directories, a trailing `/` only matches directories, a pattern with a `/` other than a
+// trailing one is relative to the directory of the ignore file, and `!` re-includes what a previous pattern excluded.
+type ignoreRule struct {
+	base    string // directory of the ignore file, slash-separated and relative to the search root; "" for the root
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules are the rules of the ignore files found so far, from the root down, in file order.
+type ignoreRules []ignoreRule
+
+// load appends the rules of the ignore file of dir, if any. rel is dir relative to the search root.
+func (r *ignoreRules) load(dir, rel string) error {
+	b, err := os.ReadFile(filepath.Join(dir, IgnoreFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	rules, err := parseIgnoreFile(rel, string(b))
+	if err != nil {
+		return errors.Wrapf(err, "invalid ignore file: %s", filepath.Join(dir, IgnoreFileName))
+	}
+	*r = append(*r, rules...)
+	return nil
+}
+
+// ignored reports whether the path rel, relative to the search root, is excluded. As with gitignore, the last
+// matching rule wins, so rules of nested ignore files override those of their parents.
+func (r ignoreRules) ignored(rel string, isDir bool) bool {
+	rel = filepath.ToSlash(rel)
+	ignored := false
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if rule.base != "" {
+			var ok bool
+			if name, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if rule.pattern.MatchString(name) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnoreFile parses the content of an ignore file in the directory base, relative to the search root. Blank
+// lines and lines starting with # are skipped; a leading \ escapes a # or ! starting a pattern.
+func parseIgnoreFile(base, content string) ([]ignoreRule, error) {
+	base = filepath.ToSlash(base)
+	if base == "." {
+		base = ""
+	}
+	var rules []ignoreRule
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if line[0] == '!' {
+			rule.negate, line = true, line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		if trimmed := strings.TrimSuffix(line, "/"); trimmed != line {
+			rule.dirOnly, line = true, trimmed
+		}
+		if line == "" {
+			continue
+		}
+		pattern, err := ignorePatternRegexp(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+1)
+		}
+		rule.pattern = pattern
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ignorePatternRegexp translates a gitignore pattern, without its `!` and trailing `/`, to a regular expression
+// matching slash-separated paths relative to the directory of the ignore file.
+func ignorePatternRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		b.WriteString("(?:.*/)?") // No slash: the name matches at any level
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, errors.Newf("unterminated character class in %q", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern %q", pattern)
+	}
+	return re, nil
+}
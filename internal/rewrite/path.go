@@ -0,0 +1,61 @@
+package rewrite
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+)
+
+// PathStyle controls how file paths are reported in results, findings and logs.
+type PathStyle string
+
+const (
+	// PathStyleAsIs reports paths as given, or as discovered relative to the current directory.
+	PathStyleAsIs PathStyle = ""
+	// PathStyleRelative reports paths relative to the current directory.
+	PathStyleRelative PathStyle = "relative"
+	// PathStyleAbsolute reports absolute paths.
+	PathStyleAbsolute PathStyle = "absolute"
+)
+
+// ParsePathStyle parses a --report-path-style value. An empty string means PathStyleAsIs.
+func ParsePathStyle(s string) (PathStyle, error) {
+	switch p := PathStyle(s); p {
+	case PathStyleAsIs, PathStyleRelative, PathStyleAbsolute:
+		return p, nil
+	default:
+		return "", errors.Newf("invalid path style %q: must be relative or absolute", s)
+	}
+}
+
+// normalizePaths rewrites filePaths in the given style. StdioPath is left as is. Paths that can't be made relative
+// to the current directory (e.g. on another volume) are reported absolute.
+func normalizePaths(filePaths []string, style PathStyle) ([]string, error) {
+	if style == PathStyleAsIs {
+		return filePaths, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	normalized := make([]string, len(filePaths))
+	for i, filePath := range filePaths {
+		if filePath == StdioPath {
+			normalized[i] = filePath
+			continue
+		}
+		abs := filePath
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(cwd, filePath)
+		}
+		normalized[i] = abs
+		if style == PathStyleRelative {
+			if rel, err := filepath.Rel(cwd, abs); err == nil {
+				normalized[i] = rel
+			}
+		}
+	}
+	return normalized, nil
+}
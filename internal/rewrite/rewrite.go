@@ -2,10 +2,14 @@ package rewrite
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/cockroachdb/errors"
 )
@@ -13,41 +17,235 @@ import (
 type RewriteResult struct {
 	Changed   bool
 	FileCount int
+	// Files lists the changed files in file order with the lines changed in each. Only RewriteChanges records lines.
+	Files []FileChanges
+	// TotalCount is the number of files processed, whether changed or not, and FailedCount the number of them that
+	// failed; the returned error joins their errors. Both are zero for stdin input.
+	TotalCount  int
+	FailedCount int
 }
 
 type FixFunc func(ctx context.Context, content string) (string, bool, error)
 
-func Rewrite(ctx context.Context, filePaths []string, ignoreDirs []string, f FixFunc) (RewriteResult, error) {
-	if len(filePaths) == 0 {
-		slog.Debug("searching for workflow files to process")
-		workflowPaths, err := findWorkflowFiles(".", ignoreDirs)
-		if err != nil {
-			return RewriteResult{}, err
+// ChangeFunc is a FixFunc that also describes each line it changed. Change.Line is filled in by the function.
+type ChangeFunc func(ctx context.Context, content string) (string, []Change, error)
+
+// Change is a line rewritten by a pin-like fix: an action reference replaced by a commit SHA.
+type Change struct {
+	Line            int    `json:"line_number"` // 1-based
+	Owner           string `json:"owner"`
+	Repo            string `json:"repo"`
+	Path            string `json:"path,omitempty"` // Path of the action within the repository, if any
+	FromRef         string `json:"from_ref"`
+	ToSHA           string `json:"to_sha"`
+	ToRef           string `json:"to_ref,omitempty"` // Tag written instead of ToSHA when pinning to tags
+	ResolvedComment string `json:"resolved_comment"`
+	Branch          bool   `json:"branch,omitempty"` // ToSHA is the current head of a branch, a moving target
+	// Text is the rewritten line, without its line ending, e.g. for reports suggesting the change. Filled in by
+	// RewritePathChanges.
+	Text string `json:"-"`
+}
+
+// FileChanges is the list of changes made to one file.
+type FileChanges struct {
+	Path    string   `json:"file"`
+	Changes []Change `json:"changes"`
+}
+
+// fixFunc is the common form of FixFunc, ChangeFunc and PathChangeFunc used by the file processing.
+type fixFunc func(ctx context.Context, path string, content string) (string, bool, []Change, error)
+
+// RewriteOptions controls how Rewrite discovers and updates files.
+type RewriteOptions struct {
+	// Directory names to skip when searching for workflow files.
+	IgnoreDirs []string
+	// Only discover workflows under .github/workflows/ and action.yml/action.yaml files instead of any YAML file.
+	ActionFilesOnly bool
+	// Skip directories nested deeper than this below the search root when discovering files. Zero means unlimited.
+	MaxDepth int
+	// Only process the discovered files that changed in git since this ref (e.g. origin/main), per
+	// `git diff --name-only`. Files given explicitly are processed regardless. Outside a git work tree, every
+	// discovered file is processed with a warning.
+	Since string
+	// Number of files processed in parallel. Zero or negative uses GOMAXPROCS. The FixFunc must be safe for
+	// concurrent use when this is not 1.
+	Concurrency int
+	// DryRun applies the fixes in memory only: files that would change are reported and counted but never written.
+	DryRun bool
+	// Diff, when set, receives a unified diff of each file that would change, in file order, and files are never
+	// written as with DryRun.
+	Diff io.Writer
+	// Number of unchanged lines shown around each change in Diff. Zero uses the default (3); negative shows none.
+	DiffContext int
+	// OnlyChangedLines drops the change records of lines that a ChangeFunc left as they were, so that
+	// RewriteResult.Files only lists lines actually changed. Files left without a change record are not counted.
+	OnlyChangedLines bool
+	// PathStyle normalizes the paths of given and discovered files, and so every path reported for them.
+	PathStyle PathStyle
+	// Stdin and Stdout are used when the file path is StdioPath. They default to os.Stdin and os.Stdout.
+	Stdin  io.Reader
+	Stdout io.Writer
+	// Progress, when set, is called with the number of files processed so far and the total number of files, each
+	// time a file is done. Calls are serialized but happen in completion order. It isn't called for stdin input.
+	Progress func(done, total int)
+}
+
+func (o RewriteOptions) dryRun() bool {
+	return o.DryRun || o.Diff != nil
+}
+
+func (o RewriteOptions) diffContext() int {
+	switch {
+	case o.DiffContext == 0:
+		return defaultDiffContext
+	case o.DiffContext < 0:
+		return 0
+	default:
+		return o.DiffContext
+	}
+}
+
+// StdioPath is the file argument that makes Rewrite read from stdin and write the result to stdout.
+const StdioPath = "-"
+
+// Finding is a location that a fix would change, reported by Check.
+type Finding struct {
+	Path    string
+	Line    int // 1-based
+	Message string
+}
+
+// CheckFunc inspects content and returns the findings in it. Finding.Path is filled in by Check.
+type CheckFunc func(ctx context.Context, content string) ([]Finding, error)
+
+func Rewrite(ctx context.Context, filePaths []string, opts RewriteOptions, f FixFunc) (RewriteResult, error) {
+	return rewrite(ctx, filePaths, opts, func(ctx context.Context, _ string, content string) (string, bool, []Change, error) {
+		modified, changed, err := f(ctx, content)
+		return modified, changed, nil, err
+	})
+}
+
+// PathFixFunc is a FixFunc that is also given the path of the file, see PathChangeFunc.
+type PathFixFunc func(ctx context.Context, path string, content string) (string, bool, error)
+
+// RewritePath works like Rewrite, passing the path of each file to f.
+func RewritePath(ctx context.Context, filePaths []string, opts RewriteOptions, f PathFixFunc) (RewriteResult, error) {
+	return rewrite(ctx, filePaths, opts, func(ctx context.Context, path string, content string) (string, bool, []Change, error) {
+		modified, changed, err := f(ctx, path, content)
+		return modified, changed, nil, err
+	})
+}
+
+// RewriteChanges works like Rewrite, additionally recording the changed lines of each file in RewriteResult.Files.
+func RewriteChanges(ctx context.Context, filePaths []string, opts RewriteOptions, f ChangeFunc) (RewriteResult, error) {
+	return RewritePathChanges(ctx, filePaths, opts, func(ctx context.Context, _ string, content string) (string, []Change, error) {
+		return f(ctx, content)
+	})
+}
+
+// PathChangeFunc is a ChangeFunc that is also given the path of the file, as found on disk (StdioPath for stdin), e.g.
+// to apply settings depending on the location of the file.
+type PathChangeFunc func(ctx context.Context, path string, content string) (string, []Change, error)
+
+// RewritePathChanges works like RewriteChanges, passing the path of each file to f.
+func RewritePathChanges(ctx context.Context, filePaths []string, opts RewriteOptions, f PathChangeFunc) (RewriteResult, error) {
+	return rewrite(ctx, filePaths, opts, func(ctx context.Context, path string, content string) (string, bool, []Change, error) {
+		modified, changes, err := f(ctx, path, content)
+		if len(changes) > 0 {
+			lines := strings.Split(modified, "\n")
+			for i, c := range changes {
+				if c.Line >= 1 && c.Line <= len(lines) {
+					changes[i].Text = strings.TrimSuffix(lines[c.Line-1], "\r")
+				}
+			}
 		}
-		slog.Debug("found workflow files", "count", len(workflowPaths))
-		if len(workflowPaths) == 0 {
-			return RewriteResult{}, nil
+		if opts.OnlyChangedLines {
+			changes = ChangedLines(content, modified, changes)
 		}
+		return modified, len(changes) > 0, changes, err
+	})
+}
 
-		filePaths = workflowPaths
+// ChangedLines returns the changes made to a line that differs between original and modified, dropping those
+// recorded for lines left as they were. changes is modified in place.
+func ChangedLines(original, modified string, changes []Change) []Change {
+	before, after := strings.Split(original, "\n"), strings.Split(modified, "\n")
+	return slices.DeleteFunc(changes, func(c Change) bool {
+		return c.Line >= 1 && c.Line <= min(len(before), len(after)) &&
+			strings.TrimSuffix(before[c.Line-1], "\r") == strings.TrimSuffix(after[c.Line-1], "\r")
+	})
+}
+
+func rewrite(ctx context.Context, filePaths []string, opts RewriteOptions, f fixFunc) (RewriteResult, error) {
+	filePaths, err := resolveFilePaths(filePaths, opts)
+	if err != nil {
+		return RewriteResult{}, err
+	}
+	f = preserveLineEndings(f)
+	if isStdio(filePaths) {
+		return processStdio(ctx, opts, f)
 	}
 
-	res := RewriteResult{}
-	var errs []error
+	results := make([]fileResult, len(filePaths))
 
-	for _, filePath := range filePaths {
-		slog.Debug("processing file", "path", filePath)
-		changed, err := processFile(ctx, filePath, f)
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
+	for range min(concurrency, len(filePaths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				slog.Debug("processing file", "path", filePaths[i])
+				results[i] = processFile(ctx, filePaths[i], opts, f)
+				if opts.Progress != nil {
+					progressMu.Lock()
+					done++
+					opts.Progress(done, len(filePaths))
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range filePaths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	// Report in file order regardless of completion order.
+	res := RewriteResult{TotalCount: len(filePaths)}
+	var errs []error
+	for i, filePath := range filePaths {
+		changed, err := results[i].changed, results[i].err
 		if err != nil {
 			// Collect the error but continue processing remaining files.
 			errs = append(errs, errors.Wrapf(err, "failed to process file: %s", filePath))
+			res.FailedCount++
 			continue
 		}
 
 		if changed {
-			slog.Info("file updated", "path", filePath)
+			if opts.Diff != nil {
+				if _, err := io.WriteString(opts.Diff, results[i].diff); err != nil {
+					return res, errors.Wrap(err, "failed to write diff")
+				}
+			}
+			if opts.dryRun() {
+				slog.Info("file would be updated (dry-run)", "path", filePath)
+			} else {
+				slog.Info("file updated", "path", filePath)
+			}
 			res.Changed = true
 			res.FileCount++
+			if results[i].changes != nil {
+				res.Files = append(res.Files, FileChanges{Path: filePath, Changes: results[i].changes})
+			}
 		}
 	}
 
@@ -58,38 +256,228 @@ func Rewrite(ctx context.Context, filePaths []string, ignoreDirs []string, f Fix
 	return res, nil
 }
 
-func processFile(ctx context.Context, filePath string, f FixFunc) (bool, error) {
-	content, err := os.ReadFile(filePath)
+// Check runs f over the files without modifying them and returns all findings in file order.
+// File discovery works the same as Rewrite.
+func Check(ctx context.Context, filePaths []string, opts RewriteOptions, f CheckFunc) ([]Finding, error) {
+	return CheckPath(ctx, filePaths, opts, func(ctx context.Context, _ string, content string) ([]Finding, error) {
+		return f(ctx, content)
+	})
+}
+
+// PathCheckFunc is a CheckFunc that is also given the path of the file, see PathChangeFunc.
+type PathCheckFunc func(ctx context.Context, path string, content string) ([]Finding, error)
+
+// CheckPath works like Check, passing the path of each file to f.
+func CheckPath(ctx context.Context, filePaths []string, opts RewriteOptions, f PathCheckFunc) ([]Finding, error) {
+	filePaths, err := resolveFilePaths(filePaths, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	var errs []error
+	for _, filePath := range filePaths {
+		slog.Debug("checking file", "path", filePath)
+		content, err := readInput(filePath, opts)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to read file: %s", filePath))
+			continue
+		}
+
+		fileFindings, err := f(ctx, filePath, string(content))
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to check file: %s", filePath))
+			continue
+		}
+		for _, finding := range fileFindings {
+			finding.Path = filePath
+			findings = append(findings, finding)
+		}
+	}
+
+	if len(errs) > 0 {
+		return findings, errors.Join(errs...)
+	}
+	return findings, nil
+}
+
+// ListFiles returns the files Rewrite and Check would process for filePaths and opts, without reading them:
+// filePaths themselves, or the discovered workflow files when empty.
+func ListFiles(filePaths []string, opts RewriteOptions) ([]string, error) {
+	return resolveFilePaths(filePaths, opts)
+}
+
+// resolveFilePaths returns filePaths when given, otherwise discovers workflow files under the current directory.
+// Either way, the paths are normalized according to opts.PathStyle.
+func resolveFilePaths(filePaths []string, opts RewriteOptions) ([]string, error) {
+	if len(filePaths) > 1 && slices.Contains(filePaths, StdioPath) {
+		return nil, errors.Newf("%q (stdin) cannot be combined with other file arguments", StdioPath)
+	}
+	if len(filePaths) > 0 {
+		return normalizePaths(filePaths, opts.PathStyle)
+	}
+
+	slog.Debug("searching for workflow files to process")
+	workflowPaths, err := findWorkflowFiles(".", opts.IgnoreDirs, opts.ActionFilesOnly, opts.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("found workflow files", "count", len(workflowPaths))
+	if opts.Since != "" {
+		if workflowPaths, err = filterChangedSince(workflowPaths, opts.Since); err != nil {
+			return nil, err
+		}
+	}
+	return normalizePaths(workflowPaths, opts.PathStyle)
+}
+
+func isStdio(filePaths []string) bool {
+	return len(filePaths) == 1 && filePaths[0] == StdioPath
+}
+
+// readInput reads the file at filePath, or stdin when filePath is StdioPath.
+func readInput(filePath string, opts RewriteOptions) ([]byte, error) {
+	if filePath != StdioPath {
+		return readFile(filePath)
+	}
+	var stdin io.Reader = os.Stdin
+	if opts.Stdin != nil {
+		stdin = opts.Stdin
+	}
+	b, err := io.ReadAll(stdin)
+	return b, errors.WithStack(err)
+}
+
+// readFile reads the file at filePath. Paths given explicitly may be mistyped, so a missing file or a directory fails
+// with a plain message rather than the error of the read.
+func readFile(filePath string) ([]byte, error) {
+	b, err := os.ReadFile(filePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, errors.New("file does not exist")
+	case err != nil && isDir(filePath):
+		return nil, errors.New("is a directory, not a workflow file")
+	}
+	return b, errors.WithStack(err)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// processStdio runs f over stdin and writes the result to stdout. The content is always written, even when
+// unchanged, so the command works as a filter in pipelines. In dry-run mode nothing is written, and in diff mode
+// only the diff is written.
+func processStdio(ctx context.Context, opts RewriteOptions, f fixFunc) (RewriteResult, error) {
+	content, err := readInput(StdioPath, opts)
+	if err != nil {
+		return RewriteResult{}, errors.Wrap(err, "failed to read stdin")
+	}
+
+	modifiedContent, changed, changes, err := f(ctx, StdioPath, string(content))
+	if err != nil {
+		return RewriteResult{}, errors.Wrap(err, "failed to replace actions in stdin")
+	}
+
+	res := RewriteResult{}
+	if changed {
+		res.Changed = true
+		res.FileCount = 1
+		if changes != nil {
+			res.Files = []FileChanges{{Path: StdioPath, Changes: changes}}
+		}
+	}
+	if opts.Diff != nil {
+		diff, err := unifiedDiff(StdioPath, string(content), modifiedContent, opts.diffContext())
+		if err != nil {
+			return RewriteResult{}, errors.Wrap(err, "failed to diff stdin")
+		}
+		if _, err := io.WriteString(opts.Diff, diff); err != nil {
+			return RewriteResult{}, errors.Wrap(err, "failed to write diff")
+		}
+		return res, nil
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+
+	var stdout io.Writer = os.Stdout
+	if opts.Stdout != nil {
+		stdout = opts.Stdout
+	}
+	if _, err := io.WriteString(stdout, modifiedContent); err != nil {
+		return RewriteResult{}, errors.Wrap(err, "failed to write stdout")
+	}
+	return res, nil
+}
+
+type fileResult struct {
+	changed bool
+	changes []Change
+	// Unified diff of the file, only computed with RewriteOptions.Diff.
+	diff string
+	err  error
+}
+
+func processFile(ctx context.Context, filePath string, opts RewriteOptions, f fixFunc) fileResult {
+	content, err := readFile(filePath)
 	if err != nil {
-		return false, errors.WithStack(err)
+		return fileResult{err: err}
 	}
 
-	modifiedContent, changed, err := f(ctx, string(content))
+	modifiedContent, changed, changes, err := f(ctx, filePath, string(content))
 	if err != nil {
-		return false, errors.Wrapf(err, "failed to replace actions in file: %s", filePath)
+		return fileResult{err: errors.Wrapf(err, "failed to replace actions in file: %s", filePath)}
 	}
 	if !changed {
-		return false, nil
+		return fileResult{}
+	}
+	res := fileResult{changed: true, changes: changes}
+	if opts.Diff != nil {
+		res.diff, err = unifiedDiff(filePath, string(content), modifiedContent, opts.diffContext())
+		if err != nil {
+			return fileResult{err: errors.Wrapf(err, "failed to diff file: %s", filePath)}
+		}
+	}
+	if opts.dryRun() {
+		return res
 	}
 
-	err = writeFileAtomic(filePath, modifiedContent)
+	err = WriteFileAtomic(filePath, modifiedContent)
 	if err != nil {
-		return false, errors.Wrapf(err, "failed to write file: %s", filePath)
+		return fileResult{err: errors.Wrapf(err, "failed to write file: %s", filePath)}
 	}
 
-	return true, nil
+	return res
 }
 
 // findWorkflowFiles finds all workflow files (.yml or .yaml) in the current directory and subdirectories
 // ignoreDirs is an optional list of directory names to skip during traversal
-func findWorkflowFiles(root string, ignoreDirs []string) ([]string, error) {
+// actionFilesOnly restricts the result to files under .github/workflows/ and action.yml/action.yaml files
+// maxDepth, when positive, skips directories nested deeper than that below root (root/a/b is at depth 2)
+// Paths excluded by the IgnoreFileName files of root and its subdirectories are skipped as well.
+func findWorkflowFiles(root string, ignoreDirs []string, actionFilesOnly bool, maxDepth int) ([]string, error) {
 	var files []string
+	var ignores ignoreRules
 
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if rel != "." && ignores.ignored(rel, info.IsDir()) {
+			slog.Debug("skipping path excluded by ignore file", "path", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if info.IsDir() {
 			dirName := info.Name()
 
@@ -100,11 +488,20 @@ func findWorkflowFiles(root string, ignoreDirs []string) ([]string, error) {
 					return filepath.SkipDir
 				}
 			}
+
+			if maxDepth > 0 && dirDepth(root, path) > maxDepth {
+				slog.Debug("skipping directory beyond max depth", "path", path, "max_depth", maxDepth)
+				return filepath.SkipDir
+			}
+
+			if err := ignores.load(path, rel); err != nil {
+				return err
+			}
 		}
 
 		if !info.IsDir() {
 			ext := strings.ToLower(filepath.Ext(path))
-			if ext == ".yml" || ext == ".yaml" {
+			if (ext == ".yml" || ext == ".yaml") && (!actionFilesOnly || isActionFile(path)) {
 				files = append(files, path)
 			}
 		}
@@ -119,7 +516,29 @@ func findWorkflowFiles(root string, ignoreDirs []string) ([]string, error) {
 	return files, nil
 }
 
-func writeFileAtomic(targetPath, content string) error {
+// dirDepth returns how many levels path is nested below root, 0 for root itself.
+func dirDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
+
+// isActionFile reports whether path is a workflow (under .github/workflows/) or an action metadata file
+// (action.yml or action.yaml).
+func isActionFile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	if name == "action.yml" || name == "action.yaml" {
+		return true
+	}
+	dir := filepath.ToSlash(filepath.Dir(path))
+	return dir == ".github/workflows" || strings.HasSuffix(dir, "/.github/workflows")
+}
+
+// WriteFileAtomic writes content to a temporary file next to targetPath, then renames it over targetPath, so readers
+// never observe a partially written file.
+func WriteFileAtomic(targetPath, content string) error {
 	dir := filepath.Dir(targetPath)
 	fileName := filepath.Base(targetPath)
 	ext := filepath.Ext(fileName)
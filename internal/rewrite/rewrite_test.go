@@ -0,0 +1,680 @@
+package rewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replaceFix is a FixFunc replacing "old" with "new".
+func replaceFix(_ context.Context, content string) (string, bool, error) {
+	out := strings.ReplaceAll(content, "old", "new")
+	return out, out != content, nil
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRewrite(t *testing.T) {
+	dir := t.TempDir()
+	changedPath := writeTestFile(t, dir, "a.yml", "uses: old\n")
+	unchangedPath := writeTestFile(t, dir, "b.yml", "uses: other\n")
+
+	res, err := Rewrite(context.Background(), []string{changedPath, unchangedPath}, RewriteOptions{}, replaceFix)
+	require.NoError(t, err)
+	assert.True(t, res.Changed)
+	assert.Equal(t, 1, res.FileCount)
+	assert.Equal(t, "uses: new\n", readTestFile(t, changedPath))
+	assert.Equal(t, "uses: other\n", readTestFile(t, unchangedPath))
+}
+
+func TestRewrite_LineEndings(t *testing.T) {
+	// lineFix replaces whole lines like the pin fixes do, so it only matches lines without a stray \r.
+	lineFix := func(_ context.Context, content string) (string, bool, error) {
+		lines := strings.Split(content, "\n")
+		changed := false
+		for i, line := range lines {
+			if line == "  - uses: old" {
+				lines[i] = "  - uses: new"
+				changed = true
+			}
+		}
+		return strings.Join(lines, "\n"), changed, nil
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "CRLF",
+			input:    "steps:\r\n  - uses: old\r\n  - uses: other\r\n",
+			expected: "steps:\r\n  - uses: new\r\n  - uses: other\r\n",
+		},
+		{
+			name:     "CRLF without final newline",
+			input:    "steps:\r\n  - uses: other\r\n  - uses: old",
+			expected: "steps:\r\n  - uses: other\r\n  - uses: new",
+		},
+		{
+			name:     "LF",
+			input:    "steps:\n  - uses: old\n  - uses: other\n",
+			expected: "steps:\n  - uses: new\n  - uses: other\n",
+		},
+		{
+			name:     "Mostly CRLF",
+			input:    "steps:\r\n  - uses: old\r\n  - uses: other\n",
+			expected: "steps:\r\n  - uses: new\r\n  - uses: other\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, t.TempDir(), "a.yml", tt.input)
+			res, err := Rewrite(context.Background(), []string{path}, RewriteOptions{}, lineFix)
+			require.NoError(t, err)
+			assert.True(t, res.Changed)
+			assert.Equal(t, tt.expected, readTestFile(t, path))
+		})
+	}
+
+	t.Run("Unchanged CRLF files are left as is", func(t *testing.T) {
+		input := "steps:\r\n  - uses: other\n"
+		path := writeTestFile(t, t.TempDir(), "a.yml", input)
+		res, err := Rewrite(context.Background(), []string{path}, RewriteOptions{}, lineFix)
+		require.NoError(t, err)
+		assert.False(t, res.Changed)
+		assert.Equal(t, input, readTestFile(t, path))
+	})
+}
+
+func TestRewrite_FinalNewline(t *testing.T) {
+	tests := []struct {
+		name     string
+		fix      FixFunc
+		input    string
+		expected string
+		changed  bool
+	}{
+		{name: "Ends with newline", fix: replaceFix, input: "a: old\n", expected: "a: new\n", changed: true},
+		{name: "No trailing newline", fix: replaceFix, input: "a: old", expected: "a: new", changed: true},
+		{name: "Unchanged with newline", fix: replaceFix, input: "a: other\n", expected: "a: other\n"},
+		{name: "Unchanged without newline", fix: replaceFix, input: "a: other", expected: "a: other"},
+		{
+			name: "Added final newline is dropped",
+			fix: func(ctx context.Context, content string) (string, bool, error) {
+				out, changed, err := replaceFix(ctx, content)
+				return out + "\n", changed, err
+			},
+			input: "a: old", expected: "a: new", changed: true,
+		},
+		{
+			name: "Dropped final newline is restored",
+			fix: func(ctx context.Context, content string) (string, bool, error) {
+				out, changed, err := replaceFix(ctx, content)
+				return strings.TrimSuffix(out, "\n"), changed, err
+			},
+			input: "a: old\r\n", expected: "a: new\r\n", changed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, t.TempDir(), "a.yml", tt.input)
+			res, err := Rewrite(context.Background(), []string{path}, RewriteOptions{}, tt.fix)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, res.Changed)
+			assert.Equal(t, tt.expected, readTestFile(t, path))
+		})
+	}
+}
+
+func TestRewrite_Concurrency(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := range 50 {
+		content := "uses: old\n"
+		if i%10 == 0 {
+			content = "fail\n"
+		}
+		paths = append(paths, writeTestFile(t, dir, fmt.Sprintf("%02d.yml", i), content))
+	}
+
+	failingFix := func(ctx context.Context, content string) (string, bool, error) {
+		if content == "fail\n" {
+			return "", false, errors.New("broken")
+		}
+		return replaceFix(ctx, content)
+	}
+
+	res, err := Rewrite(context.Background(), paths, RewriteOptions{Concurrency: 8}, failingFix)
+	require.Error(t, err)
+	assert.True(t, res.Changed)
+	assert.Equal(t, 45, res.FileCount)
+
+	// Errors are reported in file order regardless of completion order.
+	var failed []string
+	for _, line := range strings.Split(err.Error(), "\n") {
+		if path, ok := strings.CutPrefix(line, "failed to process file: "); ok {
+			failed = append(failed, filepath.Base(strings.TrimSuffix(path, ": broken")))
+		}
+	}
+	assert.Equal(t, []string{"00.yml", "10.yml", "20.yml", "30.yml", "40.yml"}, failed)
+	assert.Equal(t, "uses: new\n", readTestFile(t, paths[1]))
+}
+
+func TestRewrite_Progress(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := range 20 {
+		paths = append(paths, writeTestFile(t, dir, fmt.Sprintf("%02d.yml", i), "uses: old\n"))
+	}
+
+	var done []int
+	progress := func(d, total int) {
+		assert.Equal(t, 20, total)
+		done = append(done, d)
+	}
+	_, err := Rewrite(context.Background(), paths, RewriteOptions{Concurrency: 4, Progress: progress}, replaceFix)
+	require.NoError(t, err)
+
+	// Called once per file, counting up, even though files complete concurrently.
+	require.Len(t, done, 20)
+	for i, d := range done {
+		assert.Equal(t, i+1, d)
+	}
+}
+
+func TestRewrite_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestFile(t, dir, "a.yml", "uses: old\n")
+	path2 := writeTestFile(t, dir, "b.yaml", "uses: old # old\n")
+	path3 := writeTestFile(t, dir, "c.yml", "uses: other\n")
+
+	res, err := Rewrite(context.Background(), []string{path1, path2, path3}, RewriteOptions{DryRun: true}, replaceFix)
+	require.NoError(t, err)
+	assert.True(t, res.Changed)
+	assert.Equal(t, 2, res.FileCount, "files that would change are still counted")
+
+	// Nothing is written.
+	assert.Equal(t, "uses: old\n", readTestFile(t, path1))
+	assert.Equal(t, "uses: old # old\n", readTestFile(t, path2))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "no temporary files are left behind")
+}
+
+func TestRewrite_Diff(t *testing.T) {
+	t.Chdir(t.TempDir())
+	path1 := writeTestFile(t, ".", "a.yml", "name: a\njobs:\n  build:\n    steps:\n      - uses: old\n")
+	path2 := writeTestFile(t, ".", "b.yml", "uses: other\n")
+	path3 := writeTestFile(t, ".github/workflows", "c.yml", "uses: old\n")
+
+	var diff bytes.Buffer
+	res, err := Rewrite(context.Background(), []string{path1, path2, path3}, RewriteOptions{Diff: &diff, Concurrency: 3}, replaceFix)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.FileCount)
+
+	expected := `--- a/a.yml
++++ b/a.yml
+@@ -2,4 +2,4 @@
+ jobs:
+   build:
+     steps:
+-      - uses: old
++      - uses: new
+--- a/.github/workflows/c.yml
++++ b/.github/workflows/c.yml
+@@ -1 +1 @@
+-uses: old
++uses: new
+`
+	assert.Equal(t, expected, diff.String(), "diffs are written in file order")
+	assert.Equal(t, "uses: old\n", readTestFile(t, path3), "files are not written")
+
+	t.Run("No newline at end of file", func(t *testing.T) {
+		path := writeTestFile(t, ".", "d.yml", "name: d\nuses: old")
+
+		var diff bytes.Buffer
+		_, err := Rewrite(context.Background(), []string{path}, RewriteOptions{Diff: &diff}, replaceFix)
+		require.NoError(t, err)
+		expected := "--- a/d.yml\n+++ b/d.yml\n@@ -1,2 +1,2 @@\n name: d\n" +
+			"-uses: old\n\\ No newline at end of file\n+uses: new\n\\ No newline at end of file\n"
+		assert.Equal(t, expected, diff.String())
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		var diff, stdout bytes.Buffer
+		opts := RewriteOptions{Stdin: strings.NewReader("uses: old\n"), Stdout: &stdout, Diff: &diff}
+
+		_, err := Rewrite(context.Background(), []string{StdioPath}, opts, replaceFix)
+		require.NoError(t, err)
+		assert.Equal(t, "--- a/-\n+++ b/-\n@@ -1 +1 @@\n-uses: old\n+uses: new\n", diff.String())
+		assert.Empty(t, stdout.String(), "only the diff is written")
+	})
+}
+
+func TestRewrite_Stdio(t *testing.T) {
+	t.Run("Reads stdin and writes the result to stdout", func(t *testing.T) {
+		var stdout bytes.Buffer
+		opts := RewriteOptions{Stdin: strings.NewReader("uses: old\n"), Stdout: &stdout}
+
+		res, err := Rewrite(context.Background(), []string{StdioPath}, opts, replaceFix)
+		require.NoError(t, err)
+		assert.True(t, res.Changed)
+		assert.Equal(t, 1, res.FileCount)
+		assert.Equal(t, "uses: new\n", stdout.String())
+	})
+
+	t.Run("Unchanged input is still written", func(t *testing.T) {
+		var stdout bytes.Buffer
+		opts := RewriteOptions{Stdin: strings.NewReader("uses: other\n"), Stdout: &stdout}
+
+		res, err := Rewrite(context.Background(), []string{StdioPath}, opts, replaceFix)
+		require.NoError(t, err)
+		assert.False(t, res.Changed)
+		assert.Equal(t, "uses: other\n", stdout.String())
+	})
+
+	t.Run("Cannot be combined with other files", func(t *testing.T) {
+		path := writeTestFile(t, t.TempDir(), "a.yml", "uses: old\n")
+		_, err := Rewrite(context.Background(), []string{StdioPath, path}, RewriteOptions{}, replaceFix)
+		require.Error(t, err)
+		assert.Equal(t, "uses: old\n", readTestFile(t, path))
+	})
+}
+
+func TestRewritePathChanges(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.yml", "uses: old\n")
+	b := writeTestFile(t, dir, "b.yml", "uses: old\n")
+
+	// Only fixes a.yml, as a per-file setting would.
+	pathFix := func(ctx context.Context, path string, content string) (string, []Change, error) {
+		if path != a {
+			return content, nil, nil
+		}
+		modified, _, err := replaceFix(ctx, content)
+		return modified, []Change{{Line: 1}}, err
+	}
+	res, err := RewritePathChanges(context.Background(), []string{a, b}, RewriteOptions{}, pathFix)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.FileCount)
+	assert.Equal(t, "uses: new\n", readTestFile(t, a))
+	assert.Equal(t, "uses: old\n", readTestFile(t, b))
+
+	var stdout strings.Builder
+	var gotPath string
+	_, err = RewritePathChanges(context.Background(), []string{StdioPath}, RewriteOptions{Stdin: strings.NewReader("uses: old\n"), Stdout: &stdout},
+		func(_ context.Context, path string, content string) (string, []Change, error) {
+			gotPath = path
+			return content, nil, nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, StdioPath, gotPath)
+}
+
+func TestRewriteChanges(t *testing.T) {
+	// Records one change per "old" line.
+	changeFix := func(_ context.Context, content string) (string, []Change, error) {
+		lines := strings.Split(content, "\n")
+		var changes []Change
+		for i, line := range lines {
+			if line == "uses: old" {
+				lines[i] = "uses: new"
+				changes = append(changes, Change{Line: i + 1, FromRef: "old", ToSHA: "new"})
+			}
+		}
+		return strings.Join(lines, "\n"), changes, nil
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("DryRun=%t", dryRun), func(t *testing.T) {
+			dir := t.TempDir()
+			a := writeTestFile(t, dir, "a.yml", "uses: old\nuses: other\nuses: old\n")
+			b := writeTestFile(t, dir, "b.yml", "uses: other\n")
+			c := writeTestFile(t, dir, "c.yml", "uses: old\n")
+
+			res, err := RewriteChanges(context.Background(), []string{a, b, c}, RewriteOptions{Concurrency: 2, DryRun: dryRun}, changeFix)
+			require.NoError(t, err)
+			assert.True(t, res.Changed)
+			assert.Equal(t, 2, res.FileCount)
+			assert.Equal(t, []FileChanges{
+				{Path: a, Changes: []Change{{Line: 1, FromRef: "old", ToSHA: "new", Text: "uses: new"}, {Line: 3, FromRef: "old", ToSHA: "new", Text: "uses: new"}}},
+				{Path: c, Changes: []Change{{Line: 1, FromRef: "old", ToSHA: "new", Text: "uses: new"}}},
+			}, res.Files, "unchanged files are not listed; each change records the rewritten line")
+		})
+	}
+
+	t.Run("Rewrite records no lines", func(t *testing.T) {
+		dir := t.TempDir()
+		a := writeTestFile(t, dir, "a.yml", "uses: old\n")
+		res, err := Rewrite(context.Background(), []string{a}, RewriteOptions{}, replaceFix)
+		require.NoError(t, err)
+		assert.True(t, res.Changed)
+		assert.Empty(t, res.Files)
+	})
+}
+
+func TestRewrite_PathStyle(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	writeTestFile(t, ".", "a.yml", "old\n")
+	writeTestFile(t, ".github/workflows", "b.yml", "old\n")
+	findOld := func(_ context.Context, content string) ([]Finding, error) {
+		return []Finding{{Line: 1, Message: content}}, nil
+	}
+	paths := func(findings []Finding) []string {
+		var paths []string
+		for _, f := range findings {
+			paths = append(paths, f.Path)
+		}
+		return paths
+	}
+	// Explicit arguments mixing absolute and relative paths.
+	args := []string{filepath.Join(cwd, "a.yml"), "./.github/workflows/b.yml"}
+
+	tests := []struct {
+		style    PathStyle
+		args     []string
+		expected []string
+	}{
+		{style: PathStyleAsIs, args: args, expected: args},
+		{style: PathStyleRelative, args: args, expected: []string{"a.yml", ".github/workflows/b.yml"}},
+		{style: PathStyleAbsolute, args: args, expected: []string{filepath.Join(cwd, "a.yml"), filepath.Join(cwd, ".github/workflows/b.yml")}},
+		{style: PathStyleRelative, expected: []string{".github/workflows/b.yml", "a.yml"}},
+		{style: PathStyleAbsolute, expected: []string{filepath.Join(cwd, ".github/workflows/b.yml"), filepath.Join(cwd, "a.yml")}},
+		{style: PathStyleAbsolute, args: []string{StdioPath}, expected: []string{StdioPath}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %v", tt.style, tt.args), func(t *testing.T) {
+			opts := RewriteOptions{PathStyle: tt.style, Stdin: strings.NewReader("old\n")}
+			findings, err := Check(context.Background(), tt.args, opts, findOld)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, paths(findings))
+		})
+	}
+
+	t.Run("Rewrite results", func(t *testing.T) {
+		changeFix := func(_ context.Context, content string) (string, []Change, error) {
+			return content, []Change{{Line: 1}}, nil
+		}
+		res, err := RewriteChanges(context.Background(), args, RewriteOptions{PathStyle: PathStyleRelative, DryRun: true}, changeFix)
+		require.NoError(t, err)
+		require.Len(t, res.Files, 2)
+		assert.Equal(t, "a.yml", res.Files[0].Path)
+		assert.Equal(t, ".github/workflows/b.yml", res.Files[1].Path)
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		for _, s := range []string{"", "relative", "absolute"} {
+			p, err := ParsePathStyle(s)
+			require.NoError(t, err)
+			assert.Equal(t, PathStyle(s), p)
+		}
+		_, err := ParsePathStyle("canonical")
+		require.Error(t, err)
+	})
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestFile(t, dir, "a.yml", "x\nold\n")
+	path2 := writeTestFile(t, dir, "b.yml", "new\n")
+
+	checkOld := func(_ context.Context, content string) ([]Finding, error) {
+		var findings []Finding
+		for i, line := range strings.Split(content, "\n") {
+			if line == "old" {
+				findings = append(findings, Finding{Line: i + 1, Message: line})
+			}
+		}
+		return findings, nil
+	}
+
+	findings, err := Check(context.Background(), []string{path1, path2}, RewriteOptions{}, checkOld)
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{{Path: path1, Line: 2, Message: "old"}}, findings)
+	assert.Equal(t, "x\nold\n", readTestFile(t, path1), "files are never modified")
+}
+
+func TestFindWorkflowFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		".github/workflows/ci.yml",
+		".github/workflows/release.yaml",
+		".github/dependabot.yml",
+		"action.yml",
+		"actions/setup/action.yaml",
+		"sub/.github/workflows/lint.yml",
+		"config/app.yml",
+		"docker-compose.yaml",
+		"node_modules/pkg/action.yml",
+		"README.md",
+	} {
+		writeTestFile(t, dir, name, "")
+	}
+
+	rel := func(paths []string) []string {
+		out := make([]string, 0, len(paths))
+		for _, p := range paths {
+			r, err := filepath.Rel(dir, p)
+			require.NoError(t, err)
+			out = append(out, filepath.ToSlash(r))
+		}
+		return out
+	}
+
+	t.Run("All YAML files", func(t *testing.T) {
+		files, err := findWorkflowFiles(dir, []string{"node_modules"}, false, 0)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			".github/workflows/ci.yml",
+			".github/workflows/release.yaml",
+			".github/dependabot.yml",
+			"action.yml",
+			"actions/setup/action.yaml",
+			"sub/.github/workflows/lint.yml",
+			"config/app.yml",
+			"docker-compose.yaml",
+		}, rel(files))
+	})
+
+	t.Run("Action files only", func(t *testing.T) {
+		files, err := findWorkflowFiles(dir, []string{"node_modules"}, true, 0)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			".github/workflows/ci.yml",
+			".github/workflows/release.yaml",
+			"action.yml",
+			"actions/setup/action.yaml",
+			"sub/.github/workflows/lint.yml",
+		}, rel(files))
+	})
+
+	t.Run("Max depth", func(t *testing.T) {
+		files, err := findWorkflowFiles(dir, []string{"node_modules"}, false, 1)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			".github/dependabot.yml",
+			"action.yml",
+			"config/app.yml",
+			"docker-compose.yaml",
+		}, rel(files))
+
+		files, err = findWorkflowFiles(dir, []string{"node_modules"}, true, 2)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			".github/workflows/ci.yml",
+			".github/workflows/release.yaml",
+			"action.yml",
+			"actions/setup/action.yaml",
+		}, rel(files), "sub/.github/workflows is at depth 3")
+	})
+}
+
+func TestFindWorkflowFiles_IgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		".github/workflows/ci.yml",
+		".github/workflows/ci.generated.yml",
+		"examples/basic.yml",
+		"examples/real-workflow.yml",
+		"examples/nested/deep.yml",
+		"vendor/lib/action.yml",
+		"tools/vendor/action.yml",
+		"tools/vendor.yml",
+		"team/ci.yml",
+		"team/fixtures/a.yml",
+		"team/fixtures/keep.yml",
+		"fixtures/a.yml",
+	} {
+		writeTestFile(t, dir, name, "")
+	}
+	writeTestFile(t, dir, IgnoreFileName, `# Directory excludes match at any level
+vendor/
+
+# Glob excludes
+*.generated.yml
+examples/**
+
+# Negation re-includes
+!examples/real-workflow.yml
+`)
+	writeTestFile(t, dir, "team/"+IgnoreFileName, "/fixtures/*\n!keep.yml\n")
+
+	files, err := findWorkflowFiles(dir, nil, false, 0)
+	require.NoError(t, err)
+	rel := make([]string, 0, len(files))
+	for _, p := range files {
+		r, err := filepath.Rel(dir, p)
+		require.NoError(t, err)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	assert.ElementsMatch(t, []string{
+		".github/workflows/ci.yml",
+		"examples/real-workflow.yml",
+		"tools/vendor.yml",
+		"team/ci.yml",
+		"team/fixtures/keep.yml",
+		"fixtures/a.yml",
+	}, rel)
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		bad := t.TempDir()
+		writeTestFile(t, bad, IgnoreFileName, "[abc\n")
+		_, err := findWorkflowFiles(bad, nil, false, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), IgnoreFileName)
+	})
+}
+
+func TestRewrite_Since(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	writeTestFile(t, dir, "unchanged.yml", "uses: old\n")
+	writeTestFile(t, dir, "committed.yml", "uses: old\n")
+	writeTestFile(t, dir, "deleted.yml", "uses: old\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	git("tag", "base")
+	writeTestFile(t, dir, "committed.yml", "uses: old # changed\n")
+	writeTestFile(t, dir, "sub/added.yaml", "uses: old\n")
+	writeTestFile(t, dir, "notes.txt", "old\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "deleted.yml")))
+	git("add", "-A")
+	git("commit", "-q", "-m", "change")
+	writeTestFile(t, dir, "uncommitted.yml", "uses: old\n")
+	git("add", "uncommitted.yml")
+
+	t.Run("Changed files only", func(t *testing.T) {
+		var processed []string
+		_, err := RewritePathChanges(context.Background(), nil, RewriteOptions{Since: "base", DryRun: true, Concurrency: 1},
+			func(_ context.Context, path string, content string) (string, []Change, error) {
+				processed = append(processed, path)
+				return content, nil, nil
+			})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"committed.yml", filepath.Join("sub", "added.yaml"), "uncommitted.yml"}, processed)
+	})
+
+	t.Run("Unknown ref", func(t *testing.T) {
+		_, err := Check(context.Background(), nil, RewriteOptions{Since: "no-such-ref"}, func(context.Context, string) ([]Finding, error) {
+			return nil, nil
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("Not a git repository", func(t *testing.T) {
+		other := t.TempDir()
+		t.Chdir(other)
+		t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(other))
+		writeTestFile(t, other, "a.yml", "uses: old\n")
+		findings, err := Check(context.Background(), nil, RewriteOptions{Since: "base"}, func(context.Context, string) ([]Finding, error) {
+			return []Finding{{Line: 1}}, nil
+		})
+		require.NoError(t, err)
+		assert.Len(t, findings, 1, "every discovered file is processed")
+	})
+}
+
+func TestRewriteChanges_OnlyChangedLines(t *testing.T) {
+	// Records a change for every uses line, including those it leaves as they were.
+	changeFix := func(_ context.Context, content string) (string, []Change, error) {
+		lines := strings.Split(content, "\n")
+		var changes []Change
+		for i, line := range lines {
+			if from, ok := strings.CutPrefix(line, "uses: "); ok {
+				lines[i] = strings.Replace(line, "old", "new", 1)
+				changes = append(changes, Change{Line: i + 1, FromRef: from})
+			}
+		}
+		return strings.Join(lines, "\n"), changes, nil
+	}
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.yml", "uses: old\nuses: pinned\n")
+	b := writeTestFile(t, dir, "b.yml", "uses: pinned\n")
+
+	res, err := RewriteChanges(context.Background(), []string{a, b}, RewriteOptions{DryRun: true}, changeFix)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.FileCount, "every change is recorded by default")
+
+	res, err = RewriteChanges(context.Background(), []string{a, b}, RewriteOptions{DryRun: true, OnlyChangedLines: true}, changeFix)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.FileCount)
+	require.Len(t, res.Files, 1)
+	assert.Equal(t, a, res.Files[0].Path)
+	require.Len(t, res.Files[0].Changes, 1)
+	assert.Equal(t, 1, res.Files[0].Changes[0].Line, "the pinned line is left out")
+}
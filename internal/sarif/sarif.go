@@ -0,0 +1,226 @@
+// Package sarif serializes the changes of a pin run as a SARIF 2.1.0 log, for GitHub code scanning and other tools
+// annotating pull requests: each unpinned action is a result of the unpinned-action rule, with the pinned line as its
+// suggested fix.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+const (
+	// Version is the SARIF version of the logs written by Write, and Schema the location of its JSON schema.
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// RuleID identifies the results of actions not pinned to a commit SHA.
+	RuleID = "unpinned-action"
+
+	// srcRoot is the base of relative artifact locations: the directory gha-fix ran in, usually the repository root.
+	srcRoot = "%SRCROOT%"
+)
+
+// Log is the top-level object of a SARIF file.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is the output of one run of a tool.
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+// Tool describes the tool that produced a run.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the tool component that produced the results, with the rules they refer to.
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules"`
+}
+
+// Rule is a reporting descriptor: what a result ID means and how it is reported by default.
+type Rule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name"`
+	ShortDescription     Message                `json:"shortDescription"`
+	FullDescription      Message                `json:"fullDescription"`
+	Help                 Message                `json:"help"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration ReportingConfiguration `json:"defaultConfiguration"`
+	Properties           *RuleProperties        `json:"properties,omitempty"`
+}
+
+// ReportingConfiguration is the default reporting of the results of a rule.
+type ReportingConfiguration struct {
+	Level string `json:"level"`
+}
+
+// RuleProperties are the properties of a rule; Tags categorize it (e.g. in code scanning).
+type RuleProperties struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Message is a plain text message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is a finding of a rule at a location.
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations"`
+	Fixes     []Fix      `json:"fixes,omitempty"`
+}
+
+// Location is where a result was found.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a region of a file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           Region           `json:"region"`
+}
+
+// ArtifactLocation is the location of a file: a URI, relative to URIBaseID when set.
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region is a range of lines of an artifact. A region with only StartLine is that whole line, without its line
+// ending.
+type Region struct {
+	StartLine int `json:"startLine"`
+}
+
+// Fix is a suggested change resolving a result.
+type Fix struct {
+	Description     Message          `json:"description"`
+	ArtifactChanges []ArtifactChange `json:"artifactChanges"`
+}
+
+// ArtifactChange is the part of a fix changing one file.
+type ArtifactChange struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Replacements     []Replacement    `json:"replacements"`
+}
+
+// Replacement replaces a region of a file with new content.
+type Replacement struct {
+	DeletedRegion   Region          `json:"deletedRegion"`
+	InsertedContent ArtifactContent `json:"insertedContent"`
+}
+
+// ArtifactContent is the content inserted by a replacement.
+type ArtifactContent struct {
+	Text string `json:"text"`
+}
+
+// rule describes RuleID.
+var rule = Rule{
+	ID:               RuleID,
+	Name:             "UnpinnedAction",
+	ShortDescription: Message{Text: "Action not pinned to a commit SHA"},
+	FullDescription: Message{Text: "The action is referenced by a tag or branch, which can be moved to different code at " +
+		"any time. Pinning it to a full commit SHA makes the workflow run the reviewed code only."},
+	Help:                 Message{Text: "Pin the action to the commit SHA of its ref, e.g. with `gha-fix pin`, keeping the ref in a comment."},
+	HelpURI:              "https://github.com/Finatext/gha-fix#pin",
+	DefaultConfiguration: ReportingConfiguration{Level: "error"},
+	Properties:           &RuleProperties{Tags: []string{"security", "supply-chain"}},
+}
+
+// New converts the changes recorded in res, as returned by a dry-run pin, to a SARIF log with one result per change.
+// Changes recording their rewritten line (rewrite.Change.Text) get a fix replacing the line with it.
+func New(res rewrite.RewriteResult) Log {
+	results := []Result{} // Always an array, never null
+	for _, file := range res.Files {
+		location := artifactLocation(file.Path)
+		for _, c := range file.Changes {
+			results = append(results, newResult(location, c))
+		}
+	}
+	return Log{
+		Schema:  Schema,
+		Version: Version,
+		Runs: []Run{{
+			Tool: Tool{Driver: Driver{
+				Name:           "gha-fix",
+				InformationURI: "https://github.com/Finatext/gha-fix",
+				Rules:          []Rule{rule},
+			}},
+			Results: results,
+		}},
+	}
+}
+
+func newResult(location ArtifactLocation, c rewrite.Change) Result {
+	action := c.Owner + "/" + c.Repo
+	if c.Path != "" {
+		action += "/" + c.Path
+	}
+	action += "@" + c.FromRef
+
+	target := c.ToSHA
+	if c.ToRef != "" {
+		target = c.ToRef
+	}
+	if c.ResolvedComment != "" && c.ResolvedComment != target {
+		target += " (" + c.ResolvedComment + ")"
+	}
+
+	region := Region{StartLine: c.Line}
+	result := Result{
+		RuleID:    RuleID,
+		Level:     rule.DefaultConfiguration.Level,
+		Message:   Message{Text: fmt.Sprintf("Action %s is not pinned to a commit SHA; pin it to %s.", action, target)},
+		Locations: []Location{{PhysicalLocation: PhysicalLocation{ArtifactLocation: location, Region: region}}},
+	}
+	if c.Text != "" {
+		result.Fixes = []Fix{{
+			Description: Message{Text: "Pin " + action + " to " + target},
+			ArtifactChanges: []ArtifactChange{{
+				ArtifactLocation: location,
+				Replacements:     []Replacement{{DeletedRegion: region, InsertedContent: ArtifactContent{Text: c.Text}}},
+			}},
+		}}
+	}
+	return result
+}
+
+// artifactLocation returns the location of the file at path: relative to %SRCROOT% for relative paths, a file URI
+// otherwise.
+func artifactLocation(path string) ArtifactLocation {
+	if filepath.IsAbs(path) {
+		uri := filepath.ToSlash(path)
+		if !strings.HasPrefix(uri, "/") {
+			uri = "/" + uri // Windows drive letters
+		}
+		return ArtifactLocation{URI: "file://" + uri}
+	}
+	return ArtifactLocation{URI: filepath.ToSlash(filepath.Clean(path)), URIBaseID: srcRoot}
+}
+
+// Write writes the SARIF log of res (see New) as indented JSON.
+func Write(w io.Writer, res rewrite.RewriteResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.WithStack(enc.Encode(New(res)))
+}
@@ -0,0 +1,97 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+var sampleResult = rewrite.RewriteResult{
+	Changed:   true,
+	FileCount: 2,
+	Files: []rewrite.FileChanges{
+		{
+			Path: ".github/workflows/ci.yml",
+			Changes: []rewrite.Change{
+				{
+					Line: 7, Owner: "actions", Repo: "checkout", FromRef: "v4",
+					ToSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", ResolvedComment: "v4.2.2",
+					Text: "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+				},
+				{
+					Line: 9, Owner: "oasdiff", Repo: "oasdiff-action", Path: "diff", FromRef: "main",
+					ToSHA: "1c611ffb1253a72924624aa4fb662e302b3565d3", ResolvedComment: "main", Branch: true,
+					Text: "      - uses: oasdiff/oasdiff-action/diff@1c611ffb1253a72924624aa4fb662e302b3565d3 # main",
+				},
+			},
+		},
+		{
+			Path: "actions/setup/action.yml",
+			Changes: []rewrite.Change{
+				{
+					Line: 12, Owner: "actions", Repo: "setup-go", FromRef: "v5",
+					ToSHA: "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b", ToRef: "v5.4.0", ResolvedComment: "v5.4.0",
+				},
+			},
+		},
+	},
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, sampleResult))
+
+	want, err := os.ReadFile("testdata/sample.sarif")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), buf.String())
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, sampleResult))
+
+	var log Log
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, New(sampleResult), log)
+
+	// The properties required by the SARIF 2.1.0 schema.
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "gha-fix", run.Tool.Driver.Name)
+	require.Len(t, run.Results, 3)
+	for _, r := range run.Results {
+		assert.NotEmpty(t, r.Message.Text)
+		assert.Equal(t, run.Tool.Driver.Rules[0].ID, r.RuleID)
+		require.Len(t, r.Locations, 1)
+		assert.Positive(t, r.Locations[0].PhysicalLocation.Region.StartLine)
+	}
+	assert.Len(t, run.Results[0].Fixes, 1)
+	assert.Empty(t, run.Results[2].Fixes, "no fix without the rewritten line")
+}
+
+func TestNew(t *testing.T) {
+	t.Run("No changes", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, rewrite.RewriteResult{}))
+		var raw map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		results := raw["runs"].([]any)[0].(map[string]any)["results"]
+		assert.Equal(t, []any{}, results, "results is an empty array, not null")
+	})
+
+	t.Run("Absolute paths", func(t *testing.T) {
+		log := New(rewrite.RewriteResult{Files: []rewrite.FileChanges{{
+			Path:    "/work/repo/.github/workflows/ci.yml",
+			Changes: []rewrite.Change{{Line: 1, Owner: "actions", Repo: "checkout", FromRef: "v4", ToSHA: "11bd71901bbe5b1630ceea73d27597364c9af683"}},
+		}}})
+		location := log.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation
+		assert.Equal(t, ArtifactLocation{URI: "file:///work/repo/.github/workflows/ci.yml"}, location)
+	})
+}
@@ -0,0 +1,71 @@
+package pin
+
+import (
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-yaml"
+)
+
+// Allowlist is the set of pre-approved commit SHAs per action repository. It is loaded from a YAML mapping of
+// owner/repo to approved SHAs:
+//
+//	actions/checkout:
+//	  - 11bd71901bbe5b1630ceea73d27597364c9af683
+//	actions/setup-go:
+//	  - 0aaccfd150d50ccaeb58ebd88d36e91967a5f35b
+//
+// Repositories missing from the allowlist have no approved SHA.
+type Allowlist struct {
+	// Lowercase owner/repo -> lowercase SHAs.
+	shas map[string]map[string]struct{}
+}
+
+// NotAllowlistedError is returned when an action resolves to a commit SHA that isn't on the allowlist.
+var NotAllowlistedError = errors.New("commit SHA is not on the allowlist")
+
+// LoadAllowlist reads an allowlist from the YAML file at path.
+func LoadAllowlist(path string) (*Allowlist, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read allowlist: %s", path)
+	}
+	allowlist, err := ParseAllowlist(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid allowlist: %s", path)
+	}
+	return allowlist, nil
+}
+
+// ParseAllowlist parses an allowlist from YAML. See Allowlist for the format.
+func ParseAllowlist(b []byte) (*Allowlist, error) {
+	var entries map[string][]string
+	if err := yaml.Unmarshal(b, &entries); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	a := &Allowlist{shas: make(map[string]map[string]struct{}, len(entries))}
+	for repo, shas := range entries {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, errors.Newf("invalid repository %q: must be owner/repo", repo)
+		}
+		key := strings.ToLower(repo)
+		if a.shas[key] == nil {
+			a.shas[key] = make(map[string]struct{}, len(shas))
+		}
+		for _, sha := range shas {
+			if len(sha) != 40 {
+				return nil, errors.Newf("invalid commit SHA %q for %s: must be a full 40-character SHA", sha, repo)
+			}
+			a.shas[key][strings.ToLower(sha)] = struct{}{}
+		}
+	}
+	return a, nil
+}
+
+// Allows reports whether sha is approved for owner/repo.
+func (a *Allowlist) Allows(owner, repo, sha string) bool {
+	_, ok := a.shas[strings.ToLower(owner+"/"+repo)][strings.ToLower(sha)]
+	return ok
+}
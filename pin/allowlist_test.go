@@ -0,0 +1,81 @@
+package pin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowlist(t *testing.T) {
+	const approved = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	const unapproved = "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b"
+	allowlist, err := ParseAllowlist([]byte(`
+actions/checkout:
+  - ` + approved + `
+actions/setup-go: []
+`))
+	require.NoError(t, err)
+
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: approved, RefComment: "v4.2.2"},
+		"actions/checkout@v3": {CommitSHA: unapproved, RefComment: "v3.6.0"},
+		"actions/setup-go@v5": {CommitSHA: unapproved, RefComment: "v5.4.0"},
+		"org/unlisted@v1":     {CommitSHA: approved, RefComment: "v1.0.0"},
+	}}
+
+	t.Run("Approved SHA is pinned", func(t *testing.T) {
+		p := &Pin{resolver: resolver, allowlist: allowlist}
+		got, changed, err := p.Apply(context.Background(), "- uses: actions/checkout@v4")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "- uses: actions/checkout@"+approved+" # v4.2.2", got)
+		assert.True(t, allowlist.Allows("Actions", "Checkout", "11BD71901BBE5B1630CEEA73D27597364C9AF683"), "matching is case-insensitive")
+	})
+
+	t.Run("Unapproved SHAs fail", func(t *testing.T) {
+		p := &Pin{resolver: resolver, allowlist: allowlist}
+		input := "- uses: actions/checkout@v3\n- uses: actions/setup-go@v5\n- uses: org/unlisted@v1\n- uses: actions/checkout@v4"
+		got, changes, err := p.ApplyChanges(context.Background(), input)
+		require.ErrorIs(t, err, NotAllowlistedError)
+		assert.Contains(t, err.Error(), "actions/checkout@v3")
+		assert.Contains(t, err.Error(), "actions/setup-go@v5")
+		assert.Contains(t, err.Error(), "org/unlisted@v1")
+		require.Len(t, changes, 1, "approved lines are still pinned")
+		assert.Equal(t, "- uses: actions/checkout@v3\n- uses: actions/setup-go@v5\n- uses: org/unlisted@v1\n- uses: actions/checkout@"+approved+" # v4.2.2", got)
+	})
+
+	t.Run("Unapproved SHAs warn only", func(t *testing.T) {
+		p := &Pin{resolver: resolver, allowlist: allowlist, allowlistWarnOnly: true}
+		got, changed, err := p.Apply(context.Background(), "- uses: actions/checkout@v3")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "- uses: actions/checkout@"+unapproved+" # v3.6.0", got)
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "allowlist.yml")
+		require.NoError(t, os.WriteFile(path, []byte("actions/checkout: ["+approved+"]\n"), 0o600))
+		a, err := LoadAllowlist(path)
+		require.NoError(t, err)
+		assert.True(t, a.Allows("actions", "checkout", approved))
+		assert.False(t, a.Allows("actions", "checkout", unapproved))
+
+		_, err = LoadAllowlist(filepath.Join(t.TempDir(), "missing.yml"))
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, s := range []string{
+			"actions: [" + approved + "]",
+			"actions/checkout: [v4]",
+			"actions/checkout: " + approved,
+		} {
+			_, err := ParseAllowlist([]byte(s))
+			assert.Error(t, err, s)
+		}
+	})
+}
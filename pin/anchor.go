@@ -0,0 +1,86 @@
+package pin
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// anchorPattern matches the YAML anchors (&name) of a line and aliasPattern its aliases (*name), as in
+// `- <<: *checkout` or `uses: *checkout`.
+var (
+	anchorPattern = regexp.MustCompile(`(?:^|[\s:\-\[,])&([^\s\[\]{},]+)`)
+	aliasPattern  = regexp.MustCompile(`(?:^|[\s:\-\[,])\*([^\s\[\]{},]+)`)
+)
+
+// anchoredUses is a uses: to pin that is defined by a YAML anchor.
+type anchoredUses struct {
+	line   int // 1-based line of the uses:
+	action string
+}
+
+// warnAnchoredUses warns about the aliases in lines, e.g. merge keys like `<<: *checkout`, that pull in a uses: Apply
+// would pin. Such steps have no uses: of their own at the usage site: the action is pinned where the anchor is
+// defined, which the warning points to.
+func (p *Pin) warnAnchoredUses(lines []string) {
+	anchors := p.anchoredTargets(lines)
+	if len(anchors) == 0 {
+		return
+	}
+	for i, line := range lines {
+		for _, m := range aliasPattern.FindAllStringSubmatch(stripLineComment(line), -1) {
+			if uses, ok := anchors[m[1]]; ok {
+				slog.Warn("step uses an action defined via a YAML anchor; it is pinned where the anchor is defined",
+					"line", i+1, "alias", "*"+m[1], "action", uses.action, "anchor_line", uses.line)
+			}
+		}
+	}
+}
+
+// anchoredTargets maps the anchors of lines defining a uses: to pin, either as the anchored value
+// (`uses: &checkout actions/checkout@v4`) or within the anchored block (`x-checkout: &checkout` followed by an indented
+// `uses:`), to that uses:.
+func (p *Pin) anchoredTargets(lines []string) map[string]anchoredUses {
+	anchors := make(map[string]anchoredUses)
+	for i, line := range lines {
+		for _, m := range anchorPattern.FindAllStringSubmatch(stripLineComment(line), -1) {
+			if usesValuePattern.MatchString(line) {
+				if parsed, ok := p.parseTarget(line); ok {
+					anchors[m[1]] = anchoredUses{line: i + 1, action: parsed.def.String()}
+				}
+				continue
+			}
+
+			indent := indentOf(line)
+			for j := i + 1; j < len(lines); j++ {
+				trimmed := strings.TrimSpace(lines[j])
+				if trimmed == "" || trimmed[0] == '#' {
+					continue
+				}
+				if indentOf(lines[j]) <= indent {
+					break
+				}
+				if parsed, ok := p.parseTarget(lines[j]); ok {
+					anchors[m[1]] = anchoredUses{line: j + 1, action: parsed.def.String()}
+					break
+				}
+			}
+		}
+	}
+	return anchors
+}
+
+// stripLineComment returns line without its comment, if any. Comments in quoted values are not recognized.
+func stripLineComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if idx := strings.Index(line, " #"); idx >= 0 {
+		return line[:idx]
+	}
+	return line
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
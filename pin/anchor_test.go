@@ -0,0 +1,126 @@
+package pin
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYAMLAnchors(t *testing.T) {
+	const checkoutSHA = "11bd71901bbe5b1630ceea73d27597364c9af683"
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		// Substrings of the logged warning, or nil if none is expected.
+		warning []string
+	}{
+		{
+			name: "Merge key of a step template",
+			input: `x-checkout: &checkout
+  uses: actions/checkout@v4
+  with:
+    fetch-depth: 0
+jobs:
+  build:
+    steps:
+      - <<: *checkout
+      - run: make`,
+			expected: `x-checkout: &checkout
+  uses: actions/checkout@` + checkoutSHA + ` # v4.2.2
+  with:
+    fetch-depth: 0
+jobs:
+  build:
+    steps:
+      - <<: *checkout
+      - run: make`,
+			warning: []string{"line=8", "alias=*checkout", "action=actions/checkout@v4", "anchor_line=2"},
+		},
+		{
+			name: "Merge key of an anchored step",
+			input: `steps:
+  - &checkout
+    name: Checkout
+    uses: actions/checkout@v4 # checkout
+  - <<: *checkout
+    with:
+      fetch-depth: 0`,
+			expected: `steps:
+  - &checkout
+    name: Checkout
+    uses: actions/checkout@` + checkoutSHA + ` # v4.2.2 # checkout
+  - <<: *checkout
+    with:
+      fetch-depth: 0`,
+			warning: []string{"line=5", "alias=*checkout", "anchor_line=4"},
+		},
+		{
+			name: "Anchored uses value",
+			input: `steps:
+  - uses: &checkout actions/checkout@v4
+  - uses: *checkout`,
+			expected: `steps:
+  - uses: &checkout actions/checkout@` + checkoutSHA + ` # v4.2.2
+  - uses: *checkout`,
+			warning: []string{"line=3", "alias=*checkout", "anchor_line=2"},
+		},
+		{
+			name: "Pinned anchors are not reported",
+			input: `x-checkout: &checkout
+  uses: actions/checkout@` + checkoutSHA + ` # v4.2.2
+steps:
+  - <<: *checkout`,
+			expected: `x-checkout: &checkout
+  uses: actions/checkout@` + checkoutSHA + ` # v4.2.2
+steps:
+  - <<: *checkout`,
+		},
+		{
+			name: "Anchors without uses are not reported",
+			input: `x-env: &env
+  GOFLAGS: -mod=mod
+steps:
+  - uses: actions/checkout@v4
+    env:
+      <<: *env`,
+			expected: `x-env: &env
+  GOFLAGS: -mod=mod
+steps:
+  - uses: actions/checkout@` + checkoutSHA + ` # v4.2.2
+    env:
+      <<: *env`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			defer slog.SetDefault(slog.Default())
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+			p := &Pin{
+				resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+					"actions/checkout@v4": {CommitSHA: checkoutSHA, RefComment: "v4.2.2"},
+				}},
+			}
+			got, _, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+
+			if tt.warning == nil {
+				assert.Empty(t, logs.String())
+				return
+			}
+			assert.Contains(t, logs.String(), "defined via a YAML anchor")
+			for _, s := range tt.warning {
+				assert.Contains(t, logs.String(), s)
+			}
+		})
+	}
+}
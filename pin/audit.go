@@ -0,0 +1,74 @@
+package pin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+// Audit reports the actions pinned to commit SHAs that an allowlist doesn't approve, catching pins that drifted from
+// the approved SHAs and actions added without approval. It never calls the GitHub API.
+type Audit struct {
+	allowlist *Allowlist
+}
+
+// AuditViolation is an action pinned to a commit SHA that isn't on the allowlist.
+type AuditViolation struct {
+	File string
+	Line int // 1-based
+	// The action as written, without the ref: owner/repo or owner/repo/path.
+	Action string
+	SHA    string
+}
+
+// NewAudit creates an audit command checking pinned SHAs against allowlist.
+func NewAudit(allowlist *Allowlist) Audit {
+	return Audit{allowlist: allowlist}
+}
+
+// Scan reports every action of input pinned to a commit SHA that the allowlist doesn't approve for its repository.
+// Each finding's Message is owner/repo[/path]@sha. Actions that aren't pinned are not audited, see Pin.Check.
+func (a *Audit) Scan(_ context.Context, input string) ([]rewrite.Finding, error) {
+	var findings []rewrite.Finding
+	var scope lineScope
+	for i, line := range strings.Split(input, "\n") {
+		if !scope.next(line) {
+			continue
+		}
+		parsed, ok := parseLine(line)
+		if !ok || !parsed.def.HasCommitSHA() {
+			continue
+		}
+		if a.allowlist.Allows(parsed.def.Owner, parsed.def.Repo, parsed.def.RefOrSHA) {
+			continue
+		}
+		findings = append(findings, rewrite.Finding{
+			Line:    i + 1,
+			Message: parsed.def.String(),
+		})
+	}
+	return findings, nil
+}
+
+// Report converts the findings of Scan to violations, in the same order.
+func (a *Audit) Report(findings []rewrite.Finding) []AuditViolation {
+	violations := make([]AuditViolation, 0, len(findings))
+	for _, finding := range findings {
+		def, ok := parseActionRef(finding.Message)
+		if !ok {
+			continue
+		}
+		action := def.Owner + "/" + def.Repo
+		if def.Path != "" {
+			action += "/" + def.Path
+		}
+		violations = append(violations, AuditViolation{
+			File:   finding.Path,
+			Line:   finding.Line,
+			Action: action,
+			SHA:    def.RefOrSHA,
+		})
+	}
+	return violations
+}
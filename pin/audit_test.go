@@ -0,0 +1,47 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+func TestAudit(t *testing.T) {
+	const approved = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	const unapproved = "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b"
+	allowlist, err := ParseAllowlist([]byte(`
+actions/checkout:
+  - ` + approved + `
+`))
+	require.NoError(t, err)
+
+	input := `steps:
+  - uses: actions/checkout@` + approved + ` # v4.2.2
+  - uses: actions/checkout@` + unapproved + ` # v3.6.0
+  - uses: Actions/Checkout@` + approved + `
+  - uses: actions/setup-go@v5
+  - uses: org/monorepo/sub@` + approved + `
+  - uses: ./local
+  - run: echo
+    with:
+      uses: not/an@` + unapproved + `
+  # - uses: actions/checkout@` + unapproved
+
+	audit := NewAudit(allowlist)
+	findings, err := audit.Scan(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, []rewrite.Finding{
+		{Line: 3, Message: "actions/checkout@" + unapproved},
+		{Line: 6, Message: "org/monorepo/sub@" + approved},
+	}, findings)
+
+	findings[0].Path = "ci.yml"
+	assert.Equal(t, []AuditViolation{
+		{File: "ci.yml", Line: 3, Action: "actions/checkout", SHA: unapproved},
+		{Line: 6, Action: "org/monorepo/sub", SHA: approved},
+	}, audit.Report(findings))
+}
@@ -0,0 +1,119 @@
+package pin
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// CommentTemplate renders the comment written after a pinned commit SHA, e.g. "pin@{{.RefComment}}" writes
+// `# pin@v4.1.1`. The template is executed with a CommentData.
+type CommentTemplate struct {
+	tmpl *template.Template
+}
+
+// CommentData is the data a CommentTemplate is executed with.
+type CommentData struct {
+	// The resolved ref, e.g. v4.1.1. This is what the default comment contains.
+	RefComment string
+	Owner      string
+	Repo       string
+	// The ref the action was referenced with before pinning, e.g. v4.
+	Ref string
+	SHA string
+}
+
+// ParseCommentTemplate parses a --comment-template value. The template is executed once with sample data so that
+// references to unknown fields fail here rather than in the middle of a run.
+func ParseCommentTemplate(s string) (*CommentTemplate, error) {
+	tmpl, err := template.New("comment").Parse(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid comment template")
+	}
+	t := &CommentTemplate{tmpl: tmpl}
+	if _, err := t.render(CommentData{
+		RefComment: "v4.1.1",
+		Owner:      "actions",
+		Repo:       "checkout",
+		Ref:        "v4",
+		SHA:        "11bd71901bbe5b1630ceea73d27597364c9af683",
+	}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *CommentTemplate) render(data CommentData) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrap(err, "failed to render comment template")
+	}
+	comment := b.String()
+	if strings.ContainsAny(comment, "\r\n") {
+		return "", errors.Newf("comment template must render a single line, got %q", comment)
+	}
+	return strings.TrimSpace(comment), nil
+}
+
+var (
+	// versionMarkerPattern matches comment segments recording a version, such as v4, 4.1 or v4.1.1-rc.1, optionally
+	// followed by the resolution date of --comment-include-date (v4.1.1 @2025-01-02).
+	versionMarkerPattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+){0,2}([-+][0-9A-Za-z.+-]*)?( @[0-9]{4}-[0-9]{2}-[0-9]{2})?$`)
+	// dateMarkerPattern matches the resolution date written after the ref by --comment-include-date.
+	dateMarkerPattern = regexp.MustCompile(`^@[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+	// commentSeparatorPattern separates the segments of "# v4.1.1 # note". A "#" inside a note (e.g. "issue #12")
+	// is not a separator.
+	commentSeparatorPattern = regexp.MustCompile(`^#\s*|\s+#\s+`)
+)
+
+// mergeComment combines the generated version comment (without "# ") with the comment the line already had. A
+// segment of the existing comment that is itself a version marker (e.g. the "v4.0.0" of "# v4.0.0 # note") is
+// replaced by the generated comment, leaving the other segments in place. Otherwise the existing comment is appended
+// after the generated one. The result includes the leading "# ", or is empty when there is no comment at all.
+func mergeComment(generated, existing string) string {
+	if existing == "" {
+		if generated == "" {
+			return ""
+		}
+		return "# " + generated
+	}
+
+	segments := commentSeparatorPattern.Split(existing, -1)
+	if len(segments) > 0 && segments[0] == "" {
+		segments = segments[1:]
+	}
+	for i, segment := range segments {
+		if !versionMarkerPattern.MatchString(strings.TrimSpace(segment)) {
+			continue
+		}
+		merged := make([]string, 0, len(segments))
+		merged = append(merged, segments[:i]...)
+		if generated != "" {
+			merged = append(merged, generated)
+		}
+		merged = append(merged, segments[i+1:]...)
+		if len(merged) == 0 {
+			return ""
+		}
+		return "# " + strings.Join(merged, " # ")
+	}
+
+	if generated == "" {
+		return existing
+	}
+	return "# " + generated + " " + existing
+}
+
+// dateMarker returns the resolution date written after the ref by --comment-include-date, e.g. "@2025-01-02".
+func dateMarker(now time.Time) string {
+	return "@" + now.UTC().Format(time.DateOnly)
+}
+
+// hasDateMarker reports whether a ref comment ("# v4.1.1 @2025-01-02") records the resolution date.
+func hasDateMarker(comment string) bool {
+	fields := strings.Fields(strings.TrimPrefix(comment, "#"))
+	return len(fields) >= 2 && dateMarkerPattern.MatchString(fields[1])
+}
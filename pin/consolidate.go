@@ -0,0 +1,160 @@
+package pin
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+// Consolidate suggests, for each action used at several versions across workflows (e.g. v4, v4.1 and v4.1.1), the
+// single version all occurrences could be pinned to, to help teams standardize.
+type Consolidate struct {
+	resolver Resolver
+}
+
+// ConsolidateOptions configures how Consolidate resolves actions.
+type ConsolidateOptions struct {
+	// Fail instead of falling back to GitHub.com when the primary API returns 404.
+	FailOnFallback bool
+	// Total number of API retries allowed across the whole run. Zero or negative means unlimited.
+	RetryBudget int
+}
+
+// ConsolidationEntry is the consolidation suggestion of one owner/repo used with several refs.
+type ConsolidationEntry struct {
+	Owner string
+	Repo  string
+	// The distinct refs as written in the workflows, sorted. Pinned references count as the version of their comment.
+	Refs []string
+	// The highest tag satisfying every ref and the commit it resolves to. Empty when the refs have no common version,
+	// e.g. v3 and v4, or a branch.
+	Version   string
+	CommitSHA string
+}
+
+// NewConsolidate creates a consolidation report command with primary GitHub client and optional fallback GitHub.com
+// client.
+func NewConsolidate(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts ConsolidateOptions) Consolidate {
+	return Consolidate{
+		resolver: newVersionResolver(primaryClient, fallbackClient, ProviderGitHub, opts.RetryBudget, pin.RetryOptions{}, pin.ResolverOptions{
+			FailOnFallback: opts.FailOnFallback,
+		}),
+	}
+}
+
+// Scan reports every remote action reference in input. Each finding's Message is owner/repo@ref, where the ref of a
+// pinned reference is the version of its comment when it has one.
+func (c *Consolidate) Scan(_ context.Context, input string) ([]rewrite.Finding, error) {
+	var findings []rewrite.Finding
+	var scope lineScope
+	for i, line := range strings.Split(input, "\n") {
+		if !scope.next(line) {
+			continue
+		}
+		parsed, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		ref := parsed.def.RefOrSHA
+		if parsed.def.HasCommitSHA() {
+			if version, _, ok := splitRefComment(parsed.comment); ok && (pin.ActionDef{RefOrSHA: version}).VersionTag() != nil {
+				ref = version
+			}
+		}
+		findings = append(findings, rewrite.Finding{
+			Line:    i + 1,
+			Message: parsed.def.Owner + "/" + parsed.def.Repo + "@" + ref,
+		})
+	}
+	return findings, nil
+}
+
+// Report groups findings (as returned by Scan) by owner/repo and returns an entry for each action used with more than
+// one ref, sorted by owner and repo. Actions failing to resolve are reported in the error while the others are
+// returned.
+func (c *Consolidate) Report(ctx context.Context, findings []rewrite.Finding) ([]ConsolidationEntry, error) {
+	refs := make(map[string][]string)
+	for _, finding := range findings {
+		def, ok := parseActionRef(finding.Message)
+		if !ok {
+			continue
+		}
+		key := def.Owner + "/" + def.Repo
+		if !slices.Contains(refs[key], def.RefOrSHA) {
+			refs[key] = append(refs[key], def.RefOrSHA)
+		}
+	}
+
+	var entries []ConsolidationEntry
+	var errs []error
+	for key, actionRefs := range refs {
+		if len(actionRefs) < 2 {
+			continue
+		}
+		slices.Sort(actionRefs)
+		owner, repo, _ := strings.Cut(key, "/")
+		entry := ConsolidationEntry{Owner: owner, Repo: repo, Refs: actionRefs}
+		if version, ok := commonVersion(actionRefs); ok {
+			def := pin.ActionDef{Owner: owner, Repo: repo, RefOrSHA: version}
+			resolved, err := c.resolver.ResolveVersion(ctx, def)
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "failed to resolve %s", def.String()))
+				continue
+			}
+			entry.Version = resolved.RefComment
+			entry.CommitSHA = resolved.CommitSHA
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b ConsolidationEntry) int {
+		return cmp.Or(cmp.Compare(a.Owner, b.Owner), cmp.Compare(a.Repo, b.Repo))
+	})
+	if len(errs) > 0 {
+		return entries, errors.Join(errs...)
+	}
+	return entries, nil
+}
+
+// commonVersion returns the most specific of refs when every other ref is a prefix of it (v4 and v4.1 of v4.1.1), so
+// that resolving it gives the highest version satisfying them all. Refs that are not versions have no common version.
+func commonVersion(refs []string) (string, bool) {
+	type versionRef struct {
+		ref       string
+		version   *semver.Version
+		precision int
+	}
+	var versions []versionRef
+	for _, ref := range refs {
+		def := pin.ActionDef{RefOrSHA: ref}
+		if def.HasCommitSHA() {
+			return "", false
+		}
+		v := def.VersionTag()
+		if v == nil {
+			return "", false
+		}
+		versions = append(versions, versionRef{ref: ref, version: v, precision: min(len(strings.Split(v.Original(), ".")), 3)})
+	}
+	if len(versions) == 0 {
+		return "", false
+	}
+
+	most := slices.MaxFunc(versions, func(a, b versionRef) int { return cmp.Compare(a.precision, b.precision) })
+	for _, v := range versions {
+		if v.version.Major() != most.version.Major() ||
+			(v.precision >= 2 && v.version.Minor() != most.version.Minor()) ||
+			(v.precision >= 3 && !v.version.Equal(most.version)) {
+			return "", false
+		}
+	}
+	return most.ref, true
+}
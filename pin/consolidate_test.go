@@ -0,0 +1,93 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		refs   []string
+		want   string
+		wantOK bool
+	}{
+		{name: "prefixes of the most specific ref", refs: []string{"v4", "v4.1", "v4.1.1"}, want: "v4.1.1", wantOK: true},
+		{name: "major and minor", refs: []string{"v4", "v4.2"}, want: "v4.2", wantOK: true},
+		{name: "same version with and without v", refs: []string{"4.1.1", "v4.1.1"}, want: "4.1.1", wantOK: true},
+		{name: "different majors", refs: []string{"v3", "v4"}},
+		{name: "different minors", refs: []string{"v4", "v4.1", "v4.2"}},
+		{name: "different patches", refs: []string{"v4.1.1", "v4.1.2"}},
+		{name: "branch", refs: []string{"main", "v4"}},
+		{name: "unresolved commit SHA", refs: []string{"11bd71901bbe5b1630ceea73d27597364c9af683", "v4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := commonVersion(tt.refs)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	input := `jobs:
+  build:
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/checkout@v4.1
+      - uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 # v4.1.1
+      - uses: actions/setup-go@v4
+      - uses: actions/setup-go@v5
+      - uses: github/codeql-action/init@v3
+      - uses: github/codeql-action/analyze@v3.28
+      - uses: actions/cache@v4
+      - uses: actions/cache@v4`
+
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4.1.1":    {CommitSHA: "b4ffde65f46336ab88eb53be808477a3936bae11", RefComment: "v4.1.1"},
+		"github/codeql-action@v3.28": {CommitSHA: "45775bd8235c68ba998cffa5171334d58593da47", RefComment: "v3.28.15"},
+	}}
+	c := &Consolidate{resolver: resolver}
+
+	findings, err := c.Scan(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, findings, 9)
+	assert.Equal(t, "actions/checkout@v4.1.1", findings[2].Message, "pinned references count as their comment version")
+
+	entries, err := c.Report(context.Background(), findings)
+	require.NoError(t, err)
+	assert.Equal(t, []ConsolidationEntry{
+		{
+			Owner: "actions", Repo: "checkout", Refs: []string{"v4", "v4.1", "v4.1.1"},
+			Version: "v4.1.1", CommitSHA: "b4ffde65f46336ab88eb53be808477a3936bae11",
+		},
+		{Owner: "actions", Repo: "setup-go", Refs: []string{"v4", "v5"}},
+		{
+			Owner: "github", Repo: "codeql-action", Refs: []string{"v3", "v3.28"},
+			Version: "v3.28.15", CommitSHA: "45775bd8235c68ba998cffa5171334d58593da47",
+		},
+	}, entries, "actions used with a single ref are not reported")
+}
+
+func TestConsolidate_ReportContinuesOnError(t *testing.T) {
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4.1": {CommitSHA: "b4ffde65f46336ab88eb53be808477a3936bae11", RefComment: "v4.1.7"},
+	}}
+	c := &Consolidate{resolver: resolver}
+
+	findings, err := c.Scan(context.Background(), `- uses: actions/checkout@v4
+- uses: actions/checkout@v4.1
+- uses: org/missing@v1
+- uses: org/missing@v1.2`)
+	require.NoError(t, err)
+
+	entries, err := c.Report(context.Background(), findings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "org/missing@v1.2")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "v4.1.7", entries[0].Version)
+}
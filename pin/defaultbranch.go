@@ -0,0 +1,40 @@
+package pin
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+// refLessUsesPattern matches a `uses:` line referencing an action without `@ref`, e.g. `uses: actions/checkout`. Such
+// references are invalid in workflows but show up in hand-written files; with AssumeDefaultBranch they are pinned to
+// the default branch of the repository. The groups are those of usesPattern without the `@` and the ref. Local
+// actions (`./path`) and Docker images (`docker://image`) never match.
+var refLessUsesPattern = regexp.MustCompile(`^([-\s]*(?:["']?uses["']?:\s+)(?:&[^\s\[\]{},]+\s+)?)(["']?)([^/@"'\s#.:][^/@"'\s#:]*)/([^/@"'\s#]+)(/[^\s"'@]+)?(["']?)((?:\s.*)?)$`)
+
+// parseRefLessLine parses a `uses:` line without a ref, see refLessUsesPattern. The parsed reference has an empty
+// RefOrSHA, which the resolver treats as the default branch.
+func parseRefLessLine(line string) (parsedLine, bool) {
+	matches := refLessUsesPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return parsedLine{}, false
+	}
+
+	comment := ""
+	if commentIdx := strings.Index(matches[7], "#"); commentIdx >= 0 {
+		comment = strings.TrimSpace(matches[7][commentIdx:])
+	}
+	return parsedLine{
+		def: pin.ActionDef{
+			Owner: matches[3],
+			Repo:  matches[4],
+			Path:  strings.TrimPrefix(matches[5], "/"),
+		},
+		prefix:        matches[1],
+		openQuote:     matches[2],
+		closeQuote:    matches[6],
+		comment:       comment,
+		trailingSpace: line[len(strings.TrimRight(line, " \t")):],
+	}, true
+}
@@ -0,0 +1,82 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssumeDefaultBranch(t *testing.T) {
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@": {
+			CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+			RefComment: "main",
+			WasBranch:  true,
+		},
+		"org/monorepo/sub@": {
+			CommitSHA:  "44c2b7a8a4ea60a981eaca3cf939b5f4305c123b",
+			RefComment: "trunk",
+			WasBranch:  true,
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Missing ref",
+			input:    `      - uses: actions/checkout`,
+			expected: `      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # main`,
+		},
+		{
+			name:     "Missing ref with path, quotes and a comment",
+			input:    `        uses: "org/monorepo/sub" # note`,
+			expected: `        uses: "org/monorepo/sub@44c2b7a8a4ea60a981eaca3cf939b5f4305c123b" # trunk # note`,
+		},
+		{
+			name:     "Local action",
+			input:    `      - uses: ./.github/actions/setup`,
+			expected: `      - uses: ./.github/actions/setup`,
+		},
+		{
+			name:     "Docker image",
+			input:    `      - uses: docker://alpine`,
+			expected: `      - uses: docker://alpine`,
+		},
+		{
+			name:     "Commented out",
+			input:    `      # - uses: actions/checkout`,
+			expected: `      # - uses: actions/checkout`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{resolver: resolver, assumeDefaultBranch: true}
+			got, _, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("Check reports references without ref", func(t *testing.T) {
+		p := &Pin{resolver: resolver, assumeDefaultBranch: true}
+		findings, err := p.Check(context.Background(), "steps:\n  - uses: actions/checkout\n")
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, "actions/checkout", findings[0].Message)
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		p := &Pin{resolver: resolver}
+		input := `      - uses: actions/checkout`
+		got, changed, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, input, got)
+	})
+}
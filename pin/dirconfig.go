@@ -0,0 +1,226 @@
+package pin
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-yaml"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+// DirConfigFileName is the name of the per-directory configuration files, see DirConfigs.
+const DirConfigFileName = ".gha-fix.yaml"
+
+// DirConfig holds the settings of a per-directory configuration file, under its `pin:` section:
+//
+//	pin:
+//	  ignore-owners: [team-a-org]
+//	  comment-template: "pin@{{.RefComment}}"
+//
+// Only settings selecting which actions are pinned and how their comment is written can vary per directory;
+// resolution settings (e.g. prefer or allow-prerelease) apply to the whole run since resolutions are shared between
+// files. Nil fields are unset and inherit their value.
+type DirConfig struct {
+	IgnoreOwners             *[]string `yaml:"ignore-owners"`
+	IgnoreRepos              *[]string `yaml:"ignore-repos"`
+	OnlyOwners               *[]string `yaml:"only-owners"`
+	OnlyRepos                *[]string `yaml:"only-repos"`
+	StrictPinning202508      *bool     `yaml:"strict-pinning-202508"`
+	ExcludeReusableWorkflows *bool     `yaml:"exclude-reusable-workflows"`
+	CommentTemplate          *string   `yaml:"comment-template"`
+	NoComment                *bool     `yaml:"no-comment"`
+	NoCommentOnBranchRefs    *bool     `yaml:"no-comment-on-branch-refs"`
+	CommentMatchRefPrefix    *bool     `yaml:"comment-match-ref-prefix"`
+	CommentIncludeDate       *bool     `yaml:"comment-include-date"`
+}
+
+// ParseDirConfig parses a per-directory configuration file. Unknown keys fail, so that typos and settings that can't
+// vary per directory aren't silently ignored, as do invalid name patterns and comment templates.
+func ParseDirConfig(b []byte) (DirConfig, error) {
+	var file struct {
+		Pin DirConfig `yaml:"pin"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(b), yaml.DisallowUnknownField()).Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return DirConfig{}, errors.WithStack(err)
+	}
+	c := file.Pin
+	for _, list := range []*[]string{c.IgnoreOwners, c.IgnoreRepos, c.OnlyOwners, c.OnlyRepos} {
+		if list == nil {
+			continue
+		}
+		if err := ValidateNamePatterns(*list); err != nil {
+			return DirConfig{}, err
+		}
+	}
+	if c.CommentTemplate != nil && *c.CommentTemplate != "" {
+		if _, err := ParseCommentTemplate(*c.CommentTemplate); err != nil {
+			return DirConfig{}, err
+		}
+	}
+	return c, nil
+}
+
+// LoadDirConfig reads and parses the per-directory configuration file at path. See ParseDirConfig.
+func LoadDirConfig(path string) (DirConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return DirConfig{}, errors.Wrapf(err, "failed to read config: %s", path)
+	}
+	c, err := ParseDirConfig(b)
+	if err != nil {
+		return DirConfig{}, errors.Wrapf(err, "invalid config: %s", path)
+	}
+	return c, nil
+}
+
+// merge returns c with the settings set in nearer replacing its own. Lists are replaced, not appended to, so that a
+// subtree can also pin actions ignored elsewhere.
+func (c DirConfig) merge(nearer DirConfig) DirConfig {
+	override(&c.IgnoreOwners, nearer.IgnoreOwners)
+	override(&c.IgnoreRepos, nearer.IgnoreRepos)
+	override(&c.OnlyOwners, nearer.OnlyOwners)
+	override(&c.OnlyRepos, nearer.OnlyRepos)
+	override(&c.StrictPinning202508, nearer.StrictPinning202508)
+	override(&c.ExcludeReusableWorkflows, nearer.ExcludeReusableWorkflows)
+	override(&c.CommentTemplate, nearer.CommentTemplate)
+	override(&c.NoComment, nearer.NoComment)
+	override(&c.NoCommentOnBranchRefs, nearer.NoCommentOnBranchRefs)
+	override(&c.CommentMatchRefPrefix, nearer.CommentMatchRefPrefix)
+	override(&c.CommentIncludeDate, nearer.CommentIncludeDate)
+	return c
+}
+
+// override sets *dst to src when src is set.
+func override[T any](dst **T, src *T) {
+	if src != nil {
+		*dst = src
+	}
+}
+
+// WithDirConfig returns a copy of p with the settings of c replacing its own. The copy shares the resolver and caches
+// of p. NoComment, whether set by c or inherited, takes precedence over CommentTemplate.
+func (p *Pin) WithDirConfig(c DirConfig) (*Pin, error) {
+	cp := *p
+	if c.IgnoreOwners != nil {
+		cp.ignoreOwners = newNamePatterns(*c.IgnoreOwners)
+	}
+	if c.IgnoreRepos != nil {
+		cp.ignoreRepos = newNamePatterns(*c.IgnoreRepos)
+	}
+	if c.OnlyOwners != nil {
+		cp.onlyOwners = newNamePatterns(*c.OnlyOwners)
+	}
+	if c.OnlyRepos != nil {
+		cp.onlyRepos = newNamePatterns(*c.OnlyRepos)
+	}
+	if c.CommentTemplate != nil {
+		cp.commentTemplate = nil
+		if *c.CommentTemplate != "" {
+			t, err := ParseCommentTemplate(*c.CommentTemplate)
+			if err != nil {
+				return nil, err
+			}
+			cp.commentTemplate = t
+		}
+	}
+	setBool := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setBool(&cp.strictPinning202508, c.StrictPinning202508)
+	setBool(&cp.excludeReusableWorkflows, c.ExcludeReusableWorkflows)
+	setBool(&cp.noComment, c.NoComment)
+	setBool(&cp.noBranchComment, c.NoCommentOnBranchRefs)
+	setBool(&cp.matchRefPrefix, c.CommentMatchRefPrefix)
+	setBool(&cp.commentDate, c.CommentIncludeDate)
+	return &cp, nil
+}
+
+// DirConfigs finds the per-directory configuration files (DirConfigFileName) applying to workflow files: those in
+// the directory of the file and its parents, up to a root directory. Each file is read once. It is safe for
+// concurrent use.
+type DirConfigs struct {
+	root string
+	mu   sync.Mutex
+	// Parsed file of each directory looked up, nil when the directory has none.
+	dirs map[string]*dirConfigFile
+}
+
+type dirConfigFile struct {
+	config DirConfig
+	err    error
+}
+
+// NewDirConfigs creates a DirConfigs looking up configuration files from root down to the directories of workflows.
+func NewDirConfigs(root string) *DirConfigs {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &DirConfigs{root: root, dirs: make(map[string]*dirConfigFile)}
+}
+
+// For returns the configuration applying to the workflow file at path: the files from the root directory down to
+// the directory of path merged in that order, so that the nearest file wins for each setting it sets. ok is false
+// when no file applies, including for files outside the root directory and stdin.
+func (d *DirConfigs) For(path string) (c DirConfig, ok bool, err error) {
+	if path == rewrite.StdioPath {
+		return DirConfig{}, false, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return DirConfig{}, false, errors.WithStack(err)
+	}
+	rel, err := filepath.Rel(d.root, filepath.Dir(abs))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return DirConfig{}, false, nil
+	}
+
+	dir := d.root
+	dirs := []string{dir}
+	if rel != "." {
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			dir = filepath.Join(dir, name)
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, dir := range dirs {
+		file, err := d.load(dir)
+		if err != nil {
+			return DirConfig{}, false, err
+		}
+		if file != nil {
+			c, ok = c.merge(file.config), true
+		}
+	}
+	return c, ok, nil
+}
+
+// load returns the parsed configuration file of dir, or nil when it has none.
+func (d *DirConfigs) load(dir string) (*dirConfigFile, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file, loaded := d.dirs[dir]
+	if !loaded {
+		path := filepath.Join(dir, DirConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			slog.Debug("loading per-directory config", "path", path)
+			config, err := LoadDirConfig(path)
+			file = &dirConfigFile{config: config, err: err}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			file = &dirConfigFile{err: errors.WithStack(err)}
+		}
+		d.dirs[dir] = file
+	}
+	if file != nil && file.err != nil {
+		return nil, file.err
+	}
+	return file, nil
+}
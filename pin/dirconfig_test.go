@@ -0,0 +1,154 @@
+package pin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirConfig(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		c, err := ParseDirConfig([]byte("pin:\n  ignore-owners: [org-a, 'team-*']\n  no-comment: false\n"))
+		require.NoError(t, err)
+		require.NotNil(t, c.IgnoreOwners)
+		assert.Equal(t, []string{"org-a", "team-*"}, *c.IgnoreOwners)
+		require.NotNil(t, c.NoComment)
+		assert.False(t, *c.NoComment)
+		assert.Nil(t, c.IgnoreRepos, "unset settings stay nil")
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		c, err := ParseDirConfig(nil)
+		require.NoError(t, err)
+		assert.Equal(t, DirConfig{}, c)
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		_, err := ParseDirConfig([]byte("pin:\n  prefer: branches\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := ParseDirConfig([]byte("pin:\n  ignore-repos: ['/[/']\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid comment template", func(t *testing.T) {
+		_, err := ParseDirConfig([]byte("pin:\n  comment-template: '{{.Unknown}}'\n"))
+		require.Error(t, err)
+	})
+}
+
+func TestDirConfigs(t *testing.T) {
+	root := t.TempDir()
+	writeDirConfig := func(dir, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, dir, DirConfigFileName), []byte(content), 0o644))
+	}
+	writeDirConfig(".", "pin:\n  ignore-owners: [org-x]\n  comment-include-date: true\n")
+	writeDirConfig("team-a", "pin:\n  ignore-repos: [org-y/legacy]\n")
+	writeDirConfig("team-b", "pin:\n  ignore-owners: []\n")
+	writeDirConfig("team-b/sub", "pin:\n  comment-include-date: false\n")
+	writeDirConfig("broken", "pin:\n  unknown: true\n")
+
+	configs := NewDirConfigs(root)
+	forPath := func(path string) (DirConfig, bool) {
+		c, ok, err := configs.For(filepath.Join(root, path))
+		require.NoError(t, err)
+		return c, ok
+	}
+
+	t.Run("Root file applies everywhere", func(t *testing.T) {
+		c, ok := forPath(".github/workflows/ci.yml")
+		require.True(t, ok)
+		assert.Equal(t, []string{"org-x"}, *c.IgnoreOwners)
+		assert.True(t, *c.CommentIncludeDate)
+		assert.Nil(t, c.IgnoreRepos)
+	})
+
+	t.Run("Nested file adds settings", func(t *testing.T) {
+		c, ok := forPath("team-a/.github/workflows/ci.yml")
+		require.True(t, ok)
+		assert.Equal(t, []string{"org-x"}, *c.IgnoreOwners)
+		assert.Equal(t, []string{"org-y/legacy"}, *c.IgnoreRepos)
+	})
+
+	t.Run("Nearest file wins", func(t *testing.T) {
+		c, ok := forPath("team-b/sub/ci.yml")
+		require.True(t, ok)
+		assert.Empty(t, *c.IgnoreOwners, "team-b clears the owners ignored at the root")
+		assert.False(t, *c.CommentIncludeDate, "team-b/sub overrides the root")
+
+		c, ok = forPath("team-b/ci.yml")
+		require.True(t, ok)
+		assert.True(t, *c.CommentIncludeDate, "siblings and parents are unaffected")
+	})
+
+	t.Run("Relative paths", func(t *testing.T) {
+		t.Chdir(root)
+		c, ok, err := NewDirConfigs(".").For("team-a/ci.yml")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, []string{"org-y/legacy"}, *c.IgnoreRepos)
+	})
+
+	t.Run("Outside the root", func(t *testing.T) {
+		_, ok, err := NewDirConfigs(filepath.Join(root, "team-a")).For(filepath.Join(root, "team-b", "ci.yml"))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		_, ok, err := configs.For("-")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Invalid file fails", func(t *testing.T) {
+		_, _, err := configs.For(filepath.Join(root, "broken", "ci.yml"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), filepath.Join("broken", DirConfigFileName))
+	})
+}
+
+func TestPin_WithDirConfig(t *testing.T) {
+	input := `steps:
+  - uses: org-x/action@v1
+  - uses: actions/checkout@v4`
+
+	base := &Pin{
+		ignoreOwners: newNamePatterns([]string{"org-x"}),
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"org-x/action@v1": {
+				CommitSHA:  "aa0779029b74112dc82b436546da0706a57323ad",
+				RefComment: "v1.0.0",
+			},
+			"actions/checkout@v4": {
+				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment: "v4.2.2",
+			},
+		}},
+	}
+
+	c, err := ParseDirConfig([]byte("pin:\n  ignore-owners: [actions]\n  comment-template: 'pin@{{.RefComment}}'\n"))
+	require.NoError(t, err)
+	overridden, err := base.WithDirConfig(c)
+	require.NoError(t, err)
+
+	got, _, err := overridden.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, `steps:
+  - uses: org-x/action@aa0779029b74112dc82b436546da0706a57323ad # pin@v1.0.0
+  - uses: actions/checkout@v4`, got)
+
+	// The original Pin is unchanged.
+	got, _, err = base.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, `steps:
+  - uses: org-x/action@v1
+  - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2`, got)
+}
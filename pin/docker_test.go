@@ -0,0 +1,120 @@
+package pin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finatext/gha-fix/internal/registry"
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+func TestPinDocker(t *testing.T) {
+	const digest = "sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"
+	digests := &mockDigestResolver{digests: map[string]string{
+		"ghcr.io/org/image:1.2.3":       digest,
+		"docker.io/library/alpine:3.19": digest,
+	}}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Registry image",
+			input:    `      - uses: docker://ghcr.io/org/image:1.2.3`,
+			expected: `      - uses: docker://ghcr.io/org/image@` + digest + ` # 1.2.3`,
+		},
+		{
+			name:     "Docker Hub image with quotes and a note",
+			input:    `      - uses: "docker://alpine:3.19" # base image`,
+			expected: `      - uses: "docker://alpine@` + digest + `" # 3.19 # base image`,
+		},
+		{
+			name:     "Already pinned to a digest",
+			input:    `      - uses: docker://ghcr.io/org/image@` + digest + ` # 1.2.3`,
+			expected: `      - uses: docker://ghcr.io/org/image@` + digest + ` # 1.2.3`,
+		},
+		{
+			name:     "Commented out",
+			input:    `      # - uses: docker://ghcr.io/org/image:1.2.3`,
+			expected: `      # - uses: docker://ghcr.io/org/image:1.2.3`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{digests: digests}
+			got, _, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		p := &Pin{}
+		input := `      - uses: docker://ghcr.io/org/image:1.2.3`
+		got, changed, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, input, got)
+	})
+
+	t.Run("Changes and findings", func(t *testing.T) {
+		p := &Pin{digests: digests}
+		input := "- uses: docker://ghcr.io/org/image:1.2.3\n- uses: docker://alpine@" + digest
+		_, changes, err := p.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		assert.Equal(t, []rewrite.Change{{
+			Line: 1, Owner: "ghcr.io", Repo: "org/image", FromRef: "1.2.3", ToSHA: digest, ResolvedComment: "1.2.3",
+		}}, changes)
+
+		findings, err := p.Check(context.Background(), input)
+		require.NoError(t, err)
+		assert.Equal(t, []rewrite.Finding{{Line: 1, Message: "docker://ghcr.io/org/image:1.2.3"}}, findings)
+	})
+
+	t.Run("Registry errors leave the line unchanged", func(t *testing.T) {
+		p := &Pin{digests: digests}
+		input := "- uses: docker://ghcr.io/org/missing:1\n- uses: docker://alpine:3.19"
+		got, changed, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "- uses: docker://ghcr.io/org/missing:1\n- uses: docker://alpine@"+digest+" # 3.19", got)
+	})
+
+	t.Run("Registry errors fail the line with failOnUnresolvable", func(t *testing.T) {
+		p := &Pin{digests: digests, failOnUnresolvable: true}
+		input := "- uses: docker://ghcr.io/org/missing:1"
+		got, changed, err := p.Apply(context.Background(), input)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "docker://ghcr.io/org/missing:1")
+		assert.False(t, changed)
+		assert.Equal(t, input, got)
+	})
+
+	t.Run("Digests are resolved once", func(t *testing.T) {
+		counting := &mockDigestResolver{digests: digests.digests}
+		p := &Pin{digests: newDigestCache(counting)}
+		_, _, err := p.Apply(context.Background(), "- uses: docker://alpine:3.19\n- uses: docker://alpine:3.19")
+		require.NoError(t, err)
+		assert.Equal(t, 1, counting.calls)
+	})
+}
+
+type mockDigestResolver struct {
+	digests map[string]string // registry/repository:tag -> digest
+	calls   int
+}
+
+func (m *mockDigestResolver) Digest(_ context.Context, ref registry.Reference) (string, error) {
+	m.calls++
+	if digest, ok := m.digests[ref.Registry+"/"+ref.Repository+":"+ref.Tag]; ok {
+		return digest, nil
+	}
+	return "", errors.New("manifest unknown")
+}
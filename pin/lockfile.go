@@ -0,0 +1,186 @@
+package pin
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-yaml"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+// DefaultLockfileName is the conventional name of the lockfile, kept at the repository root next to the workflows.
+const DefaultLockfileName = "gha-fix.lock"
+
+// lockfileHeader starts every saved lockfile.
+const lockfileHeader = "# Resolutions of gha-fix pin: owner/repo@ref -> commit SHA. Refresh with `gha-fix pin --update-lock`.\n"
+
+// Lockfile records what each owner/repo@ref resolved to, so that later runs pin the same commits without calling the
+// API, e.g. offline or to reproduce a build. It is a YAML mapping of owner/repo@ref to the resolution:
+//
+//	actions/checkout@v4:
+//	  sha: 11bd71901bbe5b1630ceea73d27597364c9af683
+//	  comment: v4.2.2
+//	octo-org/deploy@main:
+//	  sha: 0aaccfd150d50ccaeb58ebd88d36e91967a5f35b
+//	  comment: main
+//	  branch: true
+//
+// Entries are saved sorted by key, so that resolving the same refs yields the same file. It is safe for concurrent
+// use.
+type Lockfile struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]LockEntry
+	changed bool
+}
+
+// LockEntry is the resolution of an owner/repo@ref recorded in a Lockfile.
+type LockEntry struct {
+	SHA string `yaml:"sha"`
+	// The ref written in the comment after the SHA, e.g. v4.2.2 for v4.
+	Comment string `yaml:"comment,omitempty"`
+	// The ref is a branch, whose head moves; see ResolvedVersion.WasBranch.
+	Branch bool `yaml:"branch,omitempty"`
+}
+
+var lockedSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// LoadLockfile reads the lockfile at path. A missing file is an empty lockfile, created by Save.
+func LoadLockfile(path string) (*Lockfile, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Lockfile{path: path, entries: make(map[string]LockEntry)}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read lockfile: %s", path)
+	}
+	entries, err := parseLockfile(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid lockfile: %s", path)
+	}
+	return &Lockfile{path: path, entries: entries}, nil
+}
+
+func parseLockfile(b []byte) (map[string]LockEntry, error) {
+	var entries map[string]LockEntry
+	if err := yaml.Unmarshal(b, &entries); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if entries == nil {
+		entries = make(map[string]LockEntry)
+	}
+	for key, entry := range entries {
+		if def, ok := parseActionRef(key); !ok || def.Path != "" {
+			return nil, errors.Newf("invalid key %q: must be owner/repo@ref", key)
+		}
+		if !lockedSHAPattern.MatchString(entry.SHA) {
+			return nil, errors.Newf("invalid commit SHA %q for %s: must be a full lowercase 40-character SHA", entry.SHA, key)
+		}
+	}
+	return entries, nil
+}
+
+// Get returns the entry of owner/repo@ref, if recorded.
+func (l *Lockfile) Get(owner, repo, ref string) (LockEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[lockKey(owner, repo, ref)]
+	return entry, ok
+}
+
+// Set records the entry of owner/repo@ref, replacing any previous one.
+func (l *Lockfile) Set(owner, repo, ref string, entry LockEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := lockKey(owner, repo, ref)
+	if previous, ok := l.entries[key]; ok && previous == entry {
+		return
+	}
+	l.entries[key] = entry
+	l.changed = true
+}
+
+func lockKey(owner, repo, ref string) string {
+	return owner + "/" + repo + "@" + ref
+}
+
+// Save writes the lockfile atomically if entries were recorded or changed since it was loaded.
+func (l *Lockfile) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.changed {
+		return nil
+	}
+	keys := make([]string, 0, len(l.entries))
+	for key := range l.entries {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	b.WriteString(lockfileHeader)
+	for _, key := range keys {
+		out, err := yaml.Marshal(map[string]LockEntry{key: l.entries[key]})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		b.Write(out)
+	}
+	if err := rewrite.WriteFileAtomic(l.path, b.String()); err != nil {
+		return errors.Wrapf(err, "failed to write lockfile: %s", l.path)
+	}
+	l.changed = false
+	return nil
+}
+
+// lockfileResolver resolves owner/repo@ref from the lockfile when recorded, and otherwise with next, recording the
+// resolution. With update, every ref is resolved with next again, refreshing its entry.
+type lockfileResolver struct {
+	next   Resolver
+	lock   *Lockfile
+	update bool
+}
+
+func (r *lockfileResolver) ResolveVersion(ctx context.Context, def ActionDef) (ResolvedVersion, error) {
+	// Pinned and ref-less references aren't locked: there is nothing to resolve, or nothing to key the entry with.
+	if def.HasCommitSHA() || !def.HasRef() {
+		return r.next.ResolveVersion(ctx, def)
+	}
+	if !r.update {
+		if entry, ok := r.lock.Get(def.Owner, def.Repo, def.RefOrSHA); ok {
+			return ResolvedVersion{CommitSHA: entry.SHA, RefComment: entry.Comment, WasBranch: entry.Branch}, nil
+		}
+	}
+	resolved, err := r.next.ResolveVersion(ctx, def)
+	if err != nil {
+		return resolved, err
+	}
+	r.lock.Set(def.Owner, def.Repo, def.RefOrSHA, LockEntry{
+		SHA:     strings.ToLower(resolved.CommitSHA),
+		Comment: resolved.RefComment,
+		Branch:  resolved.WasBranch,
+	})
+	return resolved, nil
+}
+
+// VerifyCommit verifies commits with next, when it can, for Options.VerifyExistingSHA.
+func (r *lockfileResolver) VerifyCommit(ctx context.Context, owner, repo, sha string) error {
+	if verifier, ok := r.next.(commitVerifier); ok {
+		return verifier.VerifyCommit(ctx, owner, repo, sha)
+	}
+	return nil
+}
+
+// Stats returns the statistics of next, whose resolutions are those the lockfile missed.
+func (r *lockfileResolver) Stats() pin.ResolverStats {
+	if s, ok := r.next.(interface{ Stats() pin.ResolverStats }); ok {
+		return s.Stats()
+	}
+	return pin.ResolverStats{}
+}
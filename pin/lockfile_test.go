@@ -0,0 +1,112 @@
+package pin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	lockedSHA  = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	currentSHA = "d35c59abb061a4a6fb18e82ac0862c26744d6ab5"
+)
+
+func TestLockfile(t *testing.T) {
+	input := `steps:
+  - uses: actions/checkout@v4
+  - uses: actions/setup-go@v5
+  - uses: actions/cache@` + lockedSHA
+	newResolver := func() *countingResolver {
+		return &countingResolver{mockResolver: mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4": {CommitSHA: currentSHA, RefComment: "v4.3.0"},
+			"actions/setup-go@v5": {CommitSHA: currentSHA, RefComment: "v5.5.0"},
+		}}}
+	}
+	writeLockfile := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), DefaultLockfileName)
+		require.NoError(t, os.WriteFile(path, []byte(`actions/checkout@v4:
+  sha: `+lockedSHA+`
+  comment: v4.2.2
+`), 0o600))
+		return path
+	}
+
+	t.Run("Hits are pinned to the locked commit and misses are recorded", func(t *testing.T) {
+		path := writeLockfile(t)
+		lock, err := LoadLockfile(path)
+		require.NoError(t, err)
+		resolver := newResolver()
+		p := NewPinWithResolver(resolver, Options{Lockfile: lock})
+
+		got, _, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.Equal(t, `steps:
+  - uses: actions/checkout@`+lockedSHA+` # v4.2.2
+  - uses: actions/setup-go@`+currentSHA+` # v5.5.0
+  - uses: actions/cache@`+lockedSHA, got)
+		assert.Equal(t, map[string]int{"actions/setup-go@v5": 1}, resolver.calls, "only the miss is resolved")
+
+		require.NoError(t, p.SaveLockfile())
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, lockfileHeader+`actions/checkout@v4:
+  sha: `+lockedSHA+`
+  comment: v4.2.2
+actions/setup-go@v5:
+  sha: `+currentSHA+`
+  comment: v5.5.0
+`, string(b))
+	})
+
+	t.Run("Update resolves every ref again", func(t *testing.T) {
+		path := writeLockfile(t)
+		lock, err := LoadLockfile(path)
+		require.NoError(t, err)
+		p := NewPinWithResolver(newResolver(), Options{Lockfile: lock, UpdateLock: true})
+
+		got, _, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.Contains(t, got, "actions/checkout@"+currentSHA+" # v4.3.0")
+
+		require.NoError(t, p.SaveLockfile())
+		reloaded, err := LoadLockfile(path)
+		require.NoError(t, err)
+		entry, ok := reloaded.Get("actions", "checkout", "v4")
+		require.True(t, ok)
+		assert.Equal(t, LockEntry{SHA: currentSHA, Comment: "v4.3.0"}, entry)
+	})
+
+	t.Run("Missing lockfile is created", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), DefaultLockfileName)
+		lock, err := LoadLockfile(path)
+		require.NoError(t, err)
+		require.NoError(t, lock.Save(), "nothing to write")
+		assert.NoFileExists(t, path)
+
+		p := NewPinWithResolver(newResolver(), Options{Lockfile: lock})
+		_, _, err = p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		require.NoError(t, p.SaveLockfile())
+		reloaded, err := LoadLockfile(path)
+		require.NoError(t, err)
+		_, ok := reloaded.Get("actions", "setup-go", "v5")
+		assert.True(t, ok)
+	})
+
+	t.Run("Invalid lockfiles fail", func(t *testing.T) {
+		for _, content := range []string{
+			"actions/checkout:\n  sha: " + lockedSHA + "\n",
+			"actions/checkout@v4:\n  sha: 11bd719\n",
+			"actions/checkout@v4: [",
+		} {
+			path := filepath.Join(t.TempDir(), DefaultLockfileName)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			_, err := LoadLockfile(path)
+			assert.ErrorContains(t, err, "invalid lockfile", content)
+		}
+	})
+}
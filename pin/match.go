@@ -0,0 +1,91 @@
+package pin
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// namePattern matches an owner or owner/repo name of the ignore and only lists. A plain string matches exactly, a
+// string with *, ? or [ is a glob (path.Match syntax, e.g. myorg/*), and a string wrapped in slashes is a regular
+// expression (e.g. /^internal-/) matching anywhere in the name unless anchored.
+type namePattern struct {
+	raw  string
+	glob bool
+	re   *regexp.Regexp
+}
+
+func parseNamePattern(s string) (namePattern, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return namePattern{}, errors.Wrapf(err, "invalid regular expression %q", s)
+		}
+		return namePattern{raw: s, re: re}, nil
+	}
+	if strings.ContainsAny(s, "*?[") {
+		if _, err := path.Match(s, ""); err != nil {
+			return namePattern{}, errors.Wrapf(err, "invalid glob pattern %q", s)
+		}
+		return namePattern{raw: s, glob: true}, nil
+	}
+	return namePattern{raw: s}, nil
+}
+
+func (p namePattern) match(name string) bool {
+	switch {
+	case p.re != nil:
+		return p.re.MatchString(name)
+	case p.glob:
+		ok, _ := path.Match(p.raw, name)
+		return ok
+	default:
+		return p.raw == name
+	}
+}
+
+// namePatterns is a compiled ignore or only list.
+type namePatterns []namePattern
+
+// newNamePatterns compiles list. Invalid patterns, which ValidateNamePatterns reports, only match themselves exactly.
+func newNamePatterns(list []string) namePatterns {
+	patterns := make(namePatterns, 0, len(list))
+	for _, s := range list {
+		p, err := parseNamePattern(s)
+		if err != nil {
+			p = namePattern{raw: s}
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// ValidateNamePatterns reports the invalid glob and regular expression patterns of an ignore or only list.
+func ValidateNamePatterns(list []string) error {
+	var errs []error
+	for _, s := range list {
+		if _, err := parseNamePattern(s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n namePatterns) match(name string) bool {
+	for _, p := range n {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n namePatterns) String() string {
+	raw := make([]string, len(n))
+	for i, p := range n {
+		raw[i] = p.raw
+	}
+	return "[" + strings.Join(raw, " ") + "]"
+}
@@ -0,0 +1,104 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamePatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		match    []string
+		noMatch  []string
+	}{
+		{
+			name:     "Exact",
+			patterns: []string{"actions/checkout"},
+			match:    []string{"actions/checkout"},
+			noMatch:  []string{"actions/checkout-extra", "Actions/Checkout", "actions"},
+		},
+		{
+			name:     "Wildcard repo",
+			patterns: []string{"myorg/*"},
+			match:    []string{"myorg/build", "myorg/deploy-action"},
+			noMatch:  []string{"myorg", "other/build", "myorg-tools/build"},
+		},
+		{
+			name:     "Wildcard owner",
+			patterns: []string{"*/checkout"},
+			match:    []string{"actions/checkout", "myorg/checkout"},
+			noMatch:  []string{"actions/checkout-v2"},
+		},
+		{
+			name:     "Owner glob",
+			patterns: []string{"internal-?", "team-[ab]*"},
+			match:    []string{"internal-1", "team-alpha", "team-b"},
+			noMatch:  []string{"internal-12", "team-c"},
+		},
+		{
+			name:     "Regular expression",
+			patterns: []string{"/^internal-/"},
+			match:    []string{"internal-tools/build", "internal-x"},
+			noMatch:  []string{"myorg/internal-tools"},
+		},
+		{
+			name:     "Unanchored regular expression",
+			patterns: []string{"/-(deprecated|legacy)$/"},
+			match:    []string{"myorg/build-legacy", "other/x-deprecated"},
+			noMatch:  []string{"myorg/legacy-build"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, ValidateNamePatterns(tt.patterns))
+			patterns := newNamePatterns(tt.patterns)
+			for _, name := range tt.match {
+				assert.True(t, patterns.match(name), name)
+			}
+			for _, name := range tt.noMatch {
+				assert.False(t, patterns.match(name), name)
+			}
+		})
+	}
+
+	t.Run("Invalid patterns", func(t *testing.T) {
+		err := ValidateNamePatterns([]string{"ok/*", "/(unclosed/", "bad/[x"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `"/(unclosed/"`)
+		assert.Contains(t, err.Error(), `"bad/[x"`)
+		assert.NotContains(t, err.Error(), `"ok/*"`)
+	})
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	const sha = "abcdef1234567890abcdef1234567890abcdef12"
+	r := &Pin{
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+			"myorg/build@v1":      {CommitSHA: sha, RefComment: "v1.0.0"},
+			"other/deploy@v1":     {CommitSHA: sha, RefComment: "v1.0.0"},
+		}},
+		ignoreOwners: newNamePatterns([]string{"/^internal-/"}),
+		ignoreRepos:  newNamePatterns([]string{"myorg/*", "*/deploy"}),
+	}
+
+	input := `steps:
+  - uses: actions/checkout@v4
+  - uses: myorg/build@v1
+  - uses: other/deploy@v1
+  - uses: internal-tools/lint@v1`
+	expected := `steps:
+  - uses: actions/checkout@` + sha + ` # v4.2.2
+  - uses: myorg/build@v1
+  - uses: other/deploy@v1
+  - uses: internal-tools/lint@v1`
+
+	got, _, err := r.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
@@ -4,52 +4,355 @@ import (
 	"context"
 	"log/slog"
 	"regexp"
-	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/cockroachdb/errors"
 	gogithub "github.com/google/go-github/v72/github"
 
 	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/registry"
+	"github.com/Finatext/gha-fix/internal/rewrite"
 )
 
-type resolver interface {
-	ResolveVersion(ctx context.Context, def pin.ActionDef) (pin.ResolvedVersion, error)
-}
-
 type Pin struct {
-	resolver            resolver
-	ignoreOwners        []string
-	ignoreRepos         []string
+	resolver            Resolver
+	ignoreOwners        namePatterns
+	ignoreRepos         namePatterns
 	strictPinning202508 bool
+	// Allow-list scoping pinning to these owners and owner/repo names; both empty pin every action.
+	onlyOwners namePatterns
+	onlyRepos  namePatterns
+	// Leave reusable workflow references (org/repo/.github/workflows/x.yml@ref) on their original refs.
+	excludeReusableWorkflows bool
+	// Drop whitespace that trailed the original line when the line is rewritten.
+	stripTrailingWhitespace bool
+	// Warn when a rewritten line is longer than this many characters; zero disables the warning.
+	maxLineLength int
+	// Locate the `uses:` keys with a YAML parser instead of the line scanner alone, see usesLines.
+	yamlMode bool
+	// Pin references without `@ref` to the default branch of their repository, see parseRefLessLine.
+	assumeDefaultBranch bool
+	// Quoting of the `uses:` value of rewritten lines.
+	quoteStyle QuoteStyle
+	// Renders the comment after the commit SHA; nil writes the resolved ref as is.
+	commentTemplate *CommentTemplate
+	// Write no comment after the commit SHA, besides the comment the line already had.
+	noComment bool
+	// Write no comment after the commit SHA of branch resolutions, keeping it for tag resolutions.
+	noBranchComment bool
+	// Write the version of the comment with a "v" prefix only if the original ref had one.
+	matchRefPrefix bool
+	// Append the resolution date to the comment after the commit SHA, e.g. `# v4.1.1 @2025-01-02`.
+	commentDate bool
+	// Clock for the resolution date; nil means time.Now.
+	now func() time.Time
+	// What version refs are pinned to; empty means commit SHAs.
+	pinTarget PinTarget
+	// Fail the file on actions that can't be resolved instead of leaving their line unchanged with a warning.
+	failOnUnresolvable bool
+	// Pre-approved commit SHAs; nil disables the check.
+	allowlist *Allowlist
+	// Only warn, instead of failing the line, when a resolved SHA isn't on the allowlist.
+	allowlistWarnOnly bool
+	// Resolves `docker://` image tags to digests; nil leaves Docker images untouched.
+	digests digestResolver
+	// Persistent resolution cache shared with the resolver; nil when disabled.
+	diskCache *pin.DiskCache
+	// Verifies that commit SHAs already pinned exist; nil skips such lines without API calls.
+	commits commitVerifier
+	// Lowercase the hex digits of commit SHAs already pinned, see uppercaseSHA.
+	normalizeSHA bool
+	// Lockfile shared with the resolver; nil when disabled.
+	lockfile *Lockfile
 }
 
-// NewPin creates a pin command with primary GitHub client and optional fallback GitHub.com client.
-func NewPin(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, ignoreOwners, ignoreRepos []string, strictPinning202508 bool) Pin {
-	var fallbackRepos *gogithub.RepositoriesService
-	if fallbackClient != nil {
-		fallbackRepos = fallbackClient.Repositories
+// Options configures how Pin selects and resolves action references.
+type Options struct {
+	// Owners and owner/repo names to leave unpinned. Entries may be globs (myorg/*) or /regular expressions/; see
+	// ValidateNamePatterns.
+	IgnoreOwners []string
+	IgnoreRepos  []string
+	// Only pin actions of these owners or owner/repo names, leaving every other action as is. Applied before the
+	// ignore lists, which then exclude actions within the allow-list. Both empty pin every action.
+	OnlyOwners []string
+	OnlyRepos  []string
+	// Strict SHA pinning for new GitHub's SHA pinning enforcement policy. See README for details.
+	StrictPinning202508 bool
+	// Skip reusable workflow references entirely; only actions and composite actions are pinned.
+	ExcludeReusableWorkflows bool
+	// Resolve `git describe` style refs (e.g. v4.1.1-3-gabcdef0) to the embedded commit.
+	ResolveDescribe bool
+	// Fail instead of falling back to GitHub.com when the primary API returns 404.
+	FailOnFallback bool
+	// Also fall back to GitHub.com when the primary API denies access (403) instead of failing.
+	FallbackOnForbidden bool
+	// HTTP statuses of the primary API falling back to GitHub.com. Empty means 404 only. See
+	// pin.ResolverOptions.FallbackStatuses.
+	FallbackStatuses []int
+	// Rewrite owner/repo with the canonical casing reported by the API (costs one repository lookup per action).
+	CanonicalizeNames bool
+	// Require the minor version to match when resolving v0.x refs. See pin.ResolverOptions.V0Strict.
+	V0Strict bool
+	// Let version refs resolve to pre-release tags. See pin.ResolverOptions.AllowPrerelease.
+	AllowPrerelease bool
+	// Resolve numeric-only refs (e.g. 4) to the branch of that name when it exists. See pin.ResolverOptions.PreferBranches.
+	PreferBranches bool
+	// Resolve the ref latest to the newest release tag across all majors. See pin.ResolverOptions.ResolveLatest.
+	ResolveLatest bool
+	// Strip trailing whitespace from rewritten lines instead of preserving it.
+	StripTrailingWhitespace bool
+	// Quoting of the `uses:` value of rewritten lines. Empty keeps the original quotes.
+	NormalizeQuotes QuoteStyle
+	// Renders the comment written after the commit SHA. Nil writes the resolved ref (e.g. `# v4.1.1`).
+	CommentTemplate *CommentTemplate
+	// Omit the comment after the commit SHA. Comments already on the line are kept.
+	NoComment bool
+	// Omit the comment after the commit SHA for refs resolved as branches (e.g. `# main`), keeping it for tags.
+	NoCommentOnBranchRefs bool
+	// Write the resolved version in the comment in the style of the original ref, with a "v" prefix only if it had one,
+	// whatever the tag names of the repository: v4 becomes `# v4.1.1` and 4 becomes `# 4.1.1`.
+	CommentMatchRefPrefix bool
+	// Append the date of the resolution to the comment after the commit SHA, e.g. `# v4.1.1 @2025-01-02`.
+	CommentIncludeDate bool
+	// Pin version refs to their fully qualified tag instead of the commit SHA. Empty means PinToSHA.
+	PinTo PinTarget
+	// Fail the file, leaving it unchanged, when an action can't be resolved. By default the action's line is left
+	// unchanged with a warning and the rest of the file is pinned.
+	FailOnUnresolvable bool
+	// Only pin to the pre-approved commit SHAs of the allowlist. Other resolutions fail with NotAllowlistedError.
+	Allowlist *Allowlist
+	// Pin resolutions missing from the Allowlist anyway, logging a warning instead of failing.
+	AllowlistWarnOnly bool
+	// Pin `docker://image:tag` references to the image's manifest digest by querying its registry.
+	PinDocker bool
+	// Credentials for the registries queried with PinDocker. Empty authenticates anonymously (public images only).
+	RegistryUsername string
+	RegistryPassword string
+	// Total number of API retries allowed across the whole run. Zero or negative means unlimited.
+	RetryBudget int
+	// Retries per API call on rate limit and 5xx errors. Zero uses the default (3); negative disables retries.
+	MaxRetries int
+	// Longest single wait between retries; rate limits asking to wait longer fail instead. Zero uses the default (1m).
+	MaxBackoff time.Duration
+	// Warn (advisory only) when a rewritten line exceeds this many characters. Zero disables the warning.
+	MaxLineLength int
+	// Parse workflows as YAML to only pin genuine `uses:` keys, never lookalikes inside multi-line strings. Files that
+	// aren't valid YAML fail. Lines are still rewritten in place, keeping their formatting and comments.
+	YAMLMode bool
+	// Pin references written without `@ref` (e.g. `uses: actions/checkout`), which are invalid in workflows, to the
+	// head of the default branch of their repository, looked up through the API. By default they are left as is.
+	AssumeDefaultBranch bool
+	// How long resolutions persisted in the on-disk cache stay valid. Zero disables the disk cache.
+	CacheTTL time.Duration
+	// Location of the on-disk cache. Defaults to pin.DefaultDiskCachePath when empty.
+	CachePath string
+	// Cache replaces the default resolution cache (in-memory, or on-disk with CacheTTL), e.g. with a shared store.
+	Cache pin.Cache
+	// Check through the API that actions already pinned to a commit SHA reference an existing commit of their
+	// repository, failing the file with pin.CommitNotFoundError otherwise. Costs one API call per distinct commit.
+	VerifyExistingSHA bool
+	// Rewrite commit SHAs already pinned with uppercase hex digits (e.g. @11BD7190...) in lowercase, recording the
+	// rewrite as a change even when nothing else on the line is pinned.
+	NormalizeSHA bool
+	// Kind of API server of the primary client. Empty means ProviderGitHub.
+	Provider Provider
+	// Resolver replaces the resolution through the GitHub API, see NewPinWithResolver. Nil resolves with the clients.
+	Resolver Resolver
+	// Pin the refs recorded in the lockfile to their locked commit without resolving them, and record the resolutions
+	// of the others. Nil disables the lockfile. The changes are written by SaveLockfile.
+	Lockfile *Lockfile
+	// Resolve every ref again, refreshing its lockfile entry, instead of pinning it to the locked commit.
+	UpdateLock bool
+}
+
+// NewPin creates a pin command with primary GitHub client and optional fallback GitHub.com client. With
+// Options.Resolver, the clients are unused and may be nil.
+func NewPin(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts Options) Pin {
+	if opts.Resolver != nil {
+		return newPin(lockResolver(opts.Resolver, opts), opts)
+	}
+	resolverOpts := pin.ResolverOptions{
+		ResolveDescribe:     opts.ResolveDescribe,
+		V0Strict:            opts.V0Strict,
+		AllowPrerelease:     opts.AllowPrerelease,
+		PreferBranches:      opts.PreferBranches,
+		ResolveLatest:       opts.ResolveLatest,
+		FailOnFallback:      opts.FailOnFallback,
+		FallbackOnForbidden: opts.FallbackOnForbidden,
+		FallbackStatuses:    opts.FallbackStatuses,
+		CanonicalizeNames:   opts.CanonicalizeNames,
+	}
+	var diskCache *pin.DiskCache
+	if opts.Cache != nil {
+		resolverOpts.Cache = opts.Cache
+	} else if diskCache = openDiskCache(primaryClient, resolverOpts, opts); diskCache != nil {
+		resolverOpts.Cache = diskCache
+	}
+	retryOpts := pin.RetryOptions{MaxRetries: opts.MaxRetries, MaxBackoff: opts.MaxBackoff}
+	resolver := newVersionResolver(primaryClient, fallbackClient, opts.Provider, opts.RetryBudget, retryOpts, resolverOpts)
+	p := newPin(lockResolver(resolver, opts), opts)
+	p.diskCache = diskCache
+	return p
+}
+
+// lockResolver returns resolver, behind the lockfile of opts if any.
+func lockResolver(resolver Resolver, opts Options) Resolver {
+	if opts.Lockfile == nil {
+		return resolver
+	}
+	return &lockfileResolver{next: resolver, lock: opts.Lockfile, update: opts.UpdateLock}
+}
+
+// NewPinWithResolver creates a pin command resolving action references with r instead of the GitHub API, e.g. a
+// test double or a resolution backend of the embedding program; see Resolver for the contract. The options selecting
+// and rewriting lines apply as with NewPin; those configuring the API clients and their resolutions (ResolveDescribe
+// to CanonicalizeNames, the retry and cache options) are ignored. VerifyExistingSHA requires r to also implement
+// VerifyCommit(ctx, owner, repo, sha string) error; lines already pinned aren't verified otherwise.
+func NewPinWithResolver(r Resolver, opts Options) Pin {
+	return newPin(lockResolver(r, opts), opts)
+}
+
+// newPin creates a pin command resolving with resolver, without disk cache.
+func newPin(resolver Resolver, opts Options) Pin {
+	var commits commitVerifier
+	if verifier, ok := resolver.(commitVerifier); ok && opts.VerifyExistingSHA {
+		commits = newVerifiedCommits(verifier)
 	}
-	resolver := pin.NewVersionResolver(primaryClient.Repositories, fallbackRepos)
 	return Pin{
-		resolver:            &resolver,
-		ignoreOwners:        ignoreOwners,
-		ignoreRepos:         ignoreRepos,
-		strictPinning202508: strictPinning202508,
+		resolver:                 resolver,
+		ignoreOwners:             newNamePatterns(opts.IgnoreOwners),
+		ignoreRepos:              newNamePatterns(opts.IgnoreRepos),
+		onlyOwners:               newNamePatterns(opts.OnlyOwners),
+		onlyRepos:                newNamePatterns(opts.OnlyRepos),
+		strictPinning202508:      opts.StrictPinning202508,
+		excludeReusableWorkflows: opts.ExcludeReusableWorkflows,
+		stripTrailingWhitespace:  opts.StripTrailingWhitespace,
+		maxLineLength:            opts.MaxLineLength,
+		yamlMode:                 opts.YAMLMode,
+		assumeDefaultBranch:      opts.AssumeDefaultBranch,
+		quoteStyle:               opts.NormalizeQuotes,
+		commentTemplate:          opts.CommentTemplate,
+		noComment:                opts.NoComment,
+		noBranchComment:          opts.NoCommentOnBranchRefs,
+		matchRefPrefix:           opts.CommentMatchRefPrefix,
+		commentDate:              opts.CommentIncludeDate,
+		pinTarget:                opts.PinTo,
+		failOnUnresolvable:       opts.FailOnUnresolvable,
+		allowlist:                opts.Allowlist,
+		allowlistWarnOnly:        opts.AllowlistWarnOnly,
+		digests:                  newDigestResolver(opts),
+		commits:                  commits,
+		normalizeSHA:             opts.NormalizeSHA,
+		lockfile:                 opts.Lockfile,
+	}
+}
+
+// newDigestResolver creates the Docker image digest resolver, or returns nil when Docker pinning is disabled.
+func newDigestResolver(opts Options) digestResolver {
+	if !opts.PinDocker {
+		return nil
+	}
+	return newDigestCache(registry.NewClient(nil, registry.Credentials{
+		Username: opts.RegistryUsername,
+		Password: opts.RegistryPassword,
+	}))
+}
+
+// openDiskCache opens the on-disk resolution cache for the primary API, or returns nil when it is disabled or its
+// location can't be determined. Refreshing the lockfile disables it, so that the entries reflect the refs as they are.
+func openDiskCache(primaryClient *gogithub.Client, resolverOpts pin.ResolverOptions, opts Options) *pin.DiskCache {
+	if opts.CacheTTL <= 0 || (opts.Lockfile != nil && opts.UpdateLock) {
+		return nil
+	}
+	path := opts.CachePath
+	if path == "" {
+		var err error
+		if path, err = pin.DefaultDiskCachePath(); err != nil {
+			slog.Warn("cannot determine resolution cache location; disk cache disabled", "error", err)
+			return nil
+		}
+	}
+	// Resolutions depend on the API host, so GitHub.com and GHES entries never mix.
+	return pin.OpenDiskCache(path, pin.CacheNamespace(primaryClient.BaseURL.String(), resolverOpts), opts.CacheTTL)
+}
+
+// SaveCache persists the resolutions of this run to the on-disk cache, if enabled.
+func (p *Pin) SaveCache() error {
+	if p.diskCache == nil {
+		return nil
 	}
+	return p.diskCache.Save()
+}
+
+// SaveLockfile writes the resolutions recorded in the lockfile, if enabled and changed.
+func (p *Pin) SaveLockfile() error {
+	if p.lockfile == nil {
+		return nil
+	}
+	return p.lockfile.Save()
+}
+
+// Stats returns the resolver statistics of the resolutions so far. It is safe to call while pinning is in progress.
+func (p *Pin) Stats() pin.ResolverStats {
+	if s, ok := p.resolver.(interface{ Stats() pin.ResolverStats }); ok {
+		return s.Stats()
+	}
+	return pin.ResolverStats{}
+}
+
+// newVersionResolver creates a resolver whose primary and fallback services share one retry budget for the whole run.
+func newVersionResolver(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, provider Provider, retryBudget int, retryOpts pin.RetryOptions, opts pin.ResolverOptions) *pin.VersionResolver {
+	budget := pin.NewRetryBudget(retryBudget)
+	var fallbackRepos pin.RepositoryService
+	if fallbackClient != nil {
+		fallbackRepos = pin.NewRetryingRepositoryService(fallbackClient.Repositories, budget, retryOpts)
+		opts.FallbackGitService = pin.NewRetryingGitService(fallbackClient.Git, budget, retryOpts)
+	}
+	repos, git := repositoryService(primaryClient, provider)
+	primaryRepos := pin.NewRetryingRepositoryService(repos, budget, retryOpts)
+	if git != nil {
+		opts.GitService = pin.NewRetryingGitService(git, budget, retryOpts)
+	}
+	resolver := pin.NewVersionResolver(primaryRepos, fallbackRepos, opts)
+	return &resolver
 }
 
 // Apply replaces input YAML content then returns the modified content, a boolean indicating if any replacements were
 // made, and an error if any occurred.
 func (p *Pin) Apply(ctx context.Context, input string) (string, bool, error) {
+	output, changes, err := p.ApplyChanges(ctx, input)
+	return output, len(changes) > 0, err
+}
+
+// ApplyChanges works like Apply, returning a record of each pinned line instead of a boolean.
+func (p *Pin) ApplyChanges(ctx context.Context, input string) (string, []rewrite.Change, error) {
+	targetLines, err := p.targetLines(input)
+	if err != nil {
+		return input, nil, err
+	}
 	lines := strings.Split(input, "\n")
+	p.warnAnchoredUses(lines)
 
-	changed := false
+	var changes []rewrite.Change
 	resultLines := make([]string, 0, len(lines))
 
 	var errs []error
-	for _, line := range lines {
-		modifiedLine, lineChanged, err := p.replaceLine(ctx, line)
+	var scope lineScope
+	for i, line := range lines {
+		if !scope.next(line) || (targetLines != nil && !targetLines[i+1]) {
+			resultLines = append(resultLines, line)
+			continue
+		}
+
+		if p.commits != nil {
+			if err := p.verifyPinnedLine(ctx, line); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		modifiedLine, lineChanges, err := p.pinLine(ctx, line)
 		if err != nil {
 			// Collect errors but continue processing remaining actions/lines.
 			errs = append(errs, err)
@@ -57,8 +360,11 @@ func (p *Pin) Apply(ctx context.Context, input string) (string, bool, error) {
 			continue
 		}
 
-		if lineChanged {
-			changed = true
+		if len(lineChanges) > 0 {
+			for _, change := range lineChanges {
+				change.Line = i + 1
+				changes = append(changes, change)
+			}
 			line = modifiedLine
 		}
 		resultLines = append(resultLines, line)
@@ -68,69 +374,445 @@ func (p *Pin) Apply(ctx context.Context, input string) (string, bool, error) {
 	output := strings.Join(resultLines, "\n")
 
 	if len(errs) > 0 {
-		return output, changed, errors.Join(errs...)
+		return output, changes, errors.Join(errs...)
 	}
-	return output, changed, nil
+	return output, changes, nil
 }
 
 func (p *Pin) replaceLine(ctx context.Context, line string) (string, bool, error) {
+	newLine, changes, err := p.pinLine(ctx, line)
+	return newLine, len(changes) > 0, err
+}
+
+// pinLine pins the actions referenced by line, returning the new line and a record of each change, or the line as is
+// and no changes when there is nothing to pin.
+func (p *Pin) pinLine(ctx context.Context, line string) (string, []rewrite.Change, error) {
+	if isMultiFlowLine(line) {
+		return p.pinFlowLine(ctx, line)
+	}
+	parsed, ok := p.parseTarget(line)
+	if !ok {
+		if def, ok := p.uppercaseSHA(line); ok {
+			return lowercaseSHA(line, def), []rewrite.Change{shaCaseChange(def)}, nil
+		}
+		// Leaves the line unchanged unless it is a Docker image to pin
+		newLine, change, err := p.pinDockerLine(ctx, line)
+		if change == nil {
+			return newLine, nil, err
+		}
+		return newLine, []rewrite.Change{*change}, err
+	}
+
+	value, newComment, change, err := p.pinParsed(ctx, parsed)
+	if err != nil || change == nil {
+		return line, nil, err
+	}
+	// Replace a stale version marker in the existing comment rather than stacking a second one in front of it.
+	if newComment = mergeComment(newComment, parsed.comment); newComment != "" {
+		newComment = " " + newComment
+	}
+	newLine := p.finishLine(parsed.prefix+value+parsed.tail+newComment, parsed.trailingSpace, parsed.def)
+	return newLine, []rewrite.Change{*change}, nil
+}
+
+// pinFlowLine pins every `uses:` key of a line holding several flow mappings, e.g.
+// `steps: [{uses: actions/checkout@v4}, {uses: actions/setup-go@v5}]`. A line can only end with one comment, so the
+// comments of the pinned actions are joined in line order, before any comment the line already had.
+func (p *Pin) pinFlowLine(ctx context.Context, line string) (string, []rewrite.Change, error) {
+	body, comment := splitFlowComment(line)
+	trailingSpace := line[len(strings.TrimRight(line, " \t")):]
+
+	var changes []rewrite.Change
+	var comments []string
+	var errs []error
+	var last pin.ActionDef
+	newBody := flowUsesKeyPattern.ReplaceAllStringFunc(body, func(key string) string {
+		parsed, ok := p.parseFlowKey(key)
+		if !ok {
+			if def, ok := p.uppercaseSHA("{" + key[1:] + "}"); ok {
+				changes = append(changes, shaCaseChange(def))
+				last = def
+				return lowercaseSHA(key, def)
+			}
+			return key
+		}
+		value, newComment, change, err := p.pinParsed(ctx, parsed)
+		if err != nil {
+			errs = append(errs, err)
+			return key
+		}
+		if change == nil {
+			return key
+		}
+		changes = append(changes, *change)
+		if newComment != "" {
+			comments = append(comments, newComment)
+		}
+		last = parsed.def
+		return key[:1] + strings.TrimPrefix(parsed.prefix, "{") + value
+	})
+	if len(errs) > 0 {
+		return line, nil, errors.Join(errs...)
+	}
+	if len(changes) == 0 {
+		return line, nil, nil
+	}
+
+	newComment := ""
+	if len(comments) > 0 {
+		newComment = " # " + strings.Join(comments, ", ")
+	}
+	if comment != "" {
+		newComment += " " + comment
+	}
+	return p.finishLine(newBody+newComment, trailingSpace, last), changes, nil
+}
+
+// parseFlowTargets returns the actions to pin of a line holding several flow mappings, in line order.
+func (p *Pin) parseFlowTargets(line string) []parsedLine {
+	body, _ := splitFlowComment(line)
+	var targets []parsedLine
+	for _, key := range flowUsesKeyPattern.FindAllString(body, -1) {
+		if parsed, ok := p.parseFlowKey(key); ok {
+			targets = append(targets, parsed)
+		}
+	}
+	return targets
+}
+
+// parseFlowKey parses a flowUsesKeyPattern match on its own, as the single mapping `{uses: owner/repo@ref}`, applying
+// the usual options through parseTarget.
+func (p *Pin) parseFlowKey(key string) (parsedLine, bool) {
+	return p.parseTarget("{" + key[1:] + "}")
+}
+
+// pinParsed resolves the action of parsed, returning its pinned `uses:` value with the line's quotes (or the
+// normalized ones), the comment to write after it without the leading "# ", and a record of the change. The change
+// is nil, with no error, when the action is left as is.
+func (p *Pin) pinParsed(ctx context.Context, parsed parsedLine) (string, string, *rewrite.Change, error) {
+	def := parsed.def
+
+	resolved, err := p.resolver.ResolveVersion(ctx, def)
+	if err != nil {
+		if errors.Is(err, pin.AlreadyResolvedError) {
+			return "", "", nil, nil
+		}
+		// FailOnFallback is a policy, resolve-once assertions are debugging checks and API failures (e.g. rejected
+		// credentials) aren't specific to the action, so their errors fail the file regardless.
+		if !p.failOnUnresolvable && !errors.Is(err, pin.FallbackNotAllowedError) && !errors.Is(err, pin.DuplicateResolutionError) &&
+			!pin.IsAPIFailure(err) {
+			slog.Warn("leaving unresolvable action unchanged", "action", def.String(), "reason", err)
+			return "", "", nil, nil
+		}
+		return "", "", nil, errors.Wrapf(err, "failed to resolve version for %s", def.String())
+	}
+
+	if p.allowlist != nil && !p.allowlist.Allows(def.Owner, def.Repo, resolved.CommitSHA) {
+		if !p.allowlistWarnOnly {
+			return "", "", nil, errors.Wrapf(NotAllowlistedError, "%s resolved to %s", def.String(), resolved.CommitSHA)
+		}
+		slog.Warn("pinning commit SHA that is not on the allowlist", "action", def.String(), "sha", resolved.CommitSHA)
+	}
+
+	// Use the canonical owner/repo casing when the resolver looked it up
+	owner, repo := def.Owner, def.Repo
+	if resolved.CanonicalOwner != "" && resolved.CanonicalRepo != "" {
+		owner, repo = resolved.CanonicalOwner, resolved.CanonicalRepo
+	}
+
+	newComment, err := p.comment(def, owner, repo, resolved)
+	if err != nil {
+		return "", "", nil, errors.Wrapf(err, "failed to render comment for %s", def.String())
+	}
+
+	// Reconstruct the path part if necessary
+	repoPath := repo
+	if def.Path != "" {
+		repoPath = repo + "/" + def.Path
+	}
+
+	pinnedRef := resolved.CommitSHA
+	toRef := ""
+	if p.pinsToTag(def, resolved) {
+		pinnedRef, toRef = resolved.RefComment, resolved.RefComment
+	}
+
+	// Use the original quotes, unless asked to normalize them
+	newRef := owner + "/" + repoPath + "@" + pinnedRef
+	openQuote, closeQuote := p.quoteStyle.quotes(parsed.openQuote, parsed.closeQuote, newRef)
+
+	return openQuote + newRef + closeQuote, newComment, &rewrite.Change{
+		Owner:           def.Owner,
+		Repo:            def.Repo,
+		Path:            def.Path,
+		FromRef:         def.RefOrSHA,
+		ToSHA:           resolved.CommitSHA,
+		ToRef:           toRef,
+		ResolvedComment: resolved.RefComment,
+		Branch:          resolved.WasBranch,
+	}, nil
+}
+
+// finishLine applies the whitespace and line length options to a rewritten line of def.
+func (p *Pin) finishLine(newLine, trailingSpace string, def pin.ActionDef) string {
+	// Never introduce trailing whitespace; keep what the original line had unless asked to strip it.
+	newLine = strings.TrimRight(newLine, " \t")
+	if !p.stripTrailingWhitespace {
+		newLine += trailingSpace
+	}
+
+	if p.maxLineLength > 0 {
+		if length := utf8.RuneCountInString(newLine); length > p.maxLineLength {
+			slog.Warn("pinned line exceeds max line length", "action", def.String(), "length", length, "max", p.maxLineLength)
+		}
+	}
+	return newLine
+}
+
+// comment returns the comment to write after the pinned ref, without the leading "# ". By default this is the
+// resolved tag, or the original ref when pinning to the tag itself.
+func (p *Pin) comment(def pin.ActionDef, owner, repo string, resolved pin.ResolvedVersion) (string, error) {
+	if p.noComment || (p.noBranchComment && resolved.WasBranch) {
+		return "", nil
+	}
+	refComment := resolved.RefComment
+	if p.matchRefPrefix && !resolved.WasBranch && def.VersionTag() != nil && versionMarkerPattern.MatchString(refComment) {
+		refComment = matchVPrefix(refComment, def.RefOrSHA)
+	}
+	if p.commentTemplate == nil {
+		if p.pinsToTag(def, resolved) {
+			return p.dated(def.RefOrSHA), nil
+		}
+		return p.dated(refComment), nil
+	}
+	comment, err := p.commentTemplate.render(CommentData{
+		RefComment: refComment,
+		Owner:      owner,
+		Repo:       repo,
+		Ref:        def.RefOrSHA,
+		SHA:        resolved.CommitSHA,
+	})
+	if err != nil {
+		return "", err
+	}
+	return p.dated(comment), nil
+}
+
+// matchVPrefix writes version with a "v" prefix only if ref has one, e.g. 4.1.1 resolved from v4 becomes v4.1.1 and
+// v4.1.1 resolved from 4 becomes 4.1.1.
+func matchVPrefix(version, ref string) string {
+	bare := strings.TrimPrefix(version, "v")
+	if strings.HasPrefix(ref, "v") {
+		return "v" + bare
+	}
+	return bare
+}
+
+// dated appends the resolution date to comment when CommentIncludeDate is set.
+func (p *Pin) dated(comment string) string {
+	if !p.commentDate || comment == "" {
+		return comment
+	}
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	return comment + " " + dateMarker(now())
+}
+
+// Check reports every line of input that Apply would pin, without resolving anything.
+func (p *Pin) Check(_ context.Context, input string) ([]rewrite.Finding, error) {
+	targetLines, err := p.targetLines(input)
+	if err != nil {
+		return nil, err
+	}
+	var findings []rewrite.Finding
+	var scope lineScope
+	for i, line := range strings.Split(input, "\n") {
+		if !scope.next(line) || (targetLines != nil && !targetLines[i+1]) {
+			continue
+		}
+		if isMultiFlowLine(line) {
+			for _, parsed := range p.parseFlowTargets(line) {
+				findings = append(findings, rewrite.Finding{
+					Line:    i + 1,
+					Message: parsed.def.String(),
+				})
+			}
+			for _, def := range p.uppercaseFlowSHAs(line) {
+				findings = append(findings, rewrite.Finding{Line: i + 1, Message: def.String()})
+			}
+			continue
+		}
+		parsed, ok := p.parseTarget(line)
+		if !ok {
+			if def, ok := p.uppercaseSHA(line); ok {
+				findings = append(findings, rewrite.Finding{Line: i + 1, Message: def.String()})
+				continue
+			}
+			if docker, ok := p.parseDockerTarget(line); ok {
+				findings = append(findings, rewrite.Finding{
+					Line:    i + 1,
+					Message: "docker://" + docker.image.Name + ":" + docker.image.Tag,
+				})
+			}
+			continue
+		}
+		findings = append(findings, rewrite.Finding{
+			Line:    i + 1,
+			Message: parsed.def.String(),
+		})
+	}
+	return findings, nil
+}
+
+// targetLines returns the 1-based lines of input holding a `uses:` key in YAML mode, or nil when every line is
+// scanned.
+func (p *Pin) targetLines(input string) (map[int]bool, error) {
+	if !p.yamlMode {
+		return nil, nil
+	}
+	return usesLines(input)
+}
+
+// inScope reports whether def is matched by the only-owners/only-repos allow-list, or no allow-list is configured.
+func (p *Pin) inScope(def pin.ActionDef) bool {
+	if len(p.onlyOwners) == 0 && len(p.onlyRepos) == 0 {
+		return true
+	}
+	return p.onlyOwners.match(def.Owner) || p.onlyRepos.match(def.Owner+"/"+def.Repo)
+}
+
+// parseTarget parses line and reports whether it references an action that should be pinned, applying the
+// ignore/exclude options. Lines that are already pinned to a commit SHA (or, with PinToTag, to a full version) are
+// not targets.
+func (p *Pin) parseTarget(line string) (parsedLine, bool) {
+	parsed, ok := p.parseCandidate(line)
+	if !ok || parsed.def.HasCommitSHA() {
+		return parsedLine{}, false
+	}
+	if p.pinTarget == PinToTag && fullVersionPattern.MatchString(parsed.def.RefOrSHA) {
+		return parsedLine{}, false // Already pinned to a tag
+	}
+	return parsed, true
+}
+
+// parseCandidate parses line and reports whether it references an action selected by the ignore/exclude options,
+// whether or not it is already pinned.
+func (p *Pin) parseCandidate(line string) (parsedLine, bool) {
 	parsed, ok := parseLine(line)
+	if !ok && p.assumeDefaultBranch {
+		parsed, ok = parseRefLessLine(line)
+	}
 	if !ok {
-		return line, false, nil // No action definition found, return the line unchanged
+		return parsedLine{}, false // No action definition found
 	}
 	def := parsed.def
 
-	// log debug to show exactly what the current replacement is... 
+	// log debug to show exactly what the current replacement is...
 	slog.Debug("pin decision",
 		"owner", def.Owner,
 		"repo", def.Repo,
 		"ref", def.RefOrSHA,
 		"is_reusable_workflow", def.IsReusableWorkflow(),
 		"strict_pinning_202508", p.strictPinning202508,
+		"exclude_reusable_workflows", p.excludeReusableWorkflows,
 		"ignore_owners", p.ignoreOwners,
 		"ignore_repos", p.ignoreRepos,
+		"only_owners", p.onlyOwners,
+		"only_repos", p.onlyRepos,
 	)
 
+	if p.excludeReusableWorkflows && def.IsReusableWorkflow() {
+		return parsedLine{}, false
+	}
+
+	// The allow-list scopes pinning first; the ignore lists below then apply within it.
+	if !p.inScope(def) {
+		return parsedLine{}, false
+	}
+
 	// Apply ignore owners check (skip for composite actions when strict pinning is enabled)
 	if !p.strictPinning202508 || def.IsReusableWorkflow() {
-		if slices.Contains(p.ignoreOwners, def.Owner) {
-			return line, false, nil
+		if p.ignoreOwners.match(def.Owner) {
+			return parsedLine{}, false
 		}
 	}
 
 	repoKey := def.Owner + "/" + def.Repo
-	if slices.Contains(p.ignoreRepos, repoKey) {
-		return line, false, nil
+	if p.ignoreRepos.match(repoKey) {
+		return parsedLine{}, false
 	}
+	return parsed, true
+}
+
+// lineScope tracks the YAML context of consecutive lines so that Apply only considers lines where a `uses:` key
+// can reference an action.
+//
+// Everything nested under a `with:` or `secrets:` key is an input value (e.g. of a reusable workflow call), never an
+// action reference, so such blocks are left untouched even if an input happens to be named `uses`. Likewise, the
+// content of block scalars (`run: |`, `description: >-`) is text, e.g. a documented example workflow, whatever it
+// looks like.
+type lineScope struct {
+	inInputs     bool
+	inputsIndent int // indentation of the enclosing with:/secrets: key while inInputs
+	inBlock      bool
+	blockIndent  int // indentation of the key of the enclosing block scalar while inBlock
+}
+
+// blockScalarPattern matches a line, without its comment, whose value starts a block scalar: a `|` or `>` indicator
+// with optional chomping and indentation indicators, after an optional anchor or tag.
+var blockScalarPattern = regexp.MustCompile(`(?:^|:\s+|^-\s+)(?:[&!]\S*\s+)*[|>][-+1-9]*$`)
 
-	if def.HasCommitSHA() {
-		return line, false, nil
+// next advances the scope past line and reports whether line may contain an action reference.
+func (s *lineScope) next(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed[0] == '#' {
+		// Blank and comment lines never end a block.
+		return !s.inInputs && !s.inBlock
 	}
+	indent := indentOf(line)
 
-	resolved, err := p.resolver.ResolveVersion(ctx, def)
-	if err != nil {
-		if errors.Is(err, pin.AlreadyResolvedError) {
-			return line, false, nil
+	if s.inBlock {
+		if indent > s.blockIndent {
+			return false
 		}
-		return "", false, errors.Wrapf(err, "failed to resolve version for %s/%s@%s", def.Owner, def.Repo, def.RefOrSHA)
+		s.inBlock = false
 	}
-
-	newComment := " # " + resolved.RefComment
-	if parsed.comment != "" {
-		newComment += " " + parsed.comment
+	if s.inInputs {
+		if indent > s.inputsIndent {
+			return false
+		}
+		s.inInputs = false
 	}
 
-	// Reconstruct the path part if necessary
-	repoPath := def.Repo
-	if def.Path != "" {
-		repoPath = def.Repo + "/" + def.Path
+	key := trimmed
+	if idx := strings.Index(key, " #"); idx >= 0 {
+		key = strings.TrimSpace(key[:idx])
 	}
+	if key == "with:" || key == "secrets:" {
+		s.inInputs = true
+		s.inputsIndent = indent
+	}
+	if blockScalarPattern.MatchString(key) {
+		s.inBlock = true
+		s.blockIndent = blockKeyIndent(line)
+	}
+	return true
+}
 
-	// Construct the new line using the original quotes
-	newRef := def.Owner + "/" + repoPath + "@" + resolved.CommitSHA
-	newLine := parsed.prefix + parsed.openQuote + newRef + parsed.closeQuote + newComment
-
-	return newLine, true, nil
+// blockKeyIndent returns the indentation of the key of a line starting a block scalar, counting the `- ` of sequence
+// entries: the content of `- run: |` is indented past `run`. A block scalar entry (`- |`) is indented past the `-`.
+func blockKeyIndent(line string) int {
+	indent := indentOf(line)
+	rest := line[indent:]
+	for strings.HasPrefix(rest, "- ") {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+	if rest == "" || rest[0] == '|' || rest[0] == '>' {
+		return indent
+	}
+	return len(line) - len(rest)
 }
 
 type parsedLine struct {
@@ -138,7 +820,11 @@ type parsedLine struct {
 	prefix     string
 	openQuote  string // Opening quote if any (e.g., '"' or ''')
 	closeQuote string // Closing quote if any (should match openQuote)
-	comment    string // Comment part of the line (if any)
+	// Rest of a flow mapping after the value, kept before the comment (e.g. " }" or ", secrets: inherit }")
+	tail    string
+	comment string // Comment part of the line (if any)
+	// Whitespace at the end of the original line (if any)
+	trailingSpace string
 }
 
 // regexp to match and extract the action definition, see testdata/pin.yml for examples:
@@ -149,19 +835,66 @@ type parsedLine struct {
 //   - uses: 'actions/checkout@v4'
 //     uses: golangci/golangci-lint-action@1481404843c368bc19ca9406f87d6e0fc97bdcfd # v7.0.0
 //     uses: Finatext/workflows-public/.github/workflows/gha-lint.yml@main
-var usesPattern = regexp.MustCompile(`^([-\s]*(?:["']?uses["']?:\s+))(["']?)([^/"']+)/([^/"']+)(/[^@"']+)?(@)([^\s#"']+)(["']?)(.*)`)
+//     uses: &checkout actions/checkout@v4
+//
+// A YAML anchor on the value is kept in the prefix, so that the aliases of an anchored uses: are pinned with it.
+//
+// The ref follows the last `@`: owner and repo can't contain `@` but the path can (e.g. `owner/repo/dir@name@v1` has
+// the path `dir@name` and the ref `v1`). The ref may contain `/` (branches like `release/2024`) and `#`, which only
+// starts a YAML comment after whitespace (`@feat#1` is the branch `feat#1`).
+var usesPattern = regexp.MustCompile(`^([-\s]*(?:["']?uses["']?:\s+)(?:&[^\s\[\]{},]+\s+)?)(["']?)([^/@"'\s#]+)/([^/@"'\s#]+)(/[^\s"']+)?(@)([^\s"'@]+)(["']?)((?:\s.*)?)$`)
+
+// flowUsesPattern matches a `uses:` key of a flow mapping, for steps and reusable workflow jobs written inline:
+//
+//   - { uses: actions/checkout@v4 }
+//     call: {uses: org/repo/.github/workflows/build.yml@main, secrets: inherit}
+//
+// Only a key of the outermost mapping matches, and the value ends at the next `,` or `}`. The groups are those of
+// usesPattern, with the suffix starting at the `,` or `}`.
+var flowUsesPattern = regexp.MustCompile(`^([-\s]*(?:[^\s#{}][^#{}]*:\s+)?\{\s*(?:[^#{}]*,\s*)?["']?uses["']?:\s+)(["']?)([^/@"',{}\s#]+)/([^/@"',{}\s#]+)(/[^\s"',{}]+)?(@)([^\s"'@,{}]+)(["']?)(\s*[,}].*)$`)
+
+// flowUsesKeyPattern matches each `uses:` key of the flow mappings of a line, from the `{` or `,` before it, so that
+// a line holding several mappings yields all of them. See pinFlowLine.
+var flowUsesKeyPattern = regexp.MustCompile(`[{,]\s*["']?uses["']?:\s+["']?[^/@"',{}\s#]+/[^/@"',{}\s#]+(?:/[^\s"',{}]+)?@[^\s"'@,{}]+["']?`)
+
+// flowInputsPattern matches the prefix of flowUsesPattern for a `with:` or `secrets:` mapping, whose `uses` key is an
+// input rather than an action reference (see lineScope).
+var flowInputsPattern = regexp.MustCompile(`^[-\s]*["']?(?:with|secrets)["']?:\s+\{`)
 
 // Group indices:
-// 1: prefix (e.g., "- uses: ", "   uses: ", or "   "uses": ")
+// 1: prefix (e.g., "- uses: ", "   uses: ", "   "uses": " or "uses: &checkout ")
 // 2: opening quote (if any)
 // 3: owner (e.g., "actions")
 // 4: repo (e.g., "checkout")
 // 5: path (e.g., "/diff" - optional)
 // 6: @ symbol
-// 7: refOrSHA (e.g., "v4", "main", commit SHA)
+// 7: refOrSHA, everything after the last @ (e.g., "v4", "main", "feature/foo", commit SHA)
 // 8: closing quote (if any)
 // 9: suffix (comments, etc.)
 
+// usesValuePattern matches any `uses:` line. Group 1 is the value as written, without quotes and comment.
+var usesValuePattern = regexp.MustCompile(`^(?:[-\s]*|[^#{}]*\{\s*(?:[^#{}]*,\s*)?)["']?uses["']?:\s+(?:&[^\s\[\]{},]+\s+)?["']?([^\s"']+)`)
+
+// isMultiFlowLine reports whether line holds more than one `uses:` key of flow mappings, outside `with:` and
+// `secrets:` mappings and comments.
+func isMultiFlowLine(line string) bool {
+	if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") || flowInputsPattern.MatchString(line) {
+		return false
+	}
+	body, _ := splitFlowComment(line)
+	return len(flowUsesKeyPattern.FindAllStringIndex(body, 2)) > 1
+}
+
+// splitFlowComment splits a line of flow mappings into its content, without trailing whitespace, and its comment. A
+// comment needs whitespace before its #; the mappings may contain a # otherwise.
+func splitFlowComment(line string) (string, string) {
+	body, comment := line, ""
+	if commentIdx := strings.Index(line, " #"); commentIdx >= 0 {
+		body, comment = line[:commentIdx], strings.TrimSpace(line[commentIdx:])
+	}
+	return strings.TrimRight(body, " \t"), comment
+}
+
 func parseLine(line string) (parsedLine, bool) {
 	// Check for leading comments
 	trimmed := strings.TrimSpace(line)
@@ -169,8 +902,36 @@ func parseLine(line string) (parsedLine, bool) {
 		return parsedLine{}, false
 	}
 
+	// Local actions (./path, ../path) are loaded from the calling repository's checkout and have nothing to pin.
+	// Recognize them explicitly so they are never resolved as owner "." or "..", with or without a ref.
+	value := ""
+	if matches := usesValuePattern.FindStringSubmatch(line); matches != nil {
+		value = matches[1]
+	}
+	if strings.HasPrefix(value, "./") || strings.HasPrefix(value, "../") {
+		if strings.Contains(value, "@") {
+			slog.Warn("skipping local action reference with a ref; local actions cannot be pinned", "uses", value)
+		} else {
+			slog.Debug("skipping local action", "uses", value)
+		}
+		return parsedLine{}, false
+	}
+
 	matches := usesPattern.FindStringSubmatch(line)
+	flow := false
+	if matches == nil {
+		matches = flowUsesPattern.FindStringSubmatch(line)
+		flow = matches != nil && !flowInputsPattern.MatchString(matches[1])
+		if !flow {
+			matches = nil
+		}
+	}
 	if matches == nil {
+		// The ref follows the last @, so owner/repo@name@v1 would have the invalid repo "repo@name". Rather than
+		// guessing, leave the line unchanged.
+		if strings.Count(value, "@") > 1 && !strings.Contains(value, "://") {
+			slog.Warn("skipping uses reference with several @ outside its path; cannot tell the ref apart", "uses", value)
+		}
 		return parsedLine{}, false
 	}
 
@@ -187,8 +948,15 @@ func parseLine(line string) (parsedLine, bool) {
 	closeQuote := matches[8] // Closing quote if any
 	suffix := matches[9]     // Any trailing comment or whitespace
 
-	comment := ""
-	if commentIdx := strings.Index(suffix, "#"); commentIdx >= 0 {
+	comment, tail := "", ""
+	if flow {
+		// A comment needs whitespace before its #; the rest of the mapping may contain a # otherwise.
+		tail = suffix
+		if commentIdx := strings.Index(suffix, " #"); commentIdx >= 0 {
+			tail, comment = suffix[:commentIdx], strings.TrimSpace(suffix[commentIdx:])
+		}
+		tail = strings.TrimRight(tail, " \t")
+	} else if commentIdx := strings.Index(suffix, "#"); commentIdx >= 0 {
 		comment = strings.TrimSpace(suffix[commentIdx:])
 	}
 
@@ -200,10 +968,12 @@ func parseLine(line string) (parsedLine, bool) {
 	}
 
 	return parsedLine{
-		def:        def,
-		prefix:     prefix,
-		openQuote:  openQuote,
-		closeQuote: closeQuote,
-		comment:    comment,
+		def:           def,
+		prefix:        prefix,
+		openQuote:     openQuote,
+		closeQuote:    closeQuote,
+		tail:          tail,
+		comment:       comment,
+		trailingSpace: line[len(strings.TrimRight(line, " \t")):],
 	}, true
 }
@@ -1,20 +1,21 @@
 package pin
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/rewrite"
 	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// Short-cuts for internal types
-type ResolvedVersion = pin.ResolvedVersion
-type ActionDef = pin.ActionDef
-
 func TestReplace(t *testing.T) {
 	inputBytes, err := os.ReadFile("../testdata/pin.yml")
 	require.NoError(t, err)
@@ -52,7 +53,316 @@ func TestReplace(t *testing.T) {
 	}
 	r := &Pin{
 		resolver:     mock,
-		ignoreOwners: []string{"Finatext"},
+		ignoreOwners: newNamePatterns([]string{"Finatext"}),
+	}
+	got, changed, err := r.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+}
+
+func TestReusableWorkflowCall(t *testing.T) {
+	input := `jobs:
+  call:
+    uses: org/repo/.github/workflows/build.yml@main # shared build
+    with:
+      uses: other/action@v1
+      config: |
+        uses: other/action@v1
+      ref: main
+    secrets:
+      token: ${{ secrets.TOKEN }}
+  call-with-suffix:
+    uses: "org/repo/.github/workflows/deploy.yml?env=prod@main"
+    with:
+      environment: prod
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          uses: other/action@v1
+      - uses: actions/checkout@v4`
+
+	expected := `jobs:
+  call:
+    uses: org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad # main # shared build
+    with:
+      uses: other/action@v1
+      config: |
+        uses: other/action@v1
+      ref: main
+    secrets:
+      token: ${{ secrets.TOKEN }}
+  call-with-suffix:
+    uses: "org/repo/.github/workflows/deploy.yml?env=prod@aa0779029b74112dc82b436546da0706a57323ad" # main
+    with:
+      environment: prod
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2
+        with:
+          uses: other/action@v1
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2`
+
+	r := &Pin{
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"org/repo@main": {
+				CommitSHA:  "aa0779029b74112dc82b436546da0706a57323ad",
+				RefComment: "main",
+			},
+			"actions/checkout@v4": {
+				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment: "v4.2.2",
+			},
+		}},
+	}
+	got, changed, err := r.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+
+	findings, err := r.Check(context.Background(), input)
+	require.NoError(t, err)
+	assert.Len(t, findings, 4, "inputs named uses are not reported")
+}
+
+func TestBlockScalars(t *testing.T) {
+	input := `name: Docs
+description: >-
+  Add this step to your workflow:
+    - uses: actions/checkout@v4
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - name: Write example
+        run: |
+          cat > example.yml <<EOF
+          steps:
+            - uses: actions/checkout@v4
+
+          # - uses: actions/checkout@v4
+          EOF
+      - uses: actions/checkout@v4 # real step
+      - env:
+          EXAMPLE: |2
+              uses: actions/checkout@v4
+        run: echo "$EXAMPLE"
+      - |
+        uses: actions/checkout@v4
+      - uses: actions/checkout@v4`
+
+	expected := `name: Docs
+description: >-
+  Add this step to your workflow:
+    - uses: actions/checkout@v4
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - name: Write example
+        run: |
+          cat > example.yml <<EOF
+          steps:
+            - uses: actions/checkout@v4
+
+          # - uses: actions/checkout@v4
+          EOF
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 # real step
+      - env:
+          EXAMPLE: |2
+              uses: actions/checkout@v4
+        run: echo "$EXAMPLE"
+      - |
+        uses: actions/checkout@v4
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2`
+
+	r := &Pin{
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4": {
+				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment: "v4.2.2",
+			},
+		}},
+	}
+	got, changed, err := r.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+
+	findings, err := r.Check(context.Background(), input)
+	require.NoError(t, err)
+	assert.Len(t, findings, 2, "block scalar content is not reported")
+}
+
+func TestFlowMappingUses(t *testing.T) {
+	input := `jobs:
+  call: { uses: org/repo/.github/workflows/build.yml@main, secrets: inherit } # shared build
+  call-quoted: {"uses": "org/repo/.github/workflows/build.yml@main"}
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - {uses: actions/checkout@v4}
+      - { name: Checkout, uses: 'actions/checkout@v4', with: { fetch-depth: 0 } }
+      - name: Lint
+        uses: org/lint@v1
+        with: { uses: other/action@v1 }`
+
+	expected := `jobs:
+  call: { uses: org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad, secrets: inherit } # main # shared build
+  call-quoted: {"uses": "org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad"} # main
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - {uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683} # v4.2.2
+      - { name: Checkout, uses: 'actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683', with: { fetch-depth: 0 } } # v4.2.2
+      - name: Lint
+        uses: org/lint@c4bf7fd9b2b6f5fe2e1d4aec3d0b1b6f3e2a1b9c # v1.0.0
+        with: { uses: other/action@v1 }`
+
+	r := &Pin{
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"org/repo@main": {
+				CommitSHA:  "aa0779029b74112dc82b436546da0706a57323ad",
+				RefComment: "main",
+			},
+			"actions/checkout@v4": {
+				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment: "v4.2.2",
+			},
+			"org/lint@v1": {
+				CommitSHA:  "c4bf7fd9b2b6f5fe2e1d4aec3d0b1b6f3e2a1b9c",
+				RefComment: "v1.0.0",
+			},
+		}},
+	}
+	got, changed, err := r.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+}
+
+func TestMultipleUsesOnOneLine(t *testing.T) {
+	r := &Pin{
+		ignoreOwners: newNamePatterns([]string{"org-x"}),
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4": {
+				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment: "v4.2.2",
+			},
+			"actions/setup-go@v5": {
+				CommitSHA:  "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b",
+				RefComment: "v5.4.0",
+			},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "Flow sequence of steps",
+			input:    `    steps: [{uses: actions/checkout@v4}, {name: Go, uses: "actions/setup-go@v5"}]`,
+			expected: `    steps: [{uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683}, {name: Go, uses: "actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b"}] # v4.2.2, v5.4.0`,
+			changed:  true,
+		},
+		{
+			name:     "Existing comment kept",
+			input:    `  - {uses: actions/checkout@v4} , {uses: actions/setup-go@v5} # toolchain `,
+			expected: `  - {uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683} , {uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b} # v4.2.2, v5.4.0 # toolchain `,
+			changed:  true,
+		},
+		{
+			name:     "Ignored action left as is",
+			input:    `    steps: [{uses: org-x/action@v1}, {uses: actions/setup-go@v5}]`,
+			expected: `    steps: [{uses: org-x/action@v1}, {uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b}] # v5.4.0`,
+			changed:  true,
+		},
+		{
+			name:     "Nothing to pin",
+			input:    `    steps: [{uses: org-x/action@v1}, {uses: org-x/other@v2}]`,
+			expected: `    steps: [{uses: org-x/action@v1}, {uses: org-x/other@v2}]`,
+			changed:  false,
+		},
+		{
+			name:     "Inputs named uses",
+			input:    `        with: {uses: actions/checkout@v4, other: {uses: actions/setup-go@v5}}`,
+			expected: `        with: {uses: actions/checkout@v4, other: {uses: actions/setup-go@v5}}`,
+			changed:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := r.replaceLine(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("Changes and findings", func(t *testing.T) {
+		input := "steps: [{uses: actions/checkout@v4}, {uses: actions/setup-go@v5}]"
+		_, changes, err := r.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, "checkout", changes[0].Repo)
+		assert.Equal(t, "setup-go", changes[1].Repo)
+		assert.Equal(t, 1, changes[1].Line)
+
+		findings, err := r.Check(context.Background(), input)
+		require.NoError(t, err)
+		require.Len(t, findings, 2)
+		assert.Equal(t, "actions/checkout@v4", findings[0].Message)
+		assert.Equal(t, "actions/setup-go@v5", findings[1].Message)
+	})
+}
+
+func TestCompositeAction(t *testing.T) {
+	input := `name: Setup
+description: Composite action
+inputs:
+  uses:
+    description: An input named uses
+    default: other/action@v1
+runs:
+  using: composite
+  steps:
+    - uses: actions/checkout@v4
+      with:
+        uses: other/action@v1
+    - uses: "actions/setup-go@v5.4" # toolchain
+    - run: echo done
+      shell: bash`
+
+	expected := `name: Setup
+description: Composite action
+inputs:
+  uses:
+    description: An input named uses
+    default: other/action@v1
+runs:
+  using: composite
+  steps:
+    - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2
+      with:
+        uses: other/action@v1
+    - uses: "actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b" # v5.4.0 # toolchain
+    - run: echo done
+      shell: bash`
+
+	r := &Pin{
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4":   {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+			"actions/setup-go@v5.4": {CommitSHA: "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b", RefComment: "v5.4.0"},
+		}},
 	}
 	got, changed, err := r.Apply(context.Background(), input)
 	require.NoError(t, err)
@@ -60,6 +370,64 @@ func TestReplace(t *testing.T) {
 	assert.Equal(t, expected, got)
 }
 
+func TestApplyChanges(t *testing.T) {
+	input := `steps:
+  - uses: actions/checkout@v4
+  - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0
+  - uses: "oasdiff/oasdiff-action/diff@v0"
+  - uses: org/legacy@main`
+
+	r := &Pin{
+		resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4":       {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+			"oasdiff/oasdiff-action@v0": {CommitSHA: "1c611ffb1253a72924624aa4fb662e302b3565d3", RefComment: "v0.0.21"},
+			"org/legacy@main":           {CommitSHA: "f43a0e5ff2bd294095638e18286ca9a3d1956744", RefComment: "main", WasBranch: true},
+		}},
+	}
+	_, changes, err := r.ApplyChanges(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, []rewrite.Change{
+		{
+			Line: 2, Owner: "actions", Repo: "checkout", FromRef: "v4",
+			ToSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", ResolvedComment: "v4.2.2",
+		},
+		{
+			Line: 4, Owner: "oasdiff", Repo: "oasdiff-action", Path: "diff", FromRef: "v0",
+			ToSHA: "1c611ffb1253a72924624aa4fb662e302b3565d3", ResolvedComment: "v0.0.21",
+		},
+		{
+			Line: 5, Owner: "org", Repo: "legacy", FromRef: "main",
+			ToSHA: "f43a0e5ff2bd294095638e18286ca9a3d1956744", ResolvedComment: "main", Branch: true,
+		},
+	}, changes)
+}
+
+func TestCheck(t *testing.T) {
+	input := `jobs:
+  build:
+    steps:
+      # - uses: actions/checkout@v3
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0
+      - uses: Finatext/internal-action@main
+      - uses: ./.github/actions/local
+      - uses: "oasdiff/oasdiff-action/diff@v0"
+  call:
+    uses: org/repo/.github/workflows/build.yml@main`
+
+	r := &Pin{
+		resolver:     &mockResolver{}, // Check must not resolve anything
+		ignoreOwners: newNamePatterns([]string{"Finatext"}),
+	}
+	findings, err := r.Check(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, []rewrite.Finding{
+		{Line: 5, Message: "actions/checkout@v4"},
+		{Line: 9, Message: "oasdiff/oasdiff-action/diff@v0"},
+		{Line: 11, Message: "org/repo/.github/workflows/build.yml@main"},
+	}, findings)
+}
+
 func TestIgnoreOwner(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -100,7 +468,7 @@ func TestIgnoreOwner(t *testing.T) {
 			}
 			r := &Pin{
 				resolver:     mock,
-				ignoreOwners: tt.ignoreOwners,
+				ignoreOwners: newNamePatterns(tt.ignoreOwners),
 			}
 
 			got, changed, err := r.replaceLine(context.Background(), tt.input)
@@ -163,7 +531,7 @@ func TestIgnoreRepo(t *testing.T) {
 			}
 			r := &Pin{
 				resolver:    mock,
-				ignoreRepos: tt.ignoreRepos,
+				ignoreRepos: newNamePatterns(tt.ignoreRepos),
 			}
 
 			got, changed, err := r.replaceLine(context.Background(), tt.input)
@@ -230,8 +598,8 @@ func TestCombinedIgnores(t *testing.T) {
 			}
 			r := &Pin{
 				resolver:     mock,
-				ignoreOwners: tt.ignoreOwners,
-				ignoreRepos:  tt.ignoreRepos,
+				ignoreOwners: newNamePatterns(tt.ignoreOwners),
+				ignoreRepos:  newNamePatterns(tt.ignoreRepos),
 			}
 
 			got, changed, err := r.replaceLine(context.Background(), tt.input)
@@ -246,6 +614,107 @@ func TestCombinedIgnores(t *testing.T) {
 	}
 }
 
+func TestOnlyOwnersAndRepos(t *testing.T) {
+	const sha = "abcdef1234567890abcdef1234567890abcdef12"
+	resolveResults := map[string]ResolvedVersion{
+		"my-org/build@v1":     {CommitSHA: sha, RefComment: "v1.0.0"},
+		"my-org/legacy@v1":    {CommitSHA: sha, RefComment: "v1.0.0"},
+		"other/deploy@v1":     {CommitSHA: sha, RefComment: "v1.0.0"},
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		changed      bool
+		onlyOwners   []string
+		onlyRepos    []string
+		ignoreOwners []string
+		ignoreRepos  []string
+	}{
+		{
+			name:    "No allow-list pins everything",
+			input:   "- uses: other/deploy@v1",
+			changed: true,
+		},
+		{
+			name:       "Owner in only-owners",
+			input:      "- uses: my-org/build@v1",
+			changed:    true,
+			onlyOwners: []string{"my-org"},
+		},
+		{
+			name:       "Owner not in only-owners",
+			input:      "- uses: other/deploy@v1",
+			changed:    false,
+			onlyOwners: []string{"my-org"},
+		},
+		{
+			name:      "Repo in only-repos",
+			input:     "- uses: actions/checkout@v4",
+			changed:   true,
+			onlyRepos: []string{"actions/checkout"},
+		},
+		{
+			name:      "Other repo of the same owner not in only-repos",
+			input:     "- uses: my-org/legacy@v1",
+			changed:   false,
+			onlyRepos: []string{"my-org/build"},
+		},
+		{
+			name:       "Either list matches",
+			input:      "- uses: actions/checkout@v4",
+			changed:    true,
+			onlyOwners: []string{"my-org"},
+			onlyRepos:  []string{"actions/checkout"},
+		},
+		{
+			name:        "Ignore-repos applies within only-owners",
+			input:       "- uses: my-org/legacy@v1",
+			changed:     false,
+			onlyOwners:  []string{"my-org"},
+			ignoreRepos: []string{"my-org/legacy"},
+		},
+		{
+			name:         "Ignore-owners applies within only-repos",
+			input:        "- uses: my-org/build@v1",
+			changed:      false,
+			onlyRepos:    []string{"my-org/build"},
+			ignoreOwners: []string{"my-org"},
+		},
+		{
+			name:         "Ignore-owners outside the allow-list changes nothing",
+			input:        "- uses: other/deploy@v1",
+			changed:      false,
+			onlyOwners:   []string{"my-org"},
+			ignoreOwners: []string{"actions"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Pin{
+				resolver:     &mockResolver{resolveResult: resolveResults},
+				onlyOwners:   newNamePatterns(tt.onlyOwners),
+				onlyRepos:    newNamePatterns(tt.onlyRepos),
+				ignoreOwners: newNamePatterns(tt.ignoreOwners),
+				ignoreRepos:  newNamePatterns(tt.ignoreRepos),
+			}
+
+			got, changed, err := r.replaceLine(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, changed)
+			if !tt.changed {
+				assert.Equal(t, tt.input, got)
+			}
+
+			findings, err := r.Check(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, len(findings) == 1, "check reports the same actions as pin")
+		})
+	}
+}
+
 func TestParseLine(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -281,6 +750,32 @@ func TestParseLine(t *testing.T) {
 			wantPrefix:  "- uses: ",
 			wantComment: "# Some comment",
 		},
+		{
+			name:  "Anchored value",
+			input: "- uses: &checkout actions/checkout@v4",
+			wantDef: ActionDef{
+				Owner:    "actions",
+				Repo:     "checkout",
+				Path:     "",
+				RefOrSHA: "v4",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: &checkout ",
+			wantComment: "",
+		},
+		{
+			name:  "Qualified tag ref",
+			input: "- uses: actions/checkout@refs/tags/v4.1.1",
+			wantDef: ActionDef{
+				Owner:    "actions",
+				Repo:     "checkout",
+				Path:     "",
+				RefOrSHA: "refs/tags/v4.1.1",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "",
+		},
 		{
 			name:  "With path",
 			input: "- uses: oasdiff/oasdiff-action/diff@v0",
@@ -320,6 +815,45 @@ func TestParseLine(t *testing.T) {
 			wantPrefix:  "- uses: ",
 			wantComment: "",
 		},
+		{
+			name:  "With commit SHA and path",
+			input: "- uses: owner/repo/sub@11bd71901bbe5b1630ceea73d27597364c9af683 # feature/foo",
+			wantDef: ActionDef{
+				Owner:    "owner",
+				Repo:     "repo",
+				Path:     "sub",
+				RefOrSHA: "11bd71901bbe5b1630ceea73d27597364c9af683",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "# feature/foo",
+		},
+		{
+			name:  "Branch with slash as the whole ref",
+			input: "- uses: owner/repo@feature/foo",
+			wantDef: ActionDef{
+				Owner:    "owner",
+				Repo:     "repo",
+				Path:     "",
+				RefOrSHA: "feature/foo",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "",
+		},
+		{
+			name:  "Branch with slash after a subdirectory",
+			input: "- uses: owner/repo/sub@feature/foo",
+			wantDef: ActionDef{
+				Owner:    "owner",
+				Repo:     "repo",
+				Path:     "sub",
+				RefOrSHA: "feature/foo",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "",
+		},
 		{
 			name:  "No dash prefix",
 			input: "uses: actions/checkout@v4",
@@ -431,61 +965,323 @@ func TestParseLine(t *testing.T) {
 			wantComment: "",
 		},
 		{
-			name:        "With uses in a comment",
-			input:       "# This comment has uses: actions/checkout@v4",
+			name:  "Path containing @",
+			input: "- uses: owner/repo/dir@name@v1 # moved from owner/old@v0",
+			wantDef: ActionDef{
+				Owner:    "owner",
+				Repo:     "repo",
+				Path:     "dir@name",
+				RefOrSHA: "v1",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "# moved from owner/old@v0",
+		},
+		{
+			name:  "Quoted path containing several @",
+			input: "- uses: 'owner/repo/a@b/c@d@v1'",
+			wantDef: ActionDef{
+				Owner:    "owner",
+				Repo:     "repo",
+				Path:     "a@b/c@d",
+				RefOrSHA: "v1",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "",
+		},
+		{
+			name:        "Several @ without a path",
+			input:       "- uses: owner/repo@name@v1",
 			wantDef:     ActionDef{},
-			wantOk:      false, // Should not match "uses" in a comment
+			wantOk:      false, // repo "repo@name" is invalid; skipped rather than guessing the ref
 			wantPrefix:  "",
 			wantComment: "",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			gotParsed, gotOk := parseLine(tt.input)
-			assert.Equal(t, tt.wantOk, gotOk)
-
-			if gotOk {
-				assert.Equal(t, tt.wantDef, gotParsed.def)
-				assert.Equal(t, tt.wantPrefix, gotParsed.prefix)
-				assert.Equal(t, tt.wantComment, gotParsed.comment)
-			}
-		})
-	}
-}
-
-func TestReplaceLine(t *testing.T) {
-	tests := []struct {
-		name           string
-		input          string
-		expected       string
-		changed        bool
-		wantErr        bool
-		resolveResults map[string]ResolvedVersion
-	}{
 		{
-			name:     "Simple action with version",
-			input:    "- uses: actions/checkout@v4",
-			expected: "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
-			changed:  true,
-			resolveResults: map[string]ResolvedVersion{
-				"actions/checkout@v4": {
-					CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
-					RefComment: "v4.2.2",
-				},
-			},
+			name:        "Several @ in the ref of a path",
+			input:       "- uses: \"owner/repo/dir@name@\"",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
 		},
 		{
-			name:     "Action with subdirectory path",
-			input:    "uses: oasdiff/oasdiff-action/diff@v0",
-			expected: "uses: oasdiff/oasdiff-action/diff@1c611ffb1253a72924624aa4fb662e302b3565d3 # v0.0.21",
-			changed:  true,
-			resolveResults: map[string]ResolvedVersion{
-				"oasdiff/oasdiff-action@v0": {
-					CommitSHA:  "1c611ffb1253a72924624aa4fb662e302b3565d3",
-					RefComment: "v0.0.21",
-				},
-			},
+			name:        "Local action",
+			input:       "- uses: ./local",
+			wantDef:     ActionDef{},
+			wantOk:      false, // Local actions are left unchanged
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "Parent-relative action",
+			input:       "      uses: ../shared/action",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "Quoted local action",
+			input:       "- uses: \"./.github/actions/build\" # build",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "Single-quoted parent-relative action",
+			input:       "- 'uses': '../shared/action'",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "Local action with ref",
+			input:       "- uses: ./.github/actions/foo@v1",
+			wantDef:     ActionDef{},
+			wantOk:      false, // Local actions cannot carry a remote ref
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "Parent-relative action with ref",
+			input:       "      uses: ../shared/action@main # comment",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "Quoted local action with ref",
+			input:       "- uses: \"./.github/actions/foo@v1\"",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:  "Dotted owner and repo",
+			input: `- uses: "my.org/repo.name@v1"`,
+			wantDef: ActionDef{
+				Owner:    "my.org",
+				Repo:     "repo.name",
+				RefOrSHA: "v1",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "",
+		},
+		{
+			name:  "Branch with slash",
+			input: "- uses: org/repo@release/2024 # comment",
+			wantDef: ActionDef{
+				Owner:    "org",
+				Repo:     "repo",
+				RefOrSHA: "release/2024",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "# comment",
+		},
+		{
+			name:  "Branch with slash after a path",
+			input: "- uses: my.org/repo.name/sub/dir@release/2024",
+			wantDef: ActionDef{
+				Owner:    "my.org",
+				Repo:     "repo.name",
+				Path:     "sub/dir",
+				RefOrSHA: "release/2024",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "",
+		},
+		{
+			name:  "Quoted reusable workflow on a slash branch",
+			input: "    uses: 'org/repo.js/.github/workflows/ci.yml@release/2024.1'",
+			wantDef: ActionDef{
+				Owner:    "org",
+				Repo:     "repo.js",
+				Path:     ".github/workflows/ci.yml",
+				RefOrSHA: "release/2024.1",
+			},
+			wantOk:      true,
+			wantPrefix:  "    uses: ",
+			wantComment: "",
+		},
+		{
+			name:  "Hash in ref",
+			input: "- uses: org/repo@feat#1 # comment",
+			wantDef: ActionDef{
+				Owner:    "org",
+				Repo:     "repo",
+				RefOrSHA: "feat#1",
+			},
+			wantOk:      true,
+			wantPrefix:  "- uses: ",
+			wantComment: "# comment",
+		},
+		{
+			name:        "Commented out value",
+			input:       "- uses: #org/repo@v1",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:  "Job-level reusable workflow",
+			input: "    uses: 'org/repo/.github/workflows/build.yml@main' # shared",
+			wantDef: ActionDef{
+				Owner:    "org",
+				Repo:     "repo",
+				Path:     ".github/workflows/build.yml",
+				RefOrSHA: "main",
+			},
+			wantOk:      true,
+			wantPrefix:  "    uses: ",
+			wantComment: "# shared",
+		},
+		{
+			name:  "Flow mapping step",
+			input: "  - { uses: actions/checkout@v4 } # checkout",
+			wantDef: ActionDef{
+				Owner:    "actions",
+				Repo:     "checkout",
+				RefOrSHA: "v4",
+			},
+			wantOk:      true,
+			wantPrefix:  "  - { uses: ",
+			wantComment: "# checkout",
+		},
+		{
+			name:  "Flow mapping job",
+			input: "  call: {name: Build, uses: org/repo/.github/workflows/build.yml@main, secrets: inherit}",
+			wantDef: ActionDef{
+				Owner:    "org",
+				Repo:     "repo",
+				Path:     ".github/workflows/build.yml",
+				RefOrSHA: "main",
+			},
+			wantOk:      true,
+			wantPrefix:  "  call: {name: Build, uses: ",
+			wantComment: "",
+		},
+		{
+			name:        "Flow mapping input named uses",
+			input:       "    with: { uses: other/action@v1 }",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "Nested flow mapping input named uses",
+			input:       "  - { uses: actions/checkout@v4, with: { uses: other/action@v1 } }",
+			wantDef:     ActionDef{Owner: "actions", Repo: "checkout", RefOrSHA: "v4"},
+			wantOk:      true,
+			wantPrefix:  "  - { uses: ",
+			wantComment: "",
+		},
+		{
+			name:        "Flow mapping local action",
+			input:       "  - { uses: ./.github/actions/foo@v1 }",
+			wantDef:     ActionDef{},
+			wantOk:      false,
+			wantPrefix:  "",
+			wantComment: "",
+		},
+		{
+			name:        "With uses in a comment",
+			input:       "# This comment has uses: actions/checkout@v4",
+			wantDef:     ActionDef{},
+			wantOk:      false, // Should not match "uses" in a comment
+			wantPrefix:  "",
+			wantComment: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotParsed, gotOk := parseLine(tt.input)
+			assert.Equal(t, tt.wantOk, gotOk)
+
+			if gotOk {
+				assert.Equal(t, tt.wantDef, gotParsed.def)
+				assert.Equal(t, tt.wantPrefix, gotParsed.prefix)
+				assert.Equal(t, tt.wantComment, gotParsed.comment)
+			}
+		})
+	}
+}
+
+func TestReplaceLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expected       string
+		changed        bool
+		wantErr        bool
+		resolveResults map[string]ResolvedVersion
+	}{
+		{
+			name:     "Simple action with version",
+			input:    "- uses: actions/checkout@v4",
+			expected: "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+			changed:  true,
+			resolveResults: map[string]ResolvedVersion{
+				"actions/checkout@v4": {
+					CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+					RefComment: "v4.2.2",
+				},
+			},
+		},
+		{
+			name:     "Subdirectory action on a slash branch",
+			input:    "- uses: owner/repo/sub@feature/foo",
+			expected: "- uses: owner/repo/sub@11bd71901bbe5b1630ceea73d27597364c9af683 # feature/foo",
+			changed:  true,
+			resolveResults: map[string]ResolvedVersion{
+				"owner/repo@feature/foo": {
+					CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+					RefComment: "feature/foo",
+					WasBranch:  true,
+				},
+			},
+		},
+		{
+			name:     "Subdirectory action already pinned to a SHA",
+			input:    "- uses: owner/repo/sub@11bd71901bbe5b1630ceea73d27597364c9af683 # feature/foo",
+			expected: "- uses: owner/repo/sub@11bd71901bbe5b1630ceea73d27597364c9af683 # feature/foo",
+			changed:  false,
+		},
+		{
+			name:     "Dotted repo on a slash branch",
+			input:    "- uses: my.org/repo.name@release/2024",
+			expected: "- uses: my.org/repo.name@aa0779029b74112dc82b436546da0706a57323ad # release/2024",
+			changed:  true,
+			resolveResults: map[string]ResolvedVersion{
+				"my.org/repo.name@release/2024": {
+					CommitSHA:  "aa0779029b74112dc82b436546da0706a57323ad",
+					RefComment: "release/2024",
+					WasBranch:  true,
+				},
+			},
+		},
+		{
+			name:     "Action with subdirectory path",
+			input:    "uses: oasdiff/oasdiff-action/diff@v0",
+			expected: "uses: oasdiff/oasdiff-action/diff@1c611ffb1253a72924624aa4fb662e302b3565d3 # v0.0.21",
+			changed:  true,
+			resolveResults: map[string]ResolvedVersion{
+				"oasdiff/oasdiff-action@v0": {
+					CommitSHA:  "1c611ffb1253a72924624aa4fb662e302b3565d3",
+					RefComment: "v0.0.21",
+				},
+			},
 		},
 		{
 			name:     "Action with deep subdirectory path",
@@ -500,48 +1296,277 @@ func TestReplaceLine(t *testing.T) {
 			},
 		},
 		{
-			name:     "Action with version and comment",
-			input:    "uses: actions/checkout@v4 # Existing comment",
-			expected: "uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 # Existing comment",
+			name:     "Action with version and comment",
+			input:    "uses: actions/checkout@v4 # Existing comment",
+			expected: "uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 # Existing comment",
+			changed:  true,
+			resolveResults: map[string]ResolvedVersion{
+				"actions/checkout@v4": {
+					CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+					RefComment: "v4.2.2",
+				},
+			},
+		},
+		{
+			name:           "Already has SHA",
+			input:          "uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4",
+			expected:       "uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4",
+			changed:        false,
+			resolveResults: map[string]ResolvedVersion{},
+		},
+		{
+			name:     "Path containing @",
+			input:    "- uses: owner/repo/dir@name@v1",
+			expected: "- uses: owner/repo/dir@name@1c611ffb1253a72924624aa4fb662e302b3565d3 # v1.0.0",
+			changed:  true,
+			resolveResults: map[string]ResolvedVersion{
+				"owner/repo/dir@name@v1": {
+					CommitSHA:  "1c611ffb1253a72924624aa4fb662e302b3565d3",
+					RefComment: "v1.0.0",
+				},
+			},
+		},
+		{
+			name:           "Several @ without a path is left unchanged",
+			input:          "- uses: owner/repo@name@v1",
+			expected:       "- uses: owner/repo@name@v1",
+			changed:        false,
+			resolveResults: map[string]ResolvedVersion{},
+		},
+		{
+			name:           "Local action is left unchanged",
+			input:          "- uses: ./local",
+			expected:       "- uses: ./local",
+			changed:        false,
+			resolveResults: map[string]ResolvedVersion{},
+		},
+		{
+			name:           "Quoted parent-relative action is left unchanged",
+			input:          "      uses: '../shared/action' # shared",
+			expected:       "      uses: '../shared/action' # shared",
+			changed:        false,
+			resolveResults: map[string]ResolvedVersion{},
+		},
+		{
+			name:           "Local action with ref is left unchanged",
+			input:          "- uses: ./.github/actions/foo@v1",
+			expected:       "- uses: ./.github/actions/foo@v1",
+			changed:        false,
+			resolveResults: map[string]ResolvedVersion{},
+		},
+		{
+			name:           "Not an action line",
+			input:          "run: echo hello",
+			expected:       "run: echo hello",
+			changed:        false,
+			resolveResults: map[string]ResolvedVersion{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockResolver{
+				resolveResult: tt.resolveResults,
+			}
+			r := &Pin{
+				resolver:     mock,
+				ignoreOwners: newNamePatterns([]string{}),
+			}
+
+			got, changed, err := r.replaceLine(context.Background(), tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
+
+func TestExcludeReusableWorkflows(t *testing.T) {
+	resolveResults := map[string]ResolvedVersion{
+		"actions/checkout@v4": {
+			CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+			RefComment: "v4.2.2",
+		},
+		"org/repo/.github/workflows/build.yml@main": {
+			CommitSHA:  "aa0779029b74112dc82b436546da0706a57323ad",
+			RefComment: "main",
+		},
+		"org/repo/path/to/action@v1": {
+			CommitSHA:  "abcdef1234567890abcdef1234567890abcdef12",
+			RefComment: "v1.0.0",
+		},
+	}
+
+	tests := []struct {
+		name                     string
+		input                    string
+		expected                 string
+		changed                  bool
+		excludeReusableWorkflows bool
+	}{
+		{
+			name:                     "Reusable workflow is skipped",
+			input:                    "    uses: org/repo/.github/workflows/build.yml@main",
+			expected:                 "    uses: org/repo/.github/workflows/build.yml@main",
+			changed:                  false,
+			excludeReusableWorkflows: true,
+		},
+		{
+			name:                     "Action is pinned",
+			input:                    "- uses: actions/checkout@v4",
+			expected:                 "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+			changed:                  true,
+			excludeReusableWorkflows: true,
+		},
+		{
+			name:                     "Composite action with path is pinned",
+			input:                    "- uses: org/repo/path/to/action@v1",
+			expected:                 "- uses: org/repo/path/to/action@abcdef1234567890abcdef1234567890abcdef12 # v1.0.0",
+			changed:                  true,
+			excludeReusableWorkflows: true,
+		},
+		{
+			name:     "Reusable workflow is pinned when disabled",
+			input:    "    uses: org/repo/.github/workflows/build.yml@main",
+			expected: "    uses: org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad # main",
+			changed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Pin{
+				resolver:                 &mockResolver{resolveResult: resolveResults},
+				excludeReusableWorkflows: tt.excludeReusableWorkflows,
+			}
+
+			got, changed, err := r.replaceLine(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
+
+func TestCanonicalNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		resolved ResolvedVersion
+	}{
+		{
+			name:     "Canonical casing replaces user casing",
+			input:    "- uses: Actions/Checkout@v4",
+			expected: "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+			resolved: ResolvedVersion{
+				CommitSHA:      "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment:     "v4.2.2",
+				CanonicalOwner: "actions",
+				CanonicalRepo:  "checkout",
+			},
+		},
+		{
+			name:     "Path casing is kept",
+			input:    "- uses: ORG/Repo/Sub/Dir@v1",
+			expected: "- uses: org/repo/Sub/Dir@abcdef1234567890abcdef1234567890abcdef12 # v1.0.0",
+			resolved: ResolvedVersion{
+				CommitSHA:      "abcdef1234567890abcdef1234567890abcdef12",
+				RefComment:     "v1.0.0",
+				CanonicalOwner: "org",
+				CanonicalRepo:  "repo",
+			},
+		},
+		{
+			name:     "User casing is kept without canonical names",
+			input:    "- uses: Actions/Checkout@v4",
+			expected: "- uses: Actions/Checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+			resolved: ResolvedVersion{
+				CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+				RefComment: "v4.2.2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, ok := parseLine(tt.input)
+			require.True(t, ok)
+			r := &Pin{
+				resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+					parsed.def.Owner + "/" + parsed.def.Repo + "@" + parsed.def.RefOrSHA: tt.resolved,
+				}},
+			}
+
+			got, changed, err := r.replaceLine(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestTrailingWhitespace(t *testing.T) {
+	resolveResults := map[string]ResolvedVersion{
+		"actions/checkout@v4": {
+			CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+			RefComment: "v4.2.2",
+		},
+	}
+
+	tests := []struct {
+		name                    string
+		input                   string
+		expected                string
+		changed                 bool
+		stripTrailingWhitespace bool
+	}{
+		{
+			name:     "No trailing whitespace is introduced",
+			input:    "- uses: actions/checkout@v4",
+			expected: "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+			changed:  true,
+		},
+		{
+			name:     "Existing trailing whitespace is preserved by default",
+			input:    "- uses: actions/checkout@v4  \t",
+			expected: "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2  \t",
 			changed:  true,
-			resolveResults: map[string]ResolvedVersion{
-				"actions/checkout@v4": {
-					CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
-					RefComment: "v4.2.2",
-				},
-			},
 		},
 		{
-			name:           "Already has SHA",
-			input:          "uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4",
-			expected:       "uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4",
-			changed:        false,
-			resolveResults: map[string]ResolvedVersion{},
+			name:     "Trailing whitespace after a comment is preserved by default",
+			input:    "- uses: actions/checkout@v4 # note   ",
+			expected: "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 # note   ",
+			changed:  true,
 		},
 		{
-			name:           "Not an action line",
-			input:          "run: echo hello",
-			expected:       "run: echo hello",
-			changed:        false,
-			resolveResults: map[string]ResolvedVersion{},
+			name:                    "Existing trailing whitespace is stripped when enabled",
+			input:                   "- uses: actions/checkout@v4 # note   ",
+			expected:                "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 # note",
+			changed:                 true,
+			stripTrailingWhitespace: true,
+		},
+		{
+			name:                    "Unmodified lines keep trailing whitespace when enabled",
+			input:                   "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683   ",
+			expected:                "- uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683   ",
+			changed:                 false,
+			stripTrailingWhitespace: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mock := &mockResolver{
-				resolveResult: tt.resolveResults,
-			}
 			r := &Pin{
-				resolver:     mock,
-				ignoreOwners: []string{},
+				resolver:                &mockResolver{resolveResult: resolveResults},
+				stripTrailingWhitespace: tt.stripTrailingWhitespace,
 			}
 
 			got, changed, err := r.replaceLine(context.Background(), tt.input)
-			if tt.wantErr {
-				require.Error(t, err)
-				return
-			}
 			require.NoError(t, err)
 			assert.Equal(t, tt.expected, got)
 			assert.Equal(t, tt.changed, changed)
@@ -549,6 +1574,514 @@ func TestReplaceLine(t *testing.T) {
 	}
 }
 
+func TestMaxLineLength(t *testing.T) {
+	line := "      - uses: actions/checkout@v4"
+	pinned := "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2"
+
+	tests := []struct {
+		name          string
+		maxLineLength int
+		warned        bool
+	}{
+		{name: "Disabled", maxLineLength: 0, warned: false},
+		{name: "Under the limit", maxLineLength: 120, warned: false},
+		{name: "Exactly the limit", maxLineLength: len(pinned), warned: false},
+		{name: "Over the limit", maxLineLength: len(pinned) - 1, warned: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			defer slog.SetDefault(slog.Default())
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+			p := &Pin{
+				resolver: &mockResolver{resolveResult: map[string]ResolvedVersion{
+					"actions/checkout@v4": {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+				}},
+				maxLineLength: tt.maxLineLength,
+			}
+			got, changed, err := p.Apply(context.Background(), line)
+			require.NoError(t, err)
+			assert.True(t, changed, "the line is pinned regardless of its length")
+			assert.Equal(t, pinned, got)
+			assert.Equal(t, tt.warned, strings.Contains(logs.String(), "exceeds max line length"))
+		})
+	}
+}
+
+func TestNormalizeQuotes(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+	}}
+
+	tests := []struct {
+		name     string
+		style    QuoteStyle
+		input    string
+		expected string
+	}{
+		{
+			name:     "Keep double",
+			style:    QuoteKeep,
+			input:    `      - uses: "actions/checkout@v4"`,
+			expected: `      - uses: "actions/checkout@` + sha + `" # v4.2.2`,
+		},
+		{
+			name:     "Unset keeps the original quotes",
+			input:    `      - uses: 'actions/checkout@v4'`,
+			expected: `      - uses: 'actions/checkout@` + sha + `' # v4.2.2`,
+		},
+		{
+			name:     "None removes double quotes",
+			style:    QuoteNone,
+			input:    `      - uses: "actions/checkout@v4"`,
+			expected: `      - uses: actions/checkout@` + sha + ` # v4.2.2`,
+		},
+		{
+			name:     "None removes single quotes",
+			style:    QuoteNone,
+			input:    `      - uses: 'actions/checkout@v4'`,
+			expected: `      - uses: actions/checkout@` + sha + ` # v4.2.2`,
+		},
+		{
+			name:     "Double quotes unquoted",
+			style:    QuoteDouble,
+			input:    `      - uses: actions/checkout@v4`,
+			expected: `      - uses: "actions/checkout@` + sha + `" # v4.2.2`,
+		},
+		{
+			name:     "Double replaces single quotes",
+			style:    QuoteDouble,
+			input:    `      - uses: 'actions/checkout@v4' # Some comment`,
+			expected: `      - uses: "actions/checkout@` + sha + `" # v4.2.2 # Some comment`,
+		},
+		{
+			name:     "Single quotes unquoted",
+			style:    QuoteSingle,
+			input:    `      - uses: actions/checkout@v4`,
+			expected: `      - uses: 'actions/checkout@` + sha + `' # v4.2.2`,
+		},
+		{
+			name:     "Single replaces double quotes",
+			style:    QuoteSingle,
+			input:    `    uses: "actions/checkout@v4"`,
+			expected: `    uses: 'actions/checkout@` + sha + `' # v4.2.2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{resolver: resolver, quoteStyle: tt.style}
+			got, changed, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestQuoteStyle(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		for _, s := range []string{"keep", "none", "double", "single"} {
+			q, err := ParseQuoteStyle(s)
+			require.NoError(t, err)
+			assert.Equal(t, QuoteStyle(s), q)
+		}
+		q, err := ParseQuoteStyle("")
+		require.NoError(t, err)
+		assert.Equal(t, QuoteKeep, q)
+		_, err = ParseQuoteStyle("backtick")
+		require.Error(t, err)
+	})
+
+	t.Run("Keeps quotes YAML requires", func(t *testing.T) {
+		openQuote, closeQuote := QuoteNone.quotes(`"`, `"`, "@org/repo@v1")
+		assert.Equal(t, `"@org/repo@v1"`, openQuote+"@org/repo@v1"+closeQuote)
+		openQuote, closeQuote = QuoteSingle.quotes(`"`, `"`, "org/it's@v1")
+		assert.Equal(t, `"org/it's@v1"`, openQuote+"org/it's@v1"+closeQuote)
+		openQuote, closeQuote = QuoteDouble.quotes("'", "'", `org/"repo"@v1`)
+		assert.Equal(t, `'org/"repo"@v1'`, openQuote+`org/"repo"@v1`+closeQuote)
+	})
+}
+
+func TestExistingComments(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+	}}
+	const pinned = `      - uses: actions/checkout@` + sha
+
+	tests := []struct {
+		name     string
+		comment  string
+		expected string
+	}{
+		{name: "No comment", comment: "", expected: pinned + " # v4.2.2"},
+		{name: "Version only", comment: "# v4.0.0", expected: pinned + " # v4.2.2"},
+		{name: "Version without space", comment: "#v4", expected: pinned + " # v4.2.2"},
+		{name: "Pre-release version", comment: "# 4.0.0-rc.1", expected: pinned + " # v4.2.2"},
+		{name: "Human only", comment: "# pinned for SOC2", expected: pinned + " # v4.2.2 # pinned for SOC2"},
+		{name: "Human note with a hash", comment: "# see issue #12", expected: pinned + " # v4.2.2 # see issue #12"},
+		{name: "Version then human", comment: "# v4.0.0 # pinned for SOC2", expected: pinned + " # v4.2.2 # pinned for SOC2"},
+		{name: "Human then version", comment: "# pinned for SOC2 # v4", expected: pinned + " # pinned for SOC2 # v4.2.2"},
+		{name: "Only the first version is replaced", comment: "# v4 # v3", expected: pinned + " # v4.2.2 # v3"},
+		{name: "Version-like words are notes", comment: "# v4 is required", expected: pinned + " # v4.2.2 # v4 is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{resolver: resolver}
+			input := `      - uses: actions/checkout@v4`
+			if tt.comment != "" {
+				input += " " + tt.comment
+			}
+			got, changed, err := p.Apply(context.Background(), input)
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("No comment removes the stale version", func(t *testing.T) {
+		p := &Pin{resolver: resolver, noComment: true}
+		got, _, err := p.Apply(context.Background(), `      - uses: actions/checkout@v4 # v4.0.0 # pinned for SOC2`)
+		require.NoError(t, err)
+		assert.Equal(t, pinned+" # pinned for SOC2", got)
+
+		got, _, err = p.Apply(context.Background(), `      - uses: actions/checkout@v4 # v4.0.0`)
+		require.NoError(t, err)
+		assert.Equal(t, pinned, got)
+	})
+}
+
+func TestPinToTag(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4":   {CommitSHA: sha, RefComment: "v4.2.2"},
+		"actions/checkout@v4.2": {CommitSHA: sha, RefComment: "v4.2.2"},
+		"org/action@main":       {CommitSHA: sha, RefComment: "main"},
+		"org/action@4":          {CommitSHA: sha, RefComment: "4"}, // Resolved to the branch named 4
+	}}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Major version",
+			input:    `      - uses: actions/checkout@v4`,
+			expected: `      - uses: actions/checkout@v4.2.2 # v4`,
+		},
+		{
+			name:     "Minor version with an existing note",
+			input:    `      - uses: "actions/checkout@v4.2" # pinned for SOC2`,
+			expected: `      - uses: "actions/checkout@v4.2.2" # v4.2 # pinned for SOC2`,
+		},
+		{
+			name:     "Full version is already pinned",
+			input:    `      - uses: actions/checkout@v4.1.1`,
+			expected: `      - uses: actions/checkout@v4.1.1`,
+		},
+		{
+			name:     "Commit SHA is left as is",
+			input:    `      - uses: actions/checkout@` + sha + ` # v4.2.2`,
+			expected: `      - uses: actions/checkout@` + sha + ` # v4.2.2`,
+		},
+		{
+			name:     "Branch is pinned to the SHA",
+			input:    `      - uses: org/action@main`,
+			expected: `      - uses: org/action@` + sha + ` # main`,
+		},
+		{
+			name:     "Version resolved to a branch is pinned to the SHA",
+			input:    `      - uses: org/action@4`,
+			expected: `      - uses: org/action@` + sha + ` # 4`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{resolver: resolver, pinTarget: PinToTag}
+			got, _, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("Changes record the tag", func(t *testing.T) {
+		p := &Pin{resolver: resolver, pinTarget: PinToTag}
+		_, changes, err := p.ApplyChanges(context.Background(), "- uses: actions/checkout@v4\n- uses: org/action@main")
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, "v4.2.2", changes[0].ToRef)
+		assert.Equal(t, sha, changes[0].ToSHA)
+		assert.Empty(t, changes[1].ToRef)
+	})
+
+	t.Run("Check skips full versions", func(t *testing.T) {
+		p := &Pin{pinTarget: PinToTag}
+		findings, err := p.Check(context.Background(), "- uses: actions/checkout@v4\n- uses: actions/checkout@v4.1.1")
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, 1, findings[0].Line)
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		target, err := ParsePinTarget("")
+		require.NoError(t, err)
+		assert.Equal(t, PinToSHA, target)
+		target, err = ParsePinTarget("tag")
+		require.NoError(t, err)
+		assert.Equal(t, PinToTag, target)
+		_, err = ParsePinTarget("branch")
+		require.Error(t, err)
+	})
+}
+
+func TestCommentIncludeDate(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolveResults := map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+	}
+	// Late in the day in UTC-5 is already the next day in UTC; the date is always UTC.
+	now := time.Date(2025, 1, 1, 22, 0, 0, 0, time.FixedZone("EST", -5*60*60))
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Date after the resolved ref",
+			input:    "- uses: actions/checkout@v4",
+			expected: "- uses: actions/checkout@" + sha + " # v4.2.2 @2025-01-02",
+		},
+		{
+			name:     "Stale dated marker is replaced",
+			input:    "- uses: actions/checkout@v4 # v4.0.0 @2024-06-01 # keep me",
+			expected: "- uses: actions/checkout@" + sha + " # v4.2.2 @2025-01-02 # keep me",
+		},
+		{
+			name:     "Other comments are kept",
+			input:    "- uses: actions/checkout@v4 # see issue #12",
+			expected: "- uses: actions/checkout@" + sha + " # v4.2.2 @2025-01-02 # see issue #12",
+		},
+		{
+			name:     "Pinned lines are left as is on re-runs",
+			input:    "- uses: actions/checkout@" + sha + " # v4.2.2 @2024-06-01",
+			expected: "- uses: actions/checkout@" + sha + " # v4.2.2 @2024-06-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{
+				resolver:    &mockResolver{resolveResult: resolveResults},
+				commentDate: true,
+				now:         func() time.Time { return now },
+			}
+			got, _, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+
+			// Running again, even on a later day, changes nothing.
+			later := &Pin{
+				resolver:    &mockResolver{resolveResult: resolveResults},
+				commentDate: true,
+				now:         func() time.Time { return now.AddDate(0, 1, 0) },
+			}
+			again, changed, err := later.Apply(context.Background(), got)
+			require.NoError(t, err)
+			assert.False(t, changed)
+			assert.Equal(t, got, again)
+		})
+	}
+
+	t.Run("No date without a comment", func(t *testing.T) {
+		p := &Pin{
+			resolver:    &mockResolver{resolveResult: resolveResults},
+			commentDate: true,
+			noComment:   true,
+			now:         func() time.Time { return now },
+		}
+		got, _, err := p.Apply(context.Background(), "- uses: actions/checkout@v4")
+		require.NoError(t, err)
+		assert.Equal(t, "- uses: actions/checkout@"+sha, got)
+	})
+}
+
+func TestCommentTemplate(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+	}}
+
+	tests := []struct {
+		name      string
+		template  string
+		noComment bool
+		input     string
+		expected  string
+	}{
+		{
+			name:     "Prefix",
+			template: "pin@{{.RefComment}}",
+			input:    `      - uses: actions/checkout@v4`,
+			expected: `      - uses: actions/checkout@` + sha + ` # pin@v4.2.2`,
+		},
+		{
+			name:     "All fields",
+			template: "{{.Owner}}/{{.Repo}} {{.Ref}} -> {{.RefComment}} ({{slice .SHA 0 7}})",
+			input:    `      - uses: actions/checkout@v4`,
+			expected: `      - uses: actions/checkout@` + sha + ` # actions/checkout v4 -> v4.2.2 (11bd719)`,
+		},
+		{
+			name:     "Existing comment is appended",
+			template: "pin@{{.RefComment}}",
+			input:    `      - uses: actions/checkout@v4 # Some comment`,
+			expected: `      - uses: actions/checkout@` + sha + ` # pin@v4.2.2 # Some comment`,
+		},
+		{
+			name:     "Empty rendering writes no comment",
+			template: "",
+			input:    `      - uses: actions/checkout@v4`,
+			expected: `      - uses: actions/checkout@` + sha,
+		},
+		{
+			name:      "No comment",
+			noComment: true,
+			input:     `      - uses: "actions/checkout@v4"`,
+			expected:  `      - uses: "actions/checkout@` + sha + `"`,
+		},
+		{
+			name:      "No comment keeps the existing comment",
+			noComment: true,
+			input:     `      - uses: actions/checkout@v4 # Some comment`,
+			expected:  `      - uses: actions/checkout@` + sha + ` # Some comment`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{resolver: resolver, noComment: tt.noComment}
+			if !tt.noComment {
+				tmpl, err := ParseCommentTemplate(tt.template)
+				require.NoError(t, err)
+				p.commentTemplate = tmpl
+			}
+			got, changed, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("Invalid templates fail to parse", func(t *testing.T) {
+		for _, s := range []string{"{{.RefComment", "{{.Version}}", "line\n{{.RefComment}}"} {
+			_, err := ParseCommentTemplate(s)
+			assert.Error(t, err, s)
+		}
+	})
+}
+
+func TestNoCommentOnBranchRefs(t *testing.T) {
+	const tagSHA = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	const branchSHA = "f43a0e5ff2bd294095638e18286ca9a3d1956744"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: tagSHA, RefComment: "v4.2.2"},
+		"org/legacy@main":     {CommitSHA: branchSHA, RefComment: "main", WasBranch: true},
+	}}
+	input := `      - uses: actions/checkout@v4
+      - uses: org/legacy@main
+      - uses: org/legacy@main # Some comment`
+
+	tests := []struct {
+		name            string
+		noBranchComment bool
+		expected        string
+	}{
+		{
+			name: "Default comments branch and tag pins",
+			expected: `      - uses: actions/checkout@` + tagSHA + ` # v4.2.2
+      - uses: org/legacy@` + branchSHA + ` # main
+      - uses: org/legacy@` + branchSHA + ` # main # Some comment`,
+		},
+		{
+			name:            "Only tag pins are commented",
+			noBranchComment: true,
+			expected: `      - uses: actions/checkout@` + tagSHA + ` # v4.2.2
+      - uses: org/legacy@` + branchSHA + `
+      - uses: org/legacy@` + branchSHA + ` # Some comment`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{resolver: resolver, noBranchComment: tt.noBranchComment}
+			got, changed, err := p.Apply(context.Background(), input)
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCommentMatchRefPrefix(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	const branchSHA = "f43a0e5ff2bd294095638e18286ca9a3d1956744"
+	// org/bare tags without a "v" prefix, actions/checkout with one.
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"org/bare@v4":         {CommitSHA: sha, RefComment: "4.1.1"},
+		"org/bare@4":          {CommitSHA: sha, RefComment: "4.1.1"},
+		"actions/checkout@4":  {CommitSHA: sha, RefComment: "v4.2.2"},
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+		"org/legacy@main":     {CommitSHA: branchSHA, RefComment: "main", WasBranch: true},
+	}}
+
+	tests := []struct {
+		name           string
+		input          string
+		matchRefPrefix bool
+		expected       string
+	}{
+		{name: "Default writes the tag name", input: "- uses: org/bare@v4", expected: "- uses: org/bare@" + sha + " # 4.1.1"},
+		{name: "v ref against non-v tags", input: "- uses: org/bare@v4", matchRefPrefix: true, expected: "- uses: org/bare@" + sha + " # v4.1.1"},
+		{name: "Non-v ref against non-v tags", input: "- uses: org/bare@4", matchRefPrefix: true, expected: "- uses: org/bare@" + sha + " # 4.1.1"},
+		{name: "Non-v ref against v tags", input: "- uses: actions/checkout@4", matchRefPrefix: true, expected: "- uses: actions/checkout@" + sha + " # 4.2.2"},
+		{name: "v ref against v tags", input: "- uses: actions/checkout@v4", matchRefPrefix: true, expected: "- uses: actions/checkout@" + sha + " # v4.2.2"},
+		{name: "Branches are unaffected", input: "- uses: org/legacy@main", matchRefPrefix: true, expected: "- uses: org/legacy@" + branchSHA + " # main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pin{resolver: resolver, matchRefPrefix: tt.matchRefPrefix}
+			got, changed, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestPinAbbreviatedSHA(t *testing.T) {
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@11bd719": {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+	}}
+	p := &Pin{resolver: resolver}
+	input := "steps:\n  - uses: actions/checkout@11bd719\n"
+
+	got, changes, err := p.ApplyChanges(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "11bd719", changes[0].FromRef)
+	assert.Equal(t, "steps:\n  - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2\n", got)
+
+	findings, err := p.Check(context.Background(), input)
+	require.NoError(t, err)
+	assert.Len(t, findings, 1)
+}
+
 type mockResolver struct {
 	resolveResult map[string]ResolvedVersion
 }
@@ -682,7 +2215,7 @@ func TestStrictPinning202508(t *testing.T) {
 			}
 			r := &Pin{
 				resolver:            mock,
-				ignoreOwners:        tt.ignoreOwners,
+				ignoreOwners:        newNamePatterns(tt.ignoreOwners),
 				strictPinning202508: tt.strictPinning202508,
 			}
 
@@ -697,3 +2230,131 @@ func TestStrictPinning202508(t *testing.T) {
 		})
 	}
 }
+
+func TestApply_FinalNewline(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+	}}
+	p := &Pin{resolver: resolver}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "Changed, ends with newline",
+			input:    "steps:\n  - uses: actions/checkout@v4\n",
+			expected: "steps:\n  - uses: actions/checkout@" + sha + " # v4.2.2\n",
+			changed:  true,
+		},
+		{
+			name:     "Changed, no trailing newline",
+			input:    "steps:\n  - uses: actions/checkout@v4",
+			expected: "steps:\n  - uses: actions/checkout@" + sha + " # v4.2.2",
+			changed:  true,
+		},
+		{
+			name:     "Unchanged, ends with newline",
+			input:    "steps:\n  - run: echo\n",
+			expected: "steps:\n  - run: echo\n",
+		},
+		{
+			name:     "Unchanged, no trailing newline",
+			input:    "steps:\n  - run: echo",
+			expected: "steps:\n  - run: echo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := p.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestFailOnUnresolvable(t *testing.T) {
+	const sha = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: sha, RefComment: "v4.2.2"},
+	}}
+	input := `      - uses: org/missing@v1
+      - uses: actions/checkout@v4`
+
+	t.Run("Unresolvable actions are left unchanged by default", func(t *testing.T) {
+		p := &Pin{resolver: resolver}
+		got, changes, err := p.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, 2, changes[0].Line)
+		assert.Equal(t, `      - uses: org/missing@v1
+      - uses: actions/checkout@`+sha+` # v4.2.2`, got)
+	})
+
+	t.Run("Unresolvable actions fail the file with failOnUnresolvable", func(t *testing.T) {
+		p := &Pin{resolver: resolver, failOnUnresolvable: true}
+		_, _, err := p.ApplyChanges(context.Background(), input)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "org/missing@v1")
+	})
+
+	t.Run("Fallback policy violations always fail", func(t *testing.T) {
+		p := &Pin{resolver: &errorResolver{err: pin.FallbackNotAllowedError}}
+		_, _, err := p.ApplyChanges(context.Background(), input)
+		require.ErrorIs(t, err, pin.FallbackNotAllowedError)
+	})
+
+	t.Run("Timed out runs always fail", func(t *testing.T) {
+		p := &Pin{resolver: &errorResolver{err: errors.Wrap(context.DeadlineExceeded, "failed to list tags")}}
+		_, _, err := p.ApplyChanges(context.Background(), input)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+type errorResolver struct {
+	err error
+}
+
+func (e *errorResolver) ResolveVersion(context.Context, ActionDef) (ResolvedVersion, error) {
+	return ResolvedVersion{}, e.err
+}
+
+// verifyingResolver resolves with mockResolver and verifies commits with mockCommitVerifier.
+type verifyingResolver struct {
+	*mockResolver
+	*mockCommitVerifier
+}
+
+func TestNewPinWithResolver(t *testing.T) {
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/setup-go@v5": {CommitSHA: "d35c59abb061a4a6fb18e82ac0862c26744d6ab5", RefComment: "v5.5.0"},
+		"myorg/deploy@v1":     {CommitSHA: "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b", RefComment: "v1.0.0"},
+	}}
+	input := `steps:
+  - uses: actions/checkout@` + typoSHA + `
+  - uses: actions/setup-go@v5
+  - uses: myorg/deploy@v1`
+
+	t.Run("Options apply", func(t *testing.T) {
+		p := NewPinWithResolver(resolver, Options{IgnoreOwners: []string{"myorg"}, NoComment: true, VerifyExistingSHA: true})
+		got, changes, err := p.ApplyChanges(context.Background(), input)
+		require.NoError(t, err, "mockResolver can't verify commits")
+		require.Len(t, changes, 1)
+		assert.Equal(t, `steps:
+  - uses: actions/checkout@`+typoSHA+`
+  - uses: actions/setup-go@d35c59abb061a4a6fb18e82ac0862c26744d6ab5
+  - uses: myorg/deploy@v1`, got)
+	})
+
+	t.Run("Commits are verified by resolvers able to", func(t *testing.T) {
+		verifier := &mockCommitVerifier{}
+		p := NewPinWithResolver(verifyingResolver{resolver, verifier}, Options{VerifyExistingSHA: true})
+		_, _, err := p.ApplyChanges(context.Background(), input)
+		require.ErrorIs(t, err, pin.CommitNotFoundError)
+		assert.Equal(t, []string{"actions/checkout@" + typoSHA}, verifier.calls)
+	})
+}
@@ -0,0 +1,40 @@
+package pin
+
+import (
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+// Provider is the kind of API server the primary client talks to.
+type Provider string
+
+const (
+	// ProviderGitHub is GitHub.com or GitHub Enterprise Server.
+	ProviderGitHub Provider = "github"
+	// ProviderGitea is Gitea or Forgejo, whose API is largely GitHub-compatible; see pin.GiteaRepositoryService for
+	// the supported operations.
+	ProviderGitea Provider = "gitea"
+)
+
+// ParseProvider parses a --provider value. An empty string means ProviderGitHub.
+func ParseProvider(s string) (Provider, error) {
+	switch p := Provider(s); p {
+	case "":
+		return ProviderGitHub, nil
+	case ProviderGitHub, ProviderGitea:
+		return p, nil
+	default:
+		return "", errors.Newf("invalid provider %q: must be github or gitea", s)
+	}
+}
+
+// repositoryService returns the RepositoryService of client for provider, and its GitService peeling annotated tags;
+// nil when the tag listing already reports the commits.
+func repositoryService(client *gogithub.Client, provider Provider) (pin.RepositoryService, pin.GitService) {
+	if provider == ProviderGitea {
+		return pin.NewGiteaRepositoryService(client), nil
+	}
+	return client.Repositories, client.Git
+}
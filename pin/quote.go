@@ -0,0 +1,65 @@
+package pin
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// QuoteStyle controls how the `uses:` value of a rewritten line is quoted.
+type QuoteStyle string
+
+const (
+	// QuoteKeep keeps the quotes of the original line.
+	QuoteKeep QuoteStyle = "keep"
+	// QuoteNone writes the value unquoted, unless YAML requires quotes.
+	QuoteNone QuoteStyle = "none"
+	// QuoteDouble writes the value in double quotes.
+	QuoteDouble QuoteStyle = "double"
+	// QuoteSingle writes the value in single quotes.
+	QuoteSingle QuoteStyle = "single"
+)
+
+// ParseQuoteStyle parses a --normalize-quotes value. An empty string means QuoteKeep.
+func ParseQuoteStyle(s string) (QuoteStyle, error) {
+	switch q := QuoteStyle(s); q {
+	case "":
+		return QuoteKeep, nil
+	case QuoteKeep, QuoteNone, QuoteDouble, QuoteSingle:
+		return q, nil
+	default:
+		return "", errors.Newf("invalid quote style %q: must be one of keep, none, double or single", s)
+	}
+}
+
+// quotes returns the opening and closing quotes to write around value, given the quotes of the original line.
+// Whenever the style would produce invalid YAML for value, the original quotes are kept.
+func (q QuoteStyle) quotes(openQuote, closeQuote, value string) (string, string) {
+	switch q {
+	case QuoteNone:
+		if !needsQuoting(value) {
+			return "", ""
+		}
+	case QuoteDouble:
+		if !strings.ContainsAny(value, `"\`) {
+			return `"`, `"`
+		}
+	case QuoteSingle:
+		if !strings.Contains(value, "'") {
+			return "'", "'"
+		}
+	}
+	return openQuote, closeQuote
+}
+
+// needsQuoting reports whether value can't be written as a YAML plain scalar as is.
+func needsQuoting(value string) bool {
+	if value == "" || strings.TrimSpace(value) != value {
+		return true
+	}
+	// Indicator characters can't start a plain scalar.
+	if strings.ContainsAny(value[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	return strings.Contains(value, ": ") || strings.Contains(value, " #") || strings.HasSuffix(value, ":")
+}
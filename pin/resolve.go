@@ -0,0 +1,117 @@
+package pin
+
+import (
+	"cmp"
+	"context"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+// Resolution is what an action reference resolves to, as Apply would pin it.
+type Resolution struct {
+	Owner string
+	Repo  string
+	// The ref as written in the workflows.
+	Ref       string
+	CommitSHA string
+	// The resolved tag or branch written in the comment, e.g. v4.1.1.
+	RefComment string
+}
+
+// ResolveAll resolves each distinct owner/repo@ref of findings (as returned by Check) with up to concurrency
+// resolutions in flight, filling the resolution cache without touching any file. Zero or negative concurrency uses
+// GOMAXPROCS. Resolutions are sorted by owner, repo and ref. Refs failing to resolve are reported in the error while
+// the others are returned.
+func (p *Pin) ResolveAll(ctx context.Context, findings []rewrite.Finding, concurrency int) ([]Resolution, error) {
+	defs := distinctActions(findings)
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	resolutions := make([]Resolution, len(defs))
+	errs := make([]error, len(defs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(defs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				def := defs[i]
+				resolved, err := p.resolver.ResolveVersion(ctx, def)
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "failed to resolve %s", def.String())
+					continue
+				}
+				resolutions[i] = Resolution{
+					Owner:      def.Owner,
+					Repo:       def.Repo,
+					Ref:        def.RefOrSHA,
+					CommitSHA:  resolved.CommitSHA,
+					RefComment: resolved.RefComment,
+				}
+			}
+		}()
+	}
+	for i := range defs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var resolved []Resolution
+	for i := range defs {
+		if errs[i] == nil {
+			resolved = append(resolved, resolutions[i])
+		}
+	}
+	slices.SortFunc(resolved, func(a, b Resolution) int {
+		return cmp.Or(cmp.Compare(a.Owner, b.Owner), cmp.Compare(a.Repo, b.Repo), cmp.Compare(a.Ref, b.Ref))
+	})
+	if err := errors.Join(errs...); err != nil {
+		return resolved, err
+	}
+	return resolved, nil
+}
+
+// CountResolutions returns the number of distinct owner/repo@ref of findings (as returned by Check) that ResolveAll
+// or Apply would resolve, e.g. to estimate the API calls of a run.
+func CountResolutions(findings []rewrite.Finding) int {
+	return len(distinctActions(findings))
+}
+
+// distinctActions returns the actions of findings to resolve, once per owner/repo@ref, in order of appearance.
+func distinctActions(findings []rewrite.Finding) []pin.ActionDef {
+	seen := make(map[string]struct{})
+	var defs []pin.ActionDef
+	for _, finding := range findings {
+		def, ok := parseActionRef(finding.Message)
+		if !ok || def.HasCommitSHA() {
+			continue // Nothing to resolve, e.g. a SHA to lowercase with NormalizeSHA
+		}
+		key := def.Owner + "/" + def.Repo + "@" + def.RefOrSHA
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// ChangedResolutions returns the resolutions of the actions that changed at least one line, e.g. per the changes of
+// RewriteResult.Files with RewriteOptions.OnlyChangedLines, dropping those that left every line as it was.
+func ChangedResolutions(resolutions []Resolution, changes []rewrite.Change) []Resolution {
+	changed := make(map[string]bool)
+	for _, c := range changes {
+		changed[c.Owner+"/"+c.Repo+"@"+c.FromRef] = true
+	}
+	return slices.DeleteFunc(slices.Clone(resolutions), func(r Resolution) bool {
+		return !changed[r.Owner+"/"+r.Repo+"@"+r.Ref]
+	})
+}
@@ -0,0 +1,106 @@
+package pin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+func TestResolveAll(t *testing.T) {
+	input := `jobs:
+  build:
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/checkout@v4
+      - uses: org/monorepo/sub-a@v1
+      - uses: org/monorepo/sub-b@v1
+      - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0
+      - uses: org/legacy@main
+      - uses: ./local-action`
+
+	resolver := &countingResolver{mockResolver: mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+		"org/monorepo@v1":     {CommitSHA: "aa0779029b74112dc82b436546da0706a57323ad", RefComment: "v1.3.0"},
+		"org/legacy@main":     {CommitSHA: "f43a0e5ff2bd294095638e18286ca9a3d1956744", RefComment: "main"},
+	}}}
+	p := &Pin{resolver: resolver}
+
+	findings, err := p.Check(context.Background(), input)
+	require.NoError(t, err)
+
+	resolutions, err := p.ResolveAll(context.Background(), findings, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []Resolution{
+		{Owner: "actions", Repo: "checkout", Ref: "v4", CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+		{Owner: "org", Repo: "legacy", Ref: "main", CommitSHA: "f43a0e5ff2bd294095638e18286ca9a3d1956744", RefComment: "main"},
+		{Owner: "org", Repo: "monorepo", Ref: "v1", CommitSHA: "aa0779029b74112dc82b436546da0706a57323ad", RefComment: "v1.3.0"},
+	}, resolutions, "every distinct owner/repo@ref is resolved once, pinned and local references are skipped")
+	assert.Equal(t, map[string]int{"actions/checkout@v4": 1, "org/monorepo@v1": 1, "org/legacy@main": 1}, resolver.calls)
+
+	t.Run("Files are not written", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "build.yml")
+		require.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+
+		findings, err := rewrite.Check(context.Background(), []string{path}, rewrite.RewriteOptions{}, p.Check)
+		require.NoError(t, err)
+		resolutions, err := p.ResolveAll(context.Background(), findings, 0)
+		require.NoError(t, err)
+		assert.Len(t, resolutions, 3)
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, input, string(b))
+	})
+
+	t.Run("Failures are reported alongside the resolutions", func(t *testing.T) {
+		findings, err := p.Check(context.Background(), "- uses: actions/checkout@v4\n- uses: org/missing@v1")
+		require.NoError(t, err)
+
+		resolutions, err := p.ResolveAll(context.Background(), findings, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "org/missing@v1")
+		require.Len(t, resolutions, 1)
+		assert.Equal(t, "checkout", resolutions[0].Repo)
+	})
+}
+
+// countingResolver is a mockResolver safe for concurrent use, counting the resolutions of each reference.
+type countingResolver struct {
+	mockResolver
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (r *countingResolver) ResolveVersion(ctx context.Context, def ActionDef) (ResolvedVersion, error) {
+	r.mu.Lock()
+	if r.calls == nil {
+		r.calls = make(map[string]int)
+	}
+	r.calls[def.Owner+"/"+def.Repo+"@"+def.RefOrSHA]++
+	r.mu.Unlock()
+	return r.mockResolver.ResolveVersion(ctx, def)
+}
+
+func TestChangedResolutions(t *testing.T) {
+	resolutions := []Resolution{
+		{Owner: "actions", Repo: "checkout", Ref: "v4", CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+		{Owner: "org", Repo: "legacy", Ref: "main", CommitSHA: "f43a0e5ff2bd294095638e18286ca9a3d1956744", RefComment: "main"},
+		{Owner: "org", Repo: "monorepo", Ref: "v1", CommitSHA: "aa0779029b74112dc82b436546da0706a57323ad", RefComment: "v1.3.0"},
+	}
+	changes := []rewrite.Change{
+		{Line: 4, Owner: "actions", Repo: "checkout", FromRef: "v4"},
+		{Line: 2, Owner: "org", Repo: "monorepo", Path: "sub-a", FromRef: "v1"},
+	}
+
+	assert.Equal(t, []Resolution{resolutions[0], resolutions[2]}, ChangedResolutions(resolutions, changes),
+		"org/legacy@main changed no line")
+	assert.Len(t, resolutions, 3, "the resolutions given are left as they were")
+	assert.Empty(t, ChangedResolutions(resolutions, nil))
+}
@@ -0,0 +1,49 @@
+package pin
+
+import (
+	"context"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+// ActionDef is an action reference (owner/repo[/path]@ref) to resolve.
+type ActionDef = pin.ActionDef
+
+// ResolvedVersion is what an action reference resolves to: the commit SHA and the ref written in the comment.
+type ResolvedVersion = pin.ResolvedVersion
+
+// Errors returned by resolvers, see Resolver.
+var (
+	// The reference is already pinned to a full commit SHA; there is nothing to resolve.
+	AlreadyResolvedError = pin.AlreadyResolvedError
+	// The ref, or its repository, doesn't exist.
+	RefNotFoundError = pin.RefNotFoundError
+	// The commit an already pinned reference points at doesn't exist in its repository.
+	CommitNotFoundError = pin.CommitNotFoundError
+)
+
+// Resolver resolves action references to the commits they are pinned to. The default resolver lists the tags and
+// refs of the repository through the GitHub API; custom implementations, passed with Options.Resolver or
+// NewPinWithResolver, can resolve from elsewhere, e.g. a lockfile, a vendored cache, a proxy service or a test double.
+//
+// ResolveVersion is called concurrently and must be safe for concurrent use. For a resolvable def it returns:
+//
+//   - CommitSHA: the full, lowercase commit SHA the line is pinned to;
+//   - RefComment: the tag or branch written in the comment after the SHA (e.g. v4.1.1 for v4), or def.RefOrSHA;
+//   - WasBranch: whether the ref is a branch, whose head moves (see Options.NoCommentOnBranchRefs);
+//   - CanonicalOwner and CanonicalRepo: optionally, the owner/repo casing to write instead of the one of def.
+//
+// Otherwise it returns an error, which decides what happens to the line:
+//
+//   - wrapping AlreadyResolvedError, when def.HasCommitSHA reports that it is already pinned: the line is left as is
+//     without warning;
+//   - for failures specific to the action, e.g. wrapping RefNotFoundError: the line is left unchanged with a warning,
+//     or the file fails with Options.FailOnUnresolvable;
+//   - for failures of the backend itself, wrapping a *github.ErrorResponse with a 401 or 5xx status, a rate limit
+//     error, or the error of the canceled ctx: the file fails, as these affect every action.
+//
+// VerifyExistingSHA additionally requires VerifyCommit(ctx, owner, repo, sha string) error, returning an error
+// wrapping CommitNotFoundError for commits missing from their repository.
+type Resolver interface {
+	ResolveVersion(ctx context.Context, def ActionDef) (ResolvedVersion, error)
+}
@@ -0,0 +1,49 @@
+package pin
+
+import (
+	"strings"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+// uppercaseSHA parses line and reports whether it references an action selected by the ignore/exclude options that
+// is pinned to a commit SHA with uppercase hex digits, to lowercase with NormalizeSHA.
+func (p *Pin) uppercaseSHA(line string) (pin.ActionDef, bool) {
+	if !p.normalizeSHA {
+		return pin.ActionDef{}, false
+	}
+	parsed, ok := p.parseCandidate(line)
+	if !ok || !parsed.def.HasCommitSHA() || parsed.def.RefOrSHA == strings.ToLower(parsed.def.RefOrSHA) {
+		return pin.ActionDef{}, false
+	}
+	return parsed.def, true
+}
+
+// uppercaseFlowSHAs returns the actions of a line holding several flow mappings that uppercaseSHA selects.
+func (p *Pin) uppercaseFlowSHAs(line string) []pin.ActionDef {
+	body, _ := splitFlowComment(line)
+	var defs []pin.ActionDef
+	for _, key := range flowUsesKeyPattern.FindAllString(body, -1) {
+		if def, ok := p.uppercaseSHA("{" + key[1:] + "}"); ok {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// lowercaseSHA rewrites the commit SHA of def in text in lowercase, leaving everything else as is.
+func lowercaseSHA(text string, def pin.ActionDef) string {
+	return strings.Replace(text, "@"+def.RefOrSHA, "@"+strings.ToLower(def.RefOrSHA), 1)
+}
+
+// shaCaseChange records the lowercasing of the commit SHA of def.
+func shaCaseChange(def pin.ActionDef) rewrite.Change {
+	return rewrite.Change{
+		Owner:   def.Owner,
+		Repo:    def.Repo,
+		Path:    def.Path,
+		FromRef: def.RefOrSHA,
+		ToSHA:   strings.ToLower(def.RefOrSHA),
+	}
+}
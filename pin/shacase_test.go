@@ -0,0 +1,77 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeSHA(t *testing.T) {
+	const upper = "11BD71901BBE5B1630CEEA73D27597364C9AF683"
+	const lower = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/setup-go@v5": {CommitSHA: "d35c59abb061a4a6fb18e82ac0862c26744d6ab5", RefComment: "v5.5.0"},
+	}}
+
+	t.Run("Uppercase SHA is lowercased", func(t *testing.T) {
+		p := &Pin{resolver: resolver, normalizeSHA: true}
+		input := `steps:
+  - uses: "actions/checkout@` + upper + `" # v4.2.2
+  - uses: actions/setup-go@v5`
+		got, changes, err := p.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		assert.Equal(t, `steps:
+  - uses: "actions/checkout@`+lower+`" # v4.2.2
+  - uses: actions/setup-go@d35c59abb061a4a6fb18e82ac0862c26744d6ab5 # v5.5.0`, got)
+		require.Len(t, changes, 2)
+		assert.Equal(t, 2, changes[0].Line)
+		assert.Equal(t, upper, changes[0].FromRef)
+		assert.Equal(t, lower, changes[0].ToSHA)
+	})
+
+	t.Run("File with only casing changes is changed", func(t *testing.T) {
+		p := &Pin{resolver: resolver, normalizeSHA: true}
+		input := "steps:\n  - uses: actions/checkout@" + upper + "\n"
+		got, changed, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "steps:\n  - uses: actions/checkout@"+lower+"\n", got)
+
+		findings, err := p.Check(context.Background(), input)
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, "actions/checkout@"+upper, findings[0].Message)
+	})
+
+	t.Run("Multi-flow lines", func(t *testing.T) {
+		p := &Pin{resolver: resolver, normalizeSHA: true}
+		input := "steps: [{uses: actions/checkout@" + upper + "}, {uses: actions/setup-go@v5}] # keep"
+		got, changes, err := p.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		assert.Len(t, changes, 2)
+		assert.Equal(t, "steps: [{uses: actions/checkout@"+lower+"}, {uses: actions/setup-go@d35c59abb061a4a6fb18e82ac0862c26744d6ab5}] # v5.5.0 # keep", got)
+
+		findings, err := p.Check(context.Background(), input)
+		require.NoError(t, err)
+		assert.Len(t, findings, 2)
+	})
+
+	t.Run("Lowercase SHAs and ignored actions are left as is", func(t *testing.T) {
+		p := &Pin{resolver: resolver, normalizeSHA: true, ignoreRepos: newNamePatterns([]string{"org/vendored"})}
+		input := "steps:\n  - uses: actions/checkout@" + lower + "\n  - uses: org/vendored@" + upper + "\n"
+		got, changed, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, input, got)
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		p := &Pin{resolver: resolver}
+		input := "steps:\n  - uses: actions/checkout@" + upper + "\n"
+		_, changed, err := p.Apply(context.Background(), input)
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+}
@@ -0,0 +1,42 @@
+package pin
+
+import (
+	"regexp"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+// PinTarget is what version refs are pinned to.
+type PinTarget string
+
+const (
+	// PinToSHA pins to the commit SHA, e.g. `@v4` to `@<sha> # v4.1.1`.
+	PinToSHA PinTarget = "sha"
+	// PinToTag pins to the fully qualified tag, e.g. `@v4` to `@v4.1.1 # v4`. Branch refs are still pinned to SHAs.
+	PinToTag PinTarget = "tag"
+)
+
+// ParsePinTarget parses a --pin-to value. An empty string means PinToSHA.
+func ParsePinTarget(s string) (PinTarget, error) {
+	switch t := PinTarget(s); t {
+	case "":
+		return PinToSHA, nil
+	case PinToSHA, PinToTag:
+		return t, nil
+	default:
+		return "", errors.Newf("invalid pin target %q: must be sha or tag", s)
+	}
+}
+
+// fullVersionPattern matches refs naming a single version (major.minor.patch), which are pinned already when
+// pinning to tags.
+var fullVersionPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+([-+][0-9A-Za-z.+-]*)?$`)
+
+// pinsToTag reports whether def is pinned to the tag it resolved to rather than a commit SHA. Refs resolving to
+// something else than a distinct tag, such as a branch (see pin.ResolverOptions.PreferBranches) or a `git describe`
+// ref, are pinned to the commit SHA.
+func (p *Pin) pinsToTag(def pin.ActionDef, resolved pin.ResolvedVersion) bool {
+	return p.pinTarget == PinToTag && def.VersionTag() != nil && resolved.RefComment != def.RefOrSHA
+}
@@ -0,0 +1,164 @@
+package pin
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/Finatext/gha-fix/internal/rewrite"
+)
+
+type trustResolver interface {
+	Resolver
+	tagFinder
+	CommitInfo(ctx context.Context, owner, repo, sha string) (pin.CommitInfo, error)
+}
+
+// Trust reports, for each distinct action reference, the commit it resolves to, whether that commit's signature is
+// verified, and how old it is, to help triage supply-chain risk.
+type Trust struct {
+	resolver trustResolver
+	now      func() time.Time
+}
+
+// TrustOptions configures how Trust resolves actions.
+type TrustOptions struct {
+	// Fail instead of falling back to GitHub.com when the primary API returns 404.
+	FailOnFallback bool
+	// Total number of API retries allowed across the whole run. Zero or negative means unlimited.
+	RetryBudget int
+}
+
+// TrustEntry is the trust information of one owner/repo@ref.
+type TrustEntry struct {
+	Owner string
+	Repo  string
+	// The ref as written in the workflows: a version, a branch or a commit SHA.
+	Ref       string
+	CommitSHA string
+	// The tag the ref resolved to, or the highest tag pointing at a pinned commit. Empty when there is none.
+	Tag string
+	// Whether GitHub verified the commit signature, and why (e.g. "valid" or "unsigned").
+	Verified           bool
+	VerificationReason string
+	// Committer date of the commit, and its age at the time of the report.
+	CommitDate time.Time
+	Age        time.Duration
+}
+
+// NewTrust creates a trust report command with primary GitHub client and optional fallback GitHub.com client.
+func NewTrust(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts TrustOptions) Trust {
+	return Trust{
+		resolver: newVersionResolver(primaryClient, fallbackClient, ProviderGitHub, opts.RetryBudget, pin.RetryOptions{}, pin.ResolverOptions{
+			FailOnFallback: opts.FailOnFallback,
+		}),
+		now: time.Now,
+	}
+}
+
+// Scan reports every remote action reference in input, pinned or not. Each finding's Message is owner/repo@ref.
+func (t *Trust) Scan(_ context.Context, input string) ([]rewrite.Finding, error) {
+	var findings []rewrite.Finding
+	var scope lineScope
+	for i, line := range strings.Split(input, "\n") {
+		if !scope.next(line) {
+			continue
+		}
+		parsed, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+		findings = append(findings, rewrite.Finding{
+			Line:    i + 1,
+			Message: parsed.def.Owner + "/" + parsed.def.Repo + "@" + parsed.def.RefOrSHA,
+		})
+	}
+	return findings, nil
+}
+
+// Report resolves each distinct owner/repo@ref of findings (as returned by Scan) and returns their trust entries
+// sorted by owner, repo and ref. Refs failing to resolve are reported in the error while the others are returned.
+func (t *Trust) Report(ctx context.Context, findings []rewrite.Finding) ([]TrustEntry, error) {
+	seen := make(map[string]struct{})
+	var entries []TrustEntry
+	var errs []error
+	for _, finding := range findings {
+		if _, ok := seen[finding.Message]; ok {
+			continue
+		}
+		seen[finding.Message] = struct{}{}
+
+		def, ok := parseActionRef(finding.Message)
+		if !ok {
+			continue
+		}
+		entry, err := t.entry(ctx, def)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b TrustEntry) int {
+		return cmp.Or(cmp.Compare(a.Owner, b.Owner), cmp.Compare(a.Repo, b.Repo), cmp.Compare(a.Ref, b.Ref))
+	})
+	if len(errs) > 0 {
+		return entries, errors.Join(errs...)
+	}
+	return entries, nil
+}
+
+func (t *Trust) entry(ctx context.Context, def pin.ActionDef) (TrustEntry, error) {
+	entry := TrustEntry{Owner: def.Owner, Repo: def.Repo, Ref: def.RefOrSHA}
+	if def.HasCommitSHA() {
+		entry.CommitSHA = def.RefOrSHA
+		tag, err := t.resolver.FindTagForSHA(ctx, def)
+		if err != nil && !errors.Is(err, pin.TagForSHANotFoundError) {
+			return TrustEntry{}, errors.Wrapf(err, "failed to find tag for %s", def.String())
+		}
+		entry.Tag = tag
+	} else {
+		resolved, err := t.resolver.ResolveVersion(ctx, def)
+		if err != nil {
+			return TrustEntry{}, errors.Wrapf(err, "failed to resolve %s", def.String())
+		}
+		entry.CommitSHA = resolved.CommitSHA
+		if def.VersionTag() != nil {
+			entry.Tag = resolved.RefComment
+		}
+	}
+
+	info, err := t.resolver.CommitInfo(ctx, def.Owner, def.Repo, entry.CommitSHA)
+	if err != nil {
+		return TrustEntry{}, errors.Wrapf(err, "failed to get commit for %s", def.String())
+	}
+	entry.Verified = info.Verified
+	entry.VerificationReason = info.VerificationReason
+	entry.CommitDate = info.Date
+	entry.Age = t.now().Sub(info.Date)
+	return entry, nil
+}
+
+// parseActionRef parses owner/repo[/path]@ref as written in a `uses:` value.
+func parseActionRef(s string) (pin.ActionDef, bool) {
+	repoPath, ref, ok := strings.Cut(s, "@")
+	if !ok || ref == "" {
+		return pin.ActionDef{}, false
+	}
+	parts := strings.SplitN(repoPath, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return pin.ActionDef{}, false
+	}
+	def := pin.ActionDef{Owner: parts[0], Repo: parts[1], RefOrSHA: ref}
+	if len(parts) == 3 {
+		def.Path = parts[2]
+	}
+	return def, true
+}
@@ -0,0 +1,117 @@
+package pin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+func TestTrust(t *testing.T) {
+	input := `jobs:
+  build:
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0
+      - uses: org/legacy@main
+      - uses: ./local-action
+  call:
+    uses: org/workflows/.github/workflows/build.yml@11bd71901bbe5b1630ceea73d27597364c9af683`
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	resolver := &mockTrustResolver{
+		mockResolver: mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4": {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+			"org/legacy@main":     {CommitSHA: "aa0779029b74112dc82b436546da0706a57323ad", RefComment: "main"},
+		}},
+		tags: map[string]string{"actions/setup-go": "v5.4.0"},
+		commits: map[string]pin.CommitInfo{
+			// actions/checkout and org/workflows share the same SHA in this test.
+			"11bd71901bbe5b1630ceea73d27597364c9af683": {Date: now.Add(-30 * 24 * time.Hour), Verified: true, VerificationReason: "valid"},
+			"0aaccfd150d50ccaeb58ebd88d36e91967a5f35b": {Date: now.Add(-2 * 24 * time.Hour), Verified: true, VerificationReason: "valid"},
+			"aa0779029b74112dc82b436546da0706a57323ad": {Date: now.Add(-3 * 365 * 24 * time.Hour), VerificationReason: "unsigned"},
+		},
+	}
+	tr := &Trust{resolver: resolver, now: func() time.Time { return now }}
+
+	findings, err := tr.Scan(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, findings, 5, "every remote reference is found, pinned or not")
+
+	entries, err := tr.Report(context.Background(), findings)
+	require.NoError(t, err)
+	require.Len(t, entries, 4, "duplicate references are reported once")
+
+	// Sorted by owner, repo and ref.
+	assert.Equal(t, TrustEntry{
+		Owner: "actions", Repo: "checkout", Ref: "v4",
+		CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", Tag: "v4.2.2",
+		Verified: true, VerificationReason: "valid",
+		CommitDate: now.Add(-30 * 24 * time.Hour), Age: 30 * 24 * time.Hour,
+	}, entries[0])
+	assert.Equal(t, TrustEntry{
+		Owner: "actions", Repo: "setup-go", Ref: "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b",
+		CommitSHA: "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b", Tag: "v5.4.0",
+		Verified: true, VerificationReason: "valid",
+		CommitDate: now.Add(-2 * 24 * time.Hour), Age: 2 * 24 * time.Hour,
+	}, entries[1])
+	assert.Equal(t, TrustEntry{
+		Owner: "org", Repo: "legacy", Ref: "main",
+		CommitSHA:          "aa0779029b74112dc82b436546da0706a57323ad",
+		VerificationReason: "unsigned",
+		CommitDate:         now.Add(-3 * 365 * 24 * time.Hour), Age: 3 * 365 * 24 * time.Hour,
+	}, entries[2], "branches have no tag")
+	assert.Equal(t, TrustEntry{
+		Owner: "org", Repo: "workflows", Ref: "11bd71901bbe5b1630ceea73d27597364c9af683",
+		CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683",
+		Verified:  true, VerificationReason: "valid",
+		CommitDate: now.Add(-30 * 24 * time.Hour), Age: 30 * 24 * time.Hour,
+	}, entries[3], "pinned commits without a tag have no tag")
+}
+
+func TestTrust_ReportContinuesOnError(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	resolver := &mockTrustResolver{
+		mockResolver: mockResolver{resolveResult: map[string]ResolvedVersion{
+			"actions/checkout@v4": {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+		}},
+		commits: map[string]pin.CommitInfo{
+			"11bd71901bbe5b1630ceea73d27597364c9af683": {Date: now, Verified: true, VerificationReason: "valid"},
+		},
+	}
+	tr := &Trust{resolver: resolver, now: func() time.Time { return now }}
+
+	findings, err := tr.Scan(context.Background(), "- uses: actions/checkout@v4\n- uses: org/missing@v1")
+	require.NoError(t, err)
+
+	entries, err := tr.Report(context.Background(), findings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "org/missing@v1")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "checkout", entries[0].Repo)
+}
+
+type mockTrustResolver struct {
+	mockResolver
+	tags    map[string]string // owner/repo -> tag at the pinned commit
+	commits map[string]pin.CommitInfo
+}
+
+func (m *mockTrustResolver) FindTagForSHA(_ context.Context, def ActionDef) (string, error) {
+	if tag, ok := m.tags[def.Owner+"/"+def.Repo]; ok {
+		return tag, nil
+	}
+	return "", pin.TagForSHANotFoundError
+}
+
+func (m *mockTrustResolver) CommitInfo(_ context.Context, _, _, sha string) (pin.CommitInfo, error) {
+	if info, ok := m.commits[sha]; ok {
+		return info, nil
+	}
+	return pin.CommitInfo{}, pin.TagNotFoundError
+}
@@ -0,0 +1,129 @@
+package pin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+type tagFinder interface {
+	FindTagForSHA(ctx context.Context, def pin.ActionDef) (string, error)
+}
+
+// Unpin reverses Pin: `owner/repo@<sha> # v4.1.1` becomes `owner/repo@v4.1.1`.
+type Unpin struct {
+	// Used only with forceAPI; nil otherwise.
+	finder tagFinder
+	// Look up a tag pointing at the SHA when the line has no recoverable ref comment.
+	forceAPI bool
+}
+
+// UnpinOptions configures how Unpin recovers refs.
+type UnpinOptions struct {
+	// Re-resolve the ref from the API when a pinned line has no ref comment. Requires a GitHub client.
+	ForceAPI bool
+}
+
+// NewUnpin creates an unpin command. The GitHub clients are only used with UnpinOptions.ForceAPI, and may be nil
+// otherwise; fallbackClient (GitHub.com) is optional as in NewPin.
+func NewUnpin(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts UnpinOptions) Unpin {
+	u := Unpin{forceAPI: opts.ForceAPI}
+	if opts.ForceAPI {
+		u.finder = newVersionResolver(primaryClient, fallbackClient, ProviderGitHub, 0, pin.RetryOptions{}, pin.ResolverOptions{})
+	}
+	return u
+}
+
+// Apply replaces pinned commit SHAs in input YAML content with the refs recorded in their comments, then returns the
+// modified content, a boolean indicating if any replacements were made, and an error if any occurred.
+func (u *Unpin) Apply(ctx context.Context, input string) (string, bool, error) {
+	lines := strings.Split(input, "\n")
+
+	changed := false
+	resultLines := make([]string, 0, len(lines))
+
+	var errs []error
+	var scope lineScope
+	for _, line := range lines {
+		if !scope.next(line) {
+			resultLines = append(resultLines, line)
+			continue
+		}
+
+		modifiedLine, lineChanged, err := u.replaceLine(ctx, line)
+		if err != nil {
+			// Collect errors but continue processing remaining actions/lines.
+			errs = append(errs, err)
+			resultLines = append(resultLines, line)
+			continue
+		}
+
+		if lineChanged {
+			changed = true
+			line = modifiedLine
+		}
+		resultLines = append(resultLines, line)
+	}
+
+	output := strings.Join(resultLines, "\n")
+
+	if len(errs) > 0 {
+		return output, changed, errors.Join(errs...)
+	}
+	return output, changed, nil
+}
+
+func (u *Unpin) replaceLine(ctx context.Context, line string) (string, bool, error) {
+	parsed, ok := parseLine(line)
+	if !ok || !parsed.def.HasCommitSHA() {
+		return line, false, nil
+	}
+	def := parsed.def
+
+	ref, rest, ok := splitRefComment(parsed.comment)
+	if !ok {
+		if !u.forceAPI {
+			return line, false, nil // No recoverable ref, leave the pin as is
+		}
+		var err error
+		ref, err = u.finder.FindTagForSHA(ctx, def)
+		if err != nil {
+			return "", false, errors.Wrapf(err, "failed to find ref for %s", def.String())
+		}
+		// The existing comment is not a ref comment, so keep all of it.
+		rest = parsed.comment
+	}
+
+	repoPath := def.Repo
+	if def.Path != "" {
+		repoPath += "/" + def.Path
+	}
+	newLine := parsed.prefix + parsed.openQuote + def.Owner + "/" + repoPath + "@" + ref + parsed.closeQuote + parsed.tail
+	if rest != "" {
+		newLine += " " + rest
+	}
+	return newLine + parsed.trailingSpace, true, nil
+}
+
+// splitRefComment splits a comment written by Pin ("# v4.1.1" or "# v4.1.1 # original comment") into the ref and the
+// remaining original comment. The resolution date of --comment-include-date ("# v4.1.1 @2025-01-02") is dropped.
+// Comments of any other shape (e.g. "# some note") don't record a ref.
+func splitRefComment(comment string) (string, string, bool) {
+	body, ok := strings.CutPrefix(comment, "#")
+	if !ok {
+		return "", "", false
+	}
+	ref, rest, _ := strings.Cut(strings.TrimSpace(body), " ")
+	rest = strings.TrimSpace(rest)
+	if date, afterDate, _ := strings.Cut(rest, " "); dateMarkerPattern.MatchString(date) {
+		rest = strings.TrimSpace(afterDate)
+	}
+	if ref == "" || (rest != "" && !strings.HasPrefix(rest, "#")) {
+		return "", "", false
+	}
+	return ref, rest, true
+}
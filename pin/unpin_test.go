@@ -0,0 +1,110 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpin(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "Ref comment",
+			input:    "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+			expected: "      - uses: actions/checkout@v4.2.2",
+			changed:  true,
+		},
+		{
+			name:     "Ref comment followed by original comment",
+			input:    "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 # Some comment",
+			expected: "      - uses: actions/checkout@v4.2.2 # Some comment",
+			changed:  true,
+		},
+		{
+			name:     "Ref comment with resolution date",
+			input:    "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 @2025-01-02 # Some comment",
+			expected: "      - uses: actions/checkout@v4.2.2 # Some comment",
+			changed:  true,
+		},
+		{
+			name:     "Flow mapping",
+			input:    "  call: { uses: org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad, secrets: inherit } # main # shared",
+			expected: "  call: { uses: org/repo/.github/workflows/build.yml@main, secrets: inherit } # shared",
+			changed:  true,
+		},
+		{
+			name:     "Quoted reusable workflow",
+			input:    `    uses: "org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad" # main`,
+			expected: `    uses: "org/repo/.github/workflows/build.yml@main"`,
+			changed:  true,
+		},
+		{
+			name:     "No comment",
+			input:    "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683",
+			expected: "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683",
+		},
+		{
+			name:     "Comment that is not a ref",
+			input:    "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # pinned by security",
+			expected: "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # pinned by security",
+		},
+		{
+			name:     "Not pinned",
+			input:    "      - uses: actions/checkout@v4 # v4.2.2",
+			expected: "      - uses: actions/checkout@v4 # v4.2.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Unpin{}
+			got, changed, err := u.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestUnpin_ForceAPI(t *testing.T) {
+	input := `steps:
+  - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683
+  - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # pinned by security
+  - uses: actions/cache@5a3ec84eff668545956fd18022155c47e93e2684 # v4.2.3`
+	expected := `steps:
+  - uses: actions/checkout@v4.2.2
+  - uses: actions/setup-go@v5.4.0 # pinned by security
+  - uses: actions/cache@v4.2.3`
+
+	finder := &mockTagFinder{tags: map[string]string{
+		"actions/checkout": "v4.2.2",
+		"actions/setup-go": "v5.4.0",
+	}}
+	u := &Unpin{finder: finder, forceAPI: true}
+	got, changed, err := u.Apply(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, 2, finder.calls, "lines with a ref comment don't call the API")
+}
+
+type mockTagFinder struct {
+	tags  map[string]string // owner/repo -> tag
+	calls int
+}
+
+func (m *mockTagFinder) FindTagForSHA(_ context.Context, def ActionDef) (string, error) {
+	m.calls++
+	if tag, ok := m.tags[def.Owner+"/"+def.Repo]; ok {
+		return tag, nil
+	}
+	return "", pin.TagForSHANotFoundError
+}
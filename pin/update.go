@@ -0,0 +1,189 @@
+package pin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/cockroachdb/errors"
+	gogithub "github.com/google/go-github/v72/github"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+type commitDater interface {
+	CommitDate(ctx context.Context, owner, repo, sha string) (time.Time, error)
+}
+
+// Update bumps already pinned actions to the latest tag matching the version recorded in their comment:
+// `owner/repo@<sha> # v4.1.1` becomes `owner/repo@<newer sha> # v4.2.2`.
+type Update struct {
+	resolver Resolver
+	// Constrain updates to the major.minor of the current version instead of only the major.
+	sameMinor bool
+	// Used only with replaceOnlyIfNewer; nil otherwise.
+	dater commitDater
+	// Skip updates whose commit is not strictly newer (by committer date) than the pinned commit.
+	replaceOnlyIfNewer bool
+	// Clock for the resolution date of comments written with --comment-include-date; nil means time.Now.
+	now func() time.Time
+}
+
+// UpdateOptions configures how Update selects the newer version.
+type UpdateOptions struct {
+	// Only update within the current minor line (v4.1.1 -> v4.1.x) instead of the major line (v4.1.1 -> v4.x.y).
+	SameMinor bool
+	// Only replace the pinned SHA when the new commit is strictly newer than it by committer date, e.g. to avoid
+	// moving to a tag that was repointed to an older commit.
+	ReplaceOnlyIfNewer bool
+	// Fail instead of falling back to GitHub.com when the primary API returns 404.
+	FailOnFallback bool
+	// Total number of API retries allowed across the whole run. Zero or negative means unlimited.
+	RetryBudget int
+}
+
+// NewUpdate creates an update command with primary GitHub client and optional fallback GitHub.com client.
+func NewUpdate(primaryClient *gogithub.Client, fallbackClient *gogithub.Client, opts UpdateOptions) Update {
+	resolver := newVersionResolver(primaryClient, fallbackClient, ProviderGitHub, opts.RetryBudget, pin.RetryOptions{}, pin.ResolverOptions{
+		FailOnFallback: opts.FailOnFallback,
+		// So that a v0.0 constraint (SameMinor for v0.0.z) stays within v0.0.x.
+		V0Strict: true,
+	})
+	u := Update{
+		resolver:           resolver,
+		sameMinor:          opts.SameMinor,
+		replaceOnlyIfNewer: opts.ReplaceOnlyIfNewer,
+	}
+	if opts.ReplaceOnlyIfNewer {
+		u.dater = resolver
+	}
+	return u
+}
+
+// Apply updates pinned actions in input YAML content then returns the modified content, a boolean indicating if any
+// updates were made, and an error if any occurred.
+func (u *Update) Apply(ctx context.Context, input string) (string, bool, error) {
+	lines := strings.Split(input, "\n")
+
+	changed := false
+	resultLines := make([]string, 0, len(lines))
+
+	var errs []error
+	var scope lineScope
+	for _, line := range lines {
+		if !scope.next(line) {
+			resultLines = append(resultLines, line)
+			continue
+		}
+
+		modifiedLine, lineChanged, err := u.replaceLine(ctx, line)
+		if err != nil {
+			// Collect errors but continue processing remaining actions/lines.
+			errs = append(errs, err)
+			resultLines = append(resultLines, line)
+			continue
+		}
+
+		if lineChanged {
+			changed = true
+			line = modifiedLine
+		}
+		resultLines = append(resultLines, line)
+	}
+
+	output := strings.Join(resultLines, "\n")
+
+	if len(errs) > 0 {
+		return output, changed, errors.Join(errs...)
+	}
+	return output, changed, nil
+}
+
+func (u *Update) replaceLine(ctx context.Context, line string) (string, bool, error) {
+	parsed, ok := parseLine(line)
+	if !ok || !parsed.def.HasCommitSHA() {
+		return line, false, nil
+	}
+	def := parsed.def
+
+	ref, rest, ok := splitRefComment(parsed.comment)
+	var current *semver.Version
+	if ok {
+		current, _ = semver.NewVersion(ref)
+	}
+	if current == nil {
+		slog.Warn("skipping pinned action without a version comment", "uses", def.String())
+		return line, false, nil
+	}
+
+	constraint := def
+	constraint.RefOrSHA = u.constraint(ref, current)
+	resolved, err := u.resolver.ResolveVersion(ctx, constraint)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to resolve latest version for %s", constraint.String())
+	}
+
+	latest, err := semver.NewVersion(resolved.RefComment)
+	if err != nil || !latest.GreaterThan(current) || strings.EqualFold(resolved.CommitSHA, def.RefOrSHA) {
+		return line, false, nil // Already the latest
+	}
+	if u.replaceOnlyIfNewer {
+		newer, err := u.isNewerCommit(ctx, def, resolved.CommitSHA)
+		if err != nil {
+			return "", false, err
+		}
+		if !newer {
+			slog.Info("skipping update to a commit not newer than the pinned one", "uses", def.String(), "to", resolved.RefComment)
+			return line, false, nil
+		}
+	}
+	slog.Debug("updating pinned action", "uses", def.String(), "from", ref, "to", resolved.RefComment)
+
+	repoPath := def.Repo
+	if def.Path != "" {
+		repoPath += "/" + def.Path
+	}
+	newLine := parsed.prefix + parsed.openQuote + def.Owner + "/" + repoPath + "@" + resolved.CommitSHA + parsed.closeQuote +
+		parsed.tail + " # " + resolved.RefComment
+	// A resolution date only changes along with the SHA, so re-runs without a newer version leave it as is.
+	if hasDateMarker(parsed.comment) {
+		now := time.Now
+		if u.now != nil {
+			now = u.now
+		}
+		newLine += " " + dateMarker(now())
+	}
+	if rest != "" {
+		newLine += " " + rest
+	}
+	return newLine + parsed.trailingSpace, true, nil
+}
+
+// isNewerCommit reports whether the commit sha was committed strictly after the commit def is pinned to.
+func (u *Update) isNewerCommit(ctx context.Context, def pin.ActionDef, sha string) (bool, error) {
+	current, err := u.dater.CommitDate(ctx, def.Owner, def.Repo, def.RefOrSHA)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get commit date for %s", def.String())
+	}
+	candidate, err := u.dater.CommitDate(ctx, def.Owner, def.Repo, sha)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get commit date for %s/%s@%s", def.Owner, def.Repo, sha)
+	}
+	return candidate.After(current), nil
+}
+
+// constraint returns the version ref to resolve for current, e.g. v4 (or v4.1 with sameMinor) for v4.1.1, keeping the
+// "v" prefix as written in ref.
+func (u *Update) constraint(ref string, current *semver.Version) string {
+	prefix := ""
+	if strings.HasPrefix(ref, "v") {
+		prefix = "v"
+	}
+	if u.sameMinor {
+		return fmt.Sprintf("%s%d.%d", prefix, current.Major(), current.Minor())
+	}
+	return fmt.Sprintf("%s%d", prefix, current.Major())
+}
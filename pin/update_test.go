@@ -0,0 +1,154 @@
+package pin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	resolveResults := map[string]ResolvedVersion{
+		"actions/checkout@v4":   {CommitSHA: "11bd71901bbe5b1630ceea73d27597364c9af683", RefComment: "v4.2.2"},
+		"actions/checkout@v4.1": {CommitSHA: "b4ffde65f46336ab88eb53be808477a3936bae11", RefComment: "v4.1.7"},
+		"actions/setup-go@v5":   {CommitSHA: "0aaccfd150d50ccaeb58ebd88d36e91967a5f35b", RefComment: "v5.4.0"},
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		changed   bool
+		sameMinor bool
+	}{
+		{
+			name:     "Bumps to the latest tag of the major",
+			input:    "      - uses: actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab # v4.1.1",
+			expected: "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2",
+			changed:  true,
+		},
+		{
+			name:      "Bumps within the minor",
+			input:     "      - uses: actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab # v4.1.1",
+			expected:  "      - uses: actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11 # v4.1.7",
+			changed:   true,
+			sameMinor: true,
+		},
+		{
+			name:     "Keeps the original comment",
+			input:    `      - uses: "actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab" # v4.1.1 # Some comment`,
+			expected: `      - uses: "actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683" # v4.2.2 # Some comment`,
+			changed:  true,
+		},
+		{
+			name:     "Refreshes the resolution date",
+			input:    "      - uses: actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab # v4.1.1 @2024-06-01 # Some comment",
+			expected: "      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2 @2025-01-02 # Some comment",
+			changed:  true,
+		},
+		{
+			name:     "Keeps the resolution date when already the latest",
+			input:    "      - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0 @2024-06-01",
+			expected: "      - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0 @2024-06-01",
+		},
+		{
+			name:     "Already the latest",
+			input:    "      - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0",
+			expected: "      - uses: actions/setup-go@0aaccfd150d50ccaeb58ebd88d36e91967a5f35b # v5.4.0",
+		},
+		{
+			name:     "Branch comment is skipped",
+			input:    "    uses: org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad # main",
+			expected: "    uses: org/repo/.github/workflows/build.yml@aa0779029b74112dc82b436546da0706a57323ad # main",
+		},
+		{
+			name:     "No comment is skipped",
+			input:    "      - uses: actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab",
+			expected: "      - uses: actions/checkout@8e5e7e5ab8b370d6c329ec480221332ada57f0ab",
+		},
+		{
+			name:     "Not pinned is skipped",
+			input:    "      - uses: actions/checkout@v4",
+			expected: "      - uses: actions/checkout@v4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Update{
+				resolver:  &mockResolver{resolveResult: resolveResults},
+				sameMinor: tt.sameMinor,
+				now:       func() time.Time { return time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC) },
+			}
+			got, changed, err := u.Apply(context.Background(), tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestUpdate_ReplaceOnlyIfNewer(t *testing.T) {
+	const (
+		pinned = "8e5e7e5ab8b370d6c329ec480221332ada57f0ab"
+		newer  = "11bd71901bbe5b1630ceea73d27597364c9af683"
+		older  = "b4ffde65f46336ab88eb53be808477a3936bae11"
+	)
+	dater := &mockCommitDater{dates: map[string]time.Time{
+		pinned: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		newer:  time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		older:  time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+	}}
+
+	tests := []struct {
+		name     string
+		resolved ResolvedVersion
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "Same commit",
+			resolved: ResolvedVersion{CommitSHA: pinned, RefComment: "v4.2.2"},
+			expected: "      - uses: actions/checkout@" + pinned + " # v4.1.1",
+		},
+		{
+			name:     "Newer commit",
+			resolved: ResolvedVersion{CommitSHA: newer, RefComment: "v4.2.2"},
+			expected: "      - uses: actions/checkout@" + newer + " # v4.2.2",
+			changed:  true,
+		},
+		{
+			name:     "Older commit",
+			resolved: ResolvedVersion{CommitSHA: older, RefComment: "v4.2.2"},
+			expected: "      - uses: actions/checkout@" + pinned + " # v4.1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Update{
+				resolver:           &mockResolver{resolveResult: map[string]ResolvedVersion{"actions/checkout@v4": tt.resolved}},
+				dater:              dater,
+				replaceOnlyIfNewer: true,
+			}
+			got, changed, err := u.Apply(context.Background(), "      - uses: actions/checkout@"+pinned+" # v4.1.1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.changed, changed)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+type mockCommitDater struct {
+	dates map[string]time.Time // sha -> committer date
+}
+
+func (m *mockCommitDater) CommitDate(_ context.Context, _, _, sha string) (time.Time, error) {
+	if date, ok := m.dates[sha]; ok {
+		return date, nil
+	}
+	return time.Time{}, errors.Newf("unknown commit %s", sha)
+}
@@ -0,0 +1,73 @@
+package pin
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+type commitVerifier interface {
+	VerifyCommit(ctx context.Context, owner, repo, sha string) error
+}
+
+// verifiedCommits verifies each owner/repo@sha once per run. Only definite answers are remembered, so a transient
+// API failure is retried by the next line pinned to the same commit. It is safe for concurrent use.
+type verifiedCommits struct {
+	verifier commitVerifier
+	mu       sync.Mutex
+	results  map[string]error
+}
+
+func newVerifiedCommits(verifier commitVerifier) *verifiedCommits {
+	return &verifiedCommits{verifier: verifier, results: make(map[string]error)}
+}
+
+func (c *verifiedCommits) VerifyCommit(ctx context.Context, owner, repo, sha string) error {
+	key := strings.ToLower(owner + "/" + repo + "@" + sha)
+	c.mu.Lock()
+	err, ok := c.results[key]
+	c.mu.Unlock()
+	if ok {
+		return err
+	}
+
+	err = c.verifier.VerifyCommit(ctx, owner, repo, sha)
+	if err == nil || errors.Is(err, pin.CommitNotFoundError) {
+		c.mu.Lock()
+		c.results[key] = err
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// verifyPinnedLine checks that the actions of line already pinned to a commit SHA reference an existing commit,
+// applying the same ignore and only options as pinning. Lines with nothing pinned in scope pass as is.
+func (p *Pin) verifyPinnedLine(ctx context.Context, line string) error {
+	var candidates []parsedLine
+	if isMultiFlowLine(line) {
+		body, _ := splitFlowComment(line)
+		for _, key := range flowUsesKeyPattern.FindAllString(body, -1) {
+			if parsed, ok := p.parseCandidate("{" + key[1:] + "}"); ok {
+				candidates = append(candidates, parsed)
+			}
+		}
+	} else if parsed, ok := p.parseCandidate(line); ok {
+		candidates = append(candidates, parsed)
+	}
+
+	var errs []error
+	for _, parsed := range candidates {
+		def := parsed.def
+		if !def.HasCommitSHA() {
+			continue
+		}
+		if err := p.commits.VerifyCommit(ctx, def.Owner, def.Repo, def.RefOrSHA); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to verify pinned commit of %s", def.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Finatext/gha-fix/internal/pin"
+)
+
+const (
+	existingSHA = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	typoSHA     = "11bd71901bbe5b1630ceea73d27597364c9af684"
+)
+
+type mockCommitVerifier struct {
+	existing map[string]bool
+	calls    []string
+}
+
+func (m *mockCommitVerifier) VerifyCommit(_ context.Context, owner, repo, sha string) error {
+	key := owner + "/" + repo + "@" + sha
+	m.calls = append(m.calls, key)
+	if !m.existing[key] {
+		return errors.Wrapf(pin.CommitNotFoundError, "%s", key)
+	}
+	return nil
+}
+
+func TestVerifyExistingSHA(t *testing.T) {
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/setup-go@v5": {CommitSHA: "d35c59abb061a4a6fb18e82ac0862c26744d6ab5", RefComment: "v5.5.0"},
+	}}
+	newVerifier := func() *mockCommitVerifier {
+		return &mockCommitVerifier{existing: map[string]bool{"actions/checkout@" + existingSHA: true}}
+	}
+
+	t.Run("Existing commits pass and the rest is pinned", func(t *testing.T) {
+		verifier := newVerifier()
+		p := &Pin{resolver: resolver, commits: newVerifiedCommits(verifier)}
+		input := `steps:
+  - uses: actions/checkout@` + existingSHA + ` # v4.2.2
+  - uses: actions/checkout@` + existingSHA + ` # v4.2.2
+  - uses: actions/setup-go@v5`
+		got, changes, err := p.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		assert.Len(t, changes, 1)
+		assert.Contains(t, got, "actions/setup-go@d35c59abb061a4a6fb18e82ac0862c26744d6ab5 # v5.5.0")
+		assert.Equal(t, []string{"actions/checkout@" + existingSHA}, verifier.calls, "each commit is verified once")
+	})
+
+	t.Run("Missing commits are reported", func(t *testing.T) {
+		p := &Pin{resolver: resolver, commits: newVerifiedCommits(newVerifier())}
+		input := `steps:
+  - uses: actions/checkout@` + typoSHA + ` # v4.2.2
+  - { uses: actions/checkout@` + existingSHA + ` }`
+		_, _, err := p.ApplyChanges(context.Background(), input)
+		require.ErrorIs(t, err, pin.CommitNotFoundError)
+		assert.Contains(t, err.Error(), "actions/checkout@"+typoSHA)
+		assert.NotContains(t, err.Error(), existingSHA)
+	})
+
+	t.Run("Multi-flow lines are verified", func(t *testing.T) {
+		p := &Pin{resolver: resolver, commits: newVerifiedCommits(newVerifier())}
+		input := `steps: [{uses: actions/checkout@` + existingSHA + `}, {uses: actions/cache@` + typoSHA + `}]`
+		_, _, err := p.ApplyChanges(context.Background(), input)
+		require.ErrorIs(t, err, pin.CommitNotFoundError)
+		assert.Contains(t, err.Error(), "actions/cache@"+typoSHA)
+	})
+
+	t.Run("Ignored actions are not verified", func(t *testing.T) {
+		verifier := newVerifier()
+		p := &Pin{resolver: resolver, commits: newVerifiedCommits(verifier), ignoreOwners: newNamePatterns([]string{"actions"})}
+		_, _, err := p.ApplyChanges(context.Background(), "steps:\n  - uses: actions/checkout@"+typoSHA+"\n")
+		require.NoError(t, err)
+		assert.Empty(t, verifier.calls)
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		p := &Pin{resolver: resolver}
+		_, _, err := p.ApplyChanges(context.Background(), "steps:\n  - uses: actions/checkout@"+typoSHA+"\n")
+		require.NoError(t, err)
+	})
+}
+
+func TestVerifiedCommits(t *testing.T) {
+	verifier := &mockCommitVerifier{existing: map[string]bool{}}
+	commits := newVerifiedCommits(verifier)
+	for range 2 {
+		err := commits.VerifyCommit(context.Background(), "actions", "checkout", typoSHA)
+		require.ErrorIs(t, err, pin.CommitNotFoundError)
+	}
+	assert.Len(t, verifier.calls, 1, "definite answers are remembered")
+
+	transient := &failingCommitVerifier{err: errors.New("connection reset")}
+	commits = newVerifiedCommits(transient)
+	for range 2 {
+		require.Error(t, commits.VerifyCommit(context.Background(), "actions", "checkout", existingSHA))
+	}
+	assert.Equal(t, 2, transient.calls, "transient failures are retried")
+}
+
+type failingCommitVerifier struct {
+	err   error
+	calls int
+}
+
+func (f *failingCommitVerifier) VerifyCommit(context.Context, string, string, string) error {
+	f.calls++
+	return f.err
+}
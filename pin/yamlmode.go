@@ -0,0 +1,62 @@
+package pin
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// usesLines parses input as YAML and returns the 1-based lines holding the value of a genuine `uses:` key: a mapping
+// key named uses with a single-line scalar value, outside `with:` and `secrets:` mappings (see lineScope). Unlike the
+// line scanner, it can't mistake the continuation lines of multi-line strings for a `uses:` key. Invalid YAML fails.
+func usesLines(input string) (map[int]bool, error) {
+	file, err := parser.ParseBytes([]byte(input), 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse YAML")
+	}
+	lines := make(map[int]bool)
+	for _, doc := range file.Docs {
+		collectUsesLines(doc.Body, lines)
+	}
+	return lines, nil
+}
+
+func collectUsesLines(node ast.Node, lines map[int]bool) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			collectUsesLines(value, lines)
+		}
+	case *ast.MappingValueNode:
+		switch n.Key.GetToken().Value {
+		case "with", "secrets":
+			return // Inputs, never action references
+		case "uses":
+			if value, ok := unwrapNode(n.Value).(*ast.StringNode); ok {
+				lines[value.GetToken().Position.Line] = true
+			}
+			return
+		}
+		collectUsesLines(n.Value, lines)
+	case *ast.SequenceNode:
+		for _, value := range n.Values {
+			collectUsesLines(value, lines)
+		}
+	case *ast.AnchorNode, *ast.TagNode:
+		collectUsesLines(unwrapNode(n), lines)
+	}
+}
+
+// unwrapNode returns the value of anchored and tagged nodes, e.g. the action of `uses: &checkout actions/checkout@v4`.
+func unwrapNode(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		default:
+			return node
+		}
+	}
+}
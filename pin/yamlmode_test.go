@@ -0,0 +1,83 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsesLines(t *testing.T) {
+	input := `jobs:
+  call:
+    uses: org/repo/.github/workflows/build.yml@main
+    secrets:
+      uses: not/an@action
+  build:
+    steps:
+      - uses: actions/checkout@v4
+      - run: |
+          - uses: actions/checkout@v4
+      - name: Go
+        uses: &setup "actions/setup-go@v5"
+      - { uses: actions/checkout@v4, with: { uses: not/an@action } }
+      - uses: actions/checkout@v4
+        with:
+          uses: not/an@action
+      - uses: >-
+          folded/scalar@v1`
+
+	lines, err := usesLines(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]bool{3: true, 8: true, 12: true, 13: true, 14: true}, lines)
+
+	_, err = usesLines("steps:\n  - uses: [actions/checkout@v4\n")
+	require.Error(t, err)
+}
+
+func TestYAMLMode(t *testing.T) {
+	input := `steps:
+  - uses: actions/checkout@v4
+  - run: "echo
+      - uses: actions/checkout@v4"`
+
+	resolver := &mockResolver{resolveResult: map[string]ResolvedVersion{
+		"actions/checkout@v4": {
+			CommitSHA:  "11bd71901bbe5b1630ceea73d27597364c9af683",
+			RefComment: "v4.2.2",
+		},
+	}}
+
+	t.Run("Multi-line strings are left as is", func(t *testing.T) {
+		r := &Pin{resolver: resolver, yamlMode: true}
+		got, changes, err := r.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, `steps:
+  - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2
+  - run: "echo
+      - uses: actions/checkout@v4"`, got)
+
+		findings, err := r.Check(context.Background(), input)
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, 2, findings[0].Line)
+	})
+
+	t.Run("Line scanner pins lookalikes", func(t *testing.T) {
+		r := &Pin{resolver: resolver}
+		_, changes, err := r.ApplyChanges(context.Background(), input)
+		require.NoError(t, err)
+		assert.Len(t, changes, 2)
+	})
+
+	t.Run("Invalid YAML fails", func(t *testing.T) {
+		r := &Pin{resolver: resolver, yamlMode: true}
+		invalid := "steps:\n  - uses: [actions/checkout@v4\n"
+		got, changed, err := r.Apply(context.Background(), invalid)
+		require.Error(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, invalid, got)
+	})
+}
@@ -0,0 +1,220 @@
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// CI is the CI system whose syntax a file is read with.
+type CI string
+
+const (
+	// CIAuto reads files named .gitlab-ci.yml (or .gitlab-ci.yaml) as GitLab CI and any other file as a GitHub
+	// Actions workflow.
+	CIAuto CI = "auto"
+	// CIGitHub reads every file as a GitHub Actions workflow, whose jobs get `timeout-minutes: <N>`.
+	CIGitHub CI = "github"
+	// CIGitLab reads every file as GitLab CI, whose jobs get `timeout: <N>m`, e.g. for files included by
+	// .gitlab-ci.yml.
+	CIGitLab CI = "gitlab"
+)
+
+// ParseCI parses a --ci value. An empty string means CIAuto.
+func ParseCI(s string) (CI, error) {
+	switch ci := CI(s); ci {
+	case "":
+		return CIAuto, nil
+	case CIAuto, CIGitHub, CIGitLab:
+		return ci, nil
+	default:
+		return "", errors.Newf("invalid CI %q: must be auto, github or gitlab", s)
+	}
+}
+
+// IsGitLabCIFile reports whether path is named like the pipeline definition of a GitLab project.
+func IsGitLabCIFile(path string) bool {
+	name := filepath.Base(path)
+	return name == ".gitlab-ci.yml" || name == ".gitlab-ci.yaml"
+}
+
+// InsertPath works like Insert, reading the file at path with the syntax of its CI system, see Options.CI.
+func (f Timeout) InsertPath(ctx context.Context, path string, input string) (string, bool, error) {
+	if f.ci == CIGitLab || (f.ci != CIGitHub && IsGitLabCIFile(path)) {
+		return f.InsertGitLab(ctx, input)
+	}
+	return f.Insert(ctx, input)
+}
+
+// gitlabGlobalKeys are the top-level keys of GitLab CI files that aren't jobs.
+var gitlabGlobalKeys = map[string]bool{
+	"default":       true,
+	"include":       true,
+	"stages":        true,
+	"variables":     true,
+	"workflow":      true,
+	"spec":          true,
+	"image":         true,
+	"services":      true,
+	"cache":         true,
+	"before_script": true,
+	"after_script":  true,
+}
+
+// InsertGitLab adds `timeout: <N>m` to the jobs of a GitLab CI file that don't have a timeout, or replaces differing
+// values when overwriting. Jobs are the top-level mappings with a script or extends key. Hidden jobs (.template),
+// trigger jobs, which GitLab runs without a timeout, and jobs inheriting a timeout from `default:` or from a template
+// of the same file through extends are skipped.
+func (f Timeout) InsertGitLab(_ context.Context, input string) (string, bool, error) {
+	file, err := parser.ParseBytes([]byte(input), parser.ParseComments)
+	if err != nil {
+		return input, false, errors.WithStack(err)
+	}
+
+	value := fmt.Sprintf("%dm", f.timeoutMinutes)
+	var positions []position
+	for _, doc := range file.Docs {
+		rootMapping, ok := doc.Body.(*ast.MappingNode)
+		if !ok {
+			continue
+		}
+		docPositions, err := f.getGitLabPositions(rootMapping, value)
+		if err != nil {
+			return input, false, err
+		}
+		positions = append(positions, docPositions...)
+	}
+
+	// Process positions in reverse order to avoid offset issues
+	lines := strings.Split(input, "\n")
+	modified := false
+	for i := len(positions) - 1; i >= 0; i-- {
+		pos := positions[i]
+		if pos.line <= 0 || pos.line > len(lines) {
+			continue
+		}
+		if pos.replace {
+			lines[pos.line-1] = replaceValue(lines[pos.line-1], "timeout:", value)
+		} else {
+			timeoutLine := fmt.Sprintf("%stimeout: %s", strings.Repeat(" ", pos.column-1), value)
+			lines = append(lines[:pos.line], append([]string{timeoutLine}, lines[pos.line:]...)...)
+		}
+		modified = true
+	}
+
+	if !modified {
+		return input, false, nil
+	}
+	return strings.Join(lines, "\n"), true, nil
+}
+
+// getGitLabPositions finds the selected jobs of rootMapping that need a timeout, as the line of the job key and the
+// column of its first property, and, when overwriting, the timeout lines whose value differs from value.
+func (f Timeout) getGitLabPositions(rootMapping *ast.MappingNode, value string) ([]position, error) {
+	mappings := make(map[string]*ast.MappingNode)
+	for _, v := range rootMapping.Values {
+		if m, ok := v.Value.(*ast.MappingNode); ok && v.Key != nil {
+			mappings[getKeyString(v.Key)] = m
+		}
+	}
+	if defaults, ok := mappings["default"]; ok && findProperty(defaults, "timeout") != nil {
+		return nil, nil // Every job inherits the default timeout
+	}
+
+	positions := []position{}
+	for _, v := range rootMapping.Values {
+		if v.Key == nil {
+			continue
+		}
+		jobID := getKeyString(v.Key)
+		job, ok := mappings[jobID]
+		if !ok || gitlabGlobalKeys[jobID] || strings.HasPrefix(jobID, ".") || !f.selected(jobID) {
+			continue
+		}
+		if findProperty(job, "trigger") != nil || (findProperty(job, "script") == nil && findProperty(job, "extends") == nil) {
+			continue
+		}
+
+		if prop := findProperty(job, "timeout"); prop != nil {
+			if pos, ok := f.replaceGitLabPosition(prop, value); ok {
+				positions = append(positions, pos)
+			}
+			continue
+		}
+		if inheritsTimeout(job, mappings, map[string]bool{jobID: true}) {
+			continue
+		}
+
+		keyToken := v.Key.GetToken()
+		firstToken := job.Values[0].Key.GetToken()
+		if keyToken == nil || keyToken.Position == nil || firstToken == nil || firstToken.Position == nil {
+			continue
+		}
+		if keyToken.Position.Line == firstToken.Position.Line {
+			return nil, errors.Wrapf(ErrFlowStyleNotSupported, "job %s", jobID)
+		}
+		positions = append(positions, position{line: keyToken.Position.Line, column: firstToken.Position.Column})
+	}
+	return positions, nil
+}
+
+// replaceGitLabPosition returns the position of an existing timeout property to overwrite. Values spanning several
+// lines and values already equal to value are left alone.
+func (f Timeout) replaceGitLabPosition(prop *ast.MappingValueNode, value string) (position, bool) {
+	if !f.overwrite || prop.Value == nil {
+		return position{}, false
+	}
+	keyToken := prop.Key.GetToken()
+	valueToken := prop.Value.GetToken()
+	if keyToken == nil || keyToken.Position == nil || valueToken == nil || valueToken.Position == nil ||
+		keyToken.Position.Line != valueToken.Position.Line || valueToken.Value == value {
+		return position{}, false
+	}
+	return position{line: keyToken.Position.Line, column: keyToken.Position.Column, replace: true}, true
+}
+
+// inheritsTimeout reports whether job extends a template of the same file that has a timeout, directly or through
+// its own extends. seen guards against extends cycles.
+func inheritsTimeout(job *ast.MappingNode, mappings map[string]*ast.MappingNode, seen map[string]bool) bool {
+	prop := findProperty(job, "extends")
+	if prop == nil {
+		return false
+	}
+	var names []string
+	switch v := prop.Value.(type) {
+	case *ast.StringNode:
+		names = append(names, v.Value)
+	case *ast.SequenceNode:
+		for _, item := range v.Values {
+			if s, ok := item.(*ast.StringNode); ok {
+				names = append(names, s.Value)
+			}
+		}
+	}
+	for _, name := range names {
+		template, ok := mappings[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if findProperty(template, "timeout") != nil || inheritsTimeout(template, mappings, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// findProperty returns the property of mapping named key, or nil.
+func findProperty(mapping *ast.MappingNode, key string) *ast.MappingValueNode {
+	for _, prop := range mapping.Values {
+		if prop.Key != nil && getKeyString(prop.Key) == key {
+			return prop
+		}
+	}
+	return nil
+}
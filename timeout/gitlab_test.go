@@ -0,0 +1,141 @@
+package timeout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertGitLab(t *testing.T) {
+	input := `stages: [build, test]
+variables:
+  GO_VERSION: "1.24"
+
+.slow:
+  timeout: 2h
+
+.base:
+  image: golang:1.24
+
+build:
+  stage: build
+  script:
+    - go build ./...
+
+test: # unit tests
+  stage: test
+  script: go test ./...
+  timeout: 30m
+
+integration:
+  extends: .slow
+  script: make integration
+
+lint:
+  extends: [.base]
+
+deploy:
+  trigger: org/deploy
+`
+
+	expected := `stages: [build, test]
+variables:
+  GO_VERSION: "1.24"
+
+.slow:
+  timeout: 2h
+
+.base:
+  image: golang:1.24
+
+build:
+  timeout: 10m
+  stage: build
+  script:
+    - go build ./...
+
+test: # unit tests
+  stage: test
+  script: go test ./...
+  timeout: 30m
+
+integration:
+  extends: .slow
+  script: make integration
+
+lint:
+  timeout: 10m
+  extends: [.base]
+
+deploy:
+  trigger: org/deploy
+`
+
+	f := NewTimeout(10)
+	got, changed, err := f.InsertGitLab(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+
+	got, changed, err = f.InsertGitLab(context.Background(), expected)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, expected, got)
+
+	t.Run("Overwrite and OnlyJobs", func(t *testing.T) {
+		f := NewTimeoutWithOptions(10, Options{Overwrite: true, OnlyJobs: []string{"test", "integration"}})
+		got, changed, err := f.InsertGitLab(context.Background(), input)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Contains(t, got, "  script: go test ./...\n  timeout: 10m\n")
+		assert.Contains(t, got, "integration:\n  extends: .slow\n") // Inherited timeouts are left alone
+		assert.Contains(t, got, "build:\n  stage: build\n")
+	})
+
+	t.Run("Default timeout", func(t *testing.T) {
+		input := "default:\n  timeout: 1h\nbuild:\n  script: make\n"
+		got, changed, err := f.InsertGitLab(context.Background(), input)
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, input, got)
+	})
+
+	t.Run("Flow style job", func(t *testing.T) {
+		_, _, err := f.InsertGitLab(context.Background(), "build: { script: make }\n")
+		require.True(t, errors.Is(err, ErrFlowStyleNotSupported))
+	})
+}
+
+func TestInsertPath(t *testing.T) {
+	github := "jobs:\n  build:\n    runs-on: ubuntu-latest\n"
+	gitlab := "build:\n  script: make\n"
+
+	tests := []struct {
+		name     string
+		ci       CI
+		path     string
+		input    string
+		expected string
+	}{
+		{"Auto GitLab file", CIAuto, "repo/.gitlab-ci.yml", gitlab, "build:\n  timeout: 5m\n  script: make\n"},
+		{"Auto workflow", "", ".github/workflows/ci.yml", github, "jobs:\n  build:\n    timeout-minutes: 5\n    runs-on: ubuntu-latest\n"},
+		{"Auto included GitLab file", CIAuto, "ci/build.yml", gitlab, gitlab},
+		{"Forced GitLab", CIGitLab, "ci/build.yml", gitlab, "build:\n  timeout: 5m\n  script: make\n"},
+		{"Forced GitLab ignores workflows", CIGitLab, ".github/workflows/ci.yml", github, github},
+		{"Forced GitHub", CIGitHub, ".gitlab-ci.yml", gitlab, gitlab},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewTimeoutWithOptions(5, Options{CI: tt.ci})
+			got, _, err := f.InsertPath(context.Background(), tt.path, tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	_, err := ParseCI("jenkins")
+	require.Error(t, err)
+}
@@ -3,6 +3,7 @@ package timeout
 import (
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/cockroachdb/errors"
@@ -21,20 +22,44 @@ var (
 
 type Timeout struct {
 	timeoutMinutes uint64
+	onlyJobs       []string
+	overwrite      bool
+	ci             CI
+}
+
+// Options narrows and extends what Insert touches.
+type Options struct {
+	// OnlyJobs limits Insert to jobs whose ID matches one of the entries, exactly or as a glob (path.Match syntax,
+	// e.g. build-*). Empty means all jobs.
+	OnlyJobs []string
+	// Overwrite replaces existing timeout-minutes values that differ from the configured one instead of leaving them.
+	Overwrite bool
+	// CI selects the syntax InsertPath reads files with. Empty means CIAuto.
+	CI CI
 }
 
 func NewTimeout(timeoutMinutes uint64) Timeout {
+	return NewTimeoutWithOptions(timeoutMinutes, Options{})
+}
+
+func NewTimeoutWithOptions(timeoutMinutes uint64, opts Options) Timeout {
 	return Timeout{
 		timeoutMinutes: timeoutMinutes,
+		onlyJobs:       opts.OnlyJobs,
+		overwrite:      opts.Overwrite,
+		ci:             opts.CI,
 	}
 }
 
 type position struct {
 	line   int
 	column int
+	// replace marks line as an existing timeout-minutes line whose value is rewritten in place instead of a job key
+	// line to insert after.
+	replace bool
 }
 
-// Insert adds timeout-minutes to jobs that don't have it
+// Insert adds timeout-minutes to jobs that don't have it, or replaces differing values when overwriting
 // Jobs that use reusable workflows (have "uses" field) are skipped
 func (f Timeout) Insert(ctx context.Context, input string) (string, bool, error) {
 	// Try to determine if this is a valid GitHub Actions workflow file
@@ -68,7 +93,7 @@ func (f Timeout) Insert(ctx context.Context, input string) (string, bool, error)
 		return input, false, nil
 	}
 
-	positions := getPositions(file)
+	positions := f.getPositions(file)
 	if len(positions) == 0 {
 		return input, false, nil
 	}
@@ -85,6 +110,12 @@ func (f Timeout) Insert(ctx context.Context, input string) (string, bool, error)
 			continue
 		}
 
+		if pos.replace {
+			lines[pos.line-1] = replaceValue(lines[pos.line-1], "timeout-minutes:", fmt.Sprint(f.timeoutMinutes))
+			modified = true
+			continue
+		}
+
 		// Calculate indentation for the timeout-minutes line
 		// It should be at the same level as other job properties
 		indent, err := getJobPropertyIndent(lines, pos.line)
@@ -111,8 +142,9 @@ func (f Timeout) Insert(ctx context.Context, input string) (string, bool, error)
 	return strings.Join(lines, "\n"), true, nil
 }
 
-// getPositions finds all job definitions that do not have timeout-minutes
-func getPositions(file *ast.File) []position {
+// getPositions finds all selected job definitions that do not have timeout-minutes and, when overwriting, the
+// timeout-minutes lines whose value differs from the configured one
+func (f Timeout) getPositions(file *ast.File) []position {
 	positions := []position{}
 	for _, doc := range file.Docs {
 		if doc.Body == nil {
@@ -143,6 +175,9 @@ func getPositions(file *ast.File) []position {
 				if jobValue.Key == nil {
 					continue
 				}
+				if !f.selected(getKeyString(jobValue.Key)) {
+					continue
+				}
 
 				// Detection for flow style jobs
 				isFlowStyle := false
@@ -210,6 +245,9 @@ func getPositions(file *ast.File) []position {
 					propKey := getKeyString(prop.Key)
 					if propKey == "timeout-minutes" {
 						hasTimeout = true
+						if pos, ok := f.replacePosition(prop); ok {
+							positions = append(positions, pos)
+						}
 						break
 					}
 					if propKey == "uses" {
@@ -236,6 +274,53 @@ func getPositions(file *ast.File) []position {
 	return positions
 }
 
+// selected reports whether the job with the given ID is in scope of OnlyJobs
+func (f Timeout) selected(jobID string) bool {
+	if len(f.onlyJobs) == 0 {
+		return true
+	}
+	for _, pattern := range f.onlyJobs {
+		if ok, _ := path.Match(pattern, jobID); ok || pattern == jobID {
+			return true
+		}
+	}
+	return false
+}
+
+// replacePosition returns the position of an existing timeout-minutes property to overwrite. Values spanning
+// several lines and values already equal to the configured one are left alone.
+func (f Timeout) replacePosition(prop *ast.MappingValueNode) (position, bool) {
+	if !f.overwrite || prop.Value == nil {
+		return position{}, false
+	}
+	keyToken := prop.Key.GetToken()
+	valueToken := prop.Value.GetToken()
+	if keyToken == nil || keyToken.Position == nil || valueToken == nil || valueToken.Position == nil ||
+		keyToken.Position.Line != valueToken.Position.Line {
+		return position{}, false
+	}
+	if valueToken.Value == fmt.Sprint(f.timeoutMinutes) {
+		return position{}, false
+	}
+	return position{line: keyToken.Position.Line, column: keyToken.Position.Column, replace: true}, true
+}
+
+// replaceValue rewrites the value of a "<key> <value>" line (key including its colon, e.g. "timeout-minutes:"),
+// keeping its indentation and any trailing comment
+func replaceValue(line string, key string, value string) string {
+	idx := strings.Index(line, key)
+	if idx < 0 {
+		return line
+	}
+	prefix := line[:idx+len(key)]
+	rest := line[idx+len(key):]
+	comment := ""
+	if i := strings.Index(rest, " #"); i >= 0 {
+		comment = rest[i:]
+	}
+	return fmt.Sprintf("%s %s%s", prefix, value, comment)
+}
+
 // getKeyString extracts the string value from a MapKeyNode
 func getKeyString(key ast.MapKeyNode) string {
 	switch n := key.(type) {
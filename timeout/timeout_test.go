@@ -413,3 +413,110 @@ jobs:
 	require.NoError(t, err)
 	assert.False(t, changed)
 }
+
+func TestFixer_Fix_OnlyJobs(t *testing.T) {
+	input := `jobs:
+  build:
+    runs-on: ubuntu-latest
+  test-unit:
+    runs-on: ubuntu-latest
+  test-e2e:
+    timeout-minutes: 30
+    runs-on: ubuntu-latest
+  deploy:
+    runs-on: ubuntu-latest`
+
+	expected := `jobs:
+  build:
+    runs-on: ubuntu-latest
+  test-unit:
+    timeout-minutes: 5
+    runs-on: ubuntu-latest
+  test-e2e:
+    timeout-minutes: 30
+    runs-on: ubuntu-latest
+  deploy:
+    timeout-minutes: 5
+    runs-on: ubuntu-latest`
+
+	f := NewTimeoutWithOptions(5, Options{OnlyJobs: []string{"test-*", "deploy"}})
+	got, changed, err := f.Insert(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+
+	f = NewTimeoutWithOptions(5, Options{OnlyJobs: []string{"release"}})
+	got, changed, err = f.Insert(context.Background(), input)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, input, got)
+}
+
+func TestFixer_Fix_Overwrite(t *testing.T) {
+	input := `jobs:
+  build:
+      timeout-minutes: 30 # keep builds short
+      runs-on: ubuntu-latest
+  test:
+    timeout-minutes: 10
+    runs-on: ubuntu-latest
+  lint:
+    timeout-minutes: "60"
+    runs-on: ubuntu-latest
+  needs-timeout:
+    runs-on: ubuntu-latest
+  reusable:
+    uses: owner/repo/.github/workflows/workflow.yml@main`
+
+	expected := `jobs:
+  build:
+      timeout-minutes: 10 # keep builds short
+      runs-on: ubuntu-latest
+  test:
+    timeout-minutes: 10
+    runs-on: ubuntu-latest
+  lint:
+    timeout-minutes: 10
+    runs-on: ubuntu-latest
+  needs-timeout:
+    timeout-minutes: 10
+    runs-on: ubuntu-latest
+  reusable:
+    uses: owner/repo/.github/workflows/workflow.yml@main`
+
+	f := NewTimeoutWithOptions(10, Options{Overwrite: true})
+	got, changed, err := f.Insert(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+
+	// Already matching values are not reported as changes
+	got, changed, err = f.Insert(context.Background(), expected)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, expected, got)
+}
+
+func TestFixer_Fix_OverwriteOnlyJobs(t *testing.T) {
+	input := `jobs:
+  build:
+    timeout-minutes: 30
+    runs-on: ubuntu-latest
+  deploy:
+    timeout-minutes: ${{ inputs.timeout }}
+    runs-on: ubuntu-latest`
+
+	expected := `jobs:
+  build:
+    timeout-minutes: 30
+    runs-on: ubuntu-latest
+  deploy:
+    timeout-minutes: 15
+    runs-on: ubuntu-latest`
+
+	f := NewTimeoutWithOptions(15, Options{OnlyJobs: []string{"deploy"}, Overwrite: true})
+	got, changed, err := f.Insert(context.Background(), input)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, expected, got)
+}